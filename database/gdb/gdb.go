@@ -8,6 +8,7 @@
 package gdb
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -16,6 +17,7 @@ import (
 	"github.com/gogf/gf/os/glog"
 
 	"github.com/gogf/gf/container/gmap"
+	"github.com/gogf/gf/container/gset"
 	"github.com/gogf/gf/container/gtype"
 	"github.com/gogf/gf/container/gvar"
 	"github.com/gogf/gf/os/gcache"
@@ -31,6 +33,7 @@ type DB interface {
 	// Query APIs.
 	Query(query string, args ...interface{}) (*sql.Rows, error)
 	Exec(sql string, args ...interface{}) (sql.Result, error)
+	ExecMulti(script string) error
 	Prepare(sql string, execOnMaster ...bool) (*sql.Stmt, error)
 
 	// Internal APIs for CURD, which can be overwrote for custom CURD implements.
@@ -40,40 +43,94 @@ type DB interface {
 	doPrepare(link dbLink, query string) (*sql.Stmt, error)
 	doInsert(link dbLink, table string, data interface{}, option int, batch ...int) (result sql.Result, err error)
 	doBatchInsert(link dbLink, table string, list interface{}, option int, batch ...int) (result sql.Result, err error)
+	doInsertSelect(link dbLink, table string, columns []string, selectQuery string, args ...interface{}) (result sql.Result, err error)
 	doUpdate(link dbLink, table string, data interface{}, condition string, args ...interface{}) (result sql.Result, err error)
+	doBatchUpdate(link dbLink, table string, column string, key string, data interface{}, batch ...int) (result sql.Result, err error)
+	doBatchInsertAndGetIds(link dbLink, table string, list interface{}, primary string, batch ...int) ([]int64, error)
+	doBatchSaveAndGetIds(link dbLink, table string, list interface{}, primary string, keys []string) ([]int64, error)
 	doDelete(link dbLink, table string, condition string, args ...interface{}) (result sql.Result, err error)
 
 	// Query APIs for convenience purpose.
 	GetAll(query string, args ...interface{}) (Result, error)
 	GetOne(query string, args ...interface{}) (Record, error)
+	GetOneOrErr(query string, args ...interface{}) (Record, error)
+	GetAllCache(duration time.Duration, key string, query string, args ...interface{}) (Result, error)
+	GetOneCache(duration time.Duration, key string, query string, args ...interface{}) (Record, error)
+	ClearCache(key string)
+	TagCache(key string, tables ...string)
 	GetValue(query string, args ...interface{}) (Value, error)
+	GetScalar(pointer interface{}, query string, args ...interface{}) error
 	GetCount(query string, args ...interface{}) (int, error)
+	Explain(query string, args ...interface{}) (Result, error)
+	CountTable(table string, condition interface{}, args ...interface{}) (int, error)
+	CountDistinct(table string, column string, condition interface{}, args ...interface{}) (int, error)
+	Sum(table string, column string, condition interface{}, args ...interface{}) (Value, error)
+	Avg(table string, column string, condition interface{}, args ...interface{}) (Value, error)
+	Min(table string, column string, condition interface{}, args ...interface{}) (Value, error)
+	Max(table string, column string, condition interface{}, args ...interface{}) (Value, error)
+	GetArray(query string, args ...interface{}) ([]Value, error)
+	GetInts(query string, args ...interface{}) ([]int, error)
+	GetStrings(query string, args ...interface{}) ([]string, error)
 	GetStruct(objPointer interface{}, query string, args ...interface{}) error
 	GetStructs(objPointerSlice interface{}, query string, args ...interface{}) error
+	GetMapStructs(pointer interface{}, key string, query string, args ...interface{}) error
 	GetScan(objPointer interface{}, query string, args ...interface{}) error
+	Find(pointer interface{}, table string, primary interface{}) error
+	Paginate(query string, page int, size int, args ...interface{}) (Result, int, error)
+	Chunk(size int, callback func(result Result) error, query string, args ...interface{}) error
+	ChunkByKey(key string, size int, callback func(result Result) error, query string, args ...interface{}) error
+	Union(subs ...interface{}) (string, []interface{}, error)
+	UnionAll(subs ...interface{}) (string, []interface{}, error)
+	GetPageWithAggregates(page int, size int, from string, where interface{}, args []interface{}, aggregates map[string]string) (Result, int, Record, error)
 
 	// Master/Slave support.
 	Master() (*sql.DB, error)
 	Slave() (*sql.DB, error)
+	MasterChecked() (*sql.DB, error)
+	SlaveChecked() (*sql.DB, error)
+	WarmUp(n int) error
+	ExecOnAll(query string, args ...interface{}) error
+	Version() (string, error)
+	Close(ctx context.Context) error
 
 	// Ping.
 	PingMaster() error
 	PingSlave() error
+	PingMasterContext(ctx context.Context) error
+	PingSlaveContext(ctx context.Context) error
+	ClusterHealth() []NodeHealth
 
 	// Transaction.
-	Begin() (*TX, error)
+	Begin(readOnly ...bool) (*TX, error)
+	Transaction(f func(tx *TX) error) error
 
 	Insert(table string, data interface{}, batch ...int) (sql.Result, error)
 	InsertIgnore(table string, data interface{}, batch ...int) (sql.Result, error)
 	Replace(table string, data interface{}, batch ...int) (sql.Result, error)
 	Save(table string, data interface{}, batch ...int) (sql.Result, error)
+	InsertOnConflict(table string, data interface{}, conflict []string, batch ...int) (sql.Result, error)
+	InsertSelect(table string, columns []string, selectQuery string, args ...interface{}) (sql.Result, error)
+	SaveAndGetStatus(table string, data interface{}) (int, error)
 
 	BatchInsert(table string, list interface{}, batch ...int) (sql.Result, error)
+	BatchInsertTx(table string, list interface{}, batch ...int) (sql.Result, error)
 	BatchReplace(table string, list interface{}, batch ...int) (sql.Result, error)
 	BatchSave(table string, list interface{}, batch ...int) (sql.Result, error)
+	BatchInsertAndGetIds(table string, list interface{}, primary string, batch ...int) ([]int64, error)
+	BatchSaveAndGetIds(table string, list interface{}, primary string, keys ...string) ([]int64, error)
 
 	Update(table string, data interface{}, condition interface{}, args ...interface{}) (sql.Result, error)
+	UpdateAndCount(table string, data interface{}, condition interface{}, args ...interface{}) (int64, error)
+	BatchUpdate(table string, column string, key string, data interface{}, batch ...int) (sql.Result, error)
 	Delete(table string, condition interface{}, args ...interface{}) (sql.Result, error)
+	DeleteAndCount(table string, condition interface{}, args ...interface{}) (int64, error)
+	ReplaceSet(table string, condition interface{}, list interface{}) error
+	Truncate(table string) (sql.Result, error)
+
+	// Dry run.
+	InsertSql(table string, data interface{}, batch ...int) ([]*Sql, error)
+	UpdateSql(table string, data interface{}, condition interface{}, args ...interface{}) (*Sql, error)
+	DeleteSql(table string, condition interface{}, args ...interface{}) (*Sql, error)
 
 	// Create model.
 	From(tables string) *Model
@@ -82,28 +139,79 @@ type DB interface {
 
 	// Configuration methods.
 	SetDebug(debug bool)
+	SetProtectFullTableOps(protect bool)
+	SetFilterUnknownColumns(filter bool)
+	SetSensitiveColumns(columns ...string)
+	SetTableResolver(resolver TableResolver)
+	WithTableResolver(resolver TableResolver) DB
+	SetTableRedirect(table string, target string)
+	NoQuote() DB
+	SetSqlComment(comment string)
+	WithSqlComment(comment string) DB
+	SetBatchNum(n int)
+	SetPingTimeout(timeout time.Duration)
+	SetMasterBreaker(threshold int, backoff time.Duration, maxBackoff time.Duration)
+	SetReplicaLagMonitor(interval time.Duration)
+	SetLogSampleRate(rate float64)
+	SetLogSlowThreshold(threshold time.Duration)
 	SetSchema(schema string)
 	SetLogger(logger *glog.Logger)
 	GetLogger() *glog.Logger
+	CatchSql() *SqlCollector
+	SetSqlHistory(size int)
+	LastSql() *Sql
+	RecentSqls(n int) []*Sql
 	SetMaxIdleConnCount(n int)
 	SetMaxOpenConnCount(n int)
 	SetMaxConnLifetime(d time.Duration)
 	Tables(schema ...string) (tables []string, err error)
+	TableExists(table string, schema ...string) (bool, error)
 	TableFields(table string, schema ...string) (map[string]*TableField, error)
+	ClearTableFieldsCache(table string, schema ...string)
+	RegisterMoneyColumn(table string, column string, scale int)
+	RegisterTypeConverter(fieldType string, converter Converter)
+	RegisterColumnConverter(table string, column string, converter Converter)
 
 	// Internal methods.
 	getCache() *gcache.Cache
 	getChars() (charLeft string, charRight string)
+	getQuoteChars() (charLeft string, charRight string)
 	getDebug() bool
 	getPrefix() string
+	getLimit(start int, limit int) string
+	getQueryTables(query string) []string
+	getTableVersions(tables []string) []int
+	setQueryCache(key string, result Result, duration time.Duration, tables []string, versions []int)
+	clearTableCache(link dbLink, table string)
+	getCollate(collation string) string
+	getLockSql(shared bool) (string, error)
+	getTruncateSql(table string) string
+	getInsertOperation(option int) string
+	getInsertIds(result sql.Result, count int) ([]int64, error)
+	getSaveSql(table string, columns []string, conflict []string) (string, error)
+	getIgnoreSql(conflict []string) string
+	getTableExistsSql() string
+	getVersionSql() string
+	getMaxPlaceholders() int
+	getSavepointSql(name string) (save string, rollback string, release string)
+	getTxOptions(readOnly bool) *sql.TxOptions
+	getSaveStatus(affected int64) int
+	getSaveCounts(affected int64, count int) (inserted int64, updated int64, saved int64)
+	getSaveReturningSql() string
+	getExplainSql(query string) (string, error)
+	getReplicaLag(link dbLink) (time.Duration, error)
+	classifyError(err error) (kind error, code string)
 	getMaster(schema ...string) (*sql.DB, error)
 	getSlave(schema ...string) (*sql.DB, error)
+	getSlaveWithStaleness(maxStaleness time.Duration, schema ...string) (*sql.DB, error)
 	quoteWord(s string) string
 	quoteString(s string) string
 	handleTableName(table string) string
 	filterFields(schema, table string, data map[string]interface{}) map[string]interface{}
 	convertValue(fieldValue []byte, fieldType string) interface{}
-	rowsToResult(rows *sql.Rows) (Result, error)
+	convertArrayData(table string, data Map) Map
+	rowsToResult(rows *sql.Rows, query string) (Result, error)
+	acquireLink(link dbLink) (release func(), err error)
 	handleSqlBeforeExec(sql string) string
 }
 
@@ -116,16 +224,50 @@ type dbLink interface {
 
 // dbBase is the base struct for database management.
 type dbBase struct {
-	db               DB            // DB interface object.
-	group            string        // Configuration group name.
-	debug            *gtype.Bool   // Enable debug mode for the database.
-	cache            *gcache.Cache // Cache manager.
-	schema           *gtype.String // Custom schema for this object.
-	prefix           string        // Table prefix.
-	logger           *glog.Logger  // Logger.
-	maxIdleConnCount int           // Max idle connection count.
-	maxOpenConnCount int           // Max open connection count.
-	maxConnLifetime  time.Duration // Max TTL for a connection.
+	db               DB               // DB interface object.
+	group            string           // Configuration group name.
+	debug            *gtype.Bool      // Enable debug mode for the database.
+	protectFullTable *gtype.Bool      // Forbid Update/Delete operations without WHERE condition.
+	filterColumns    *gtype.Bool      // Filter the data of Insert/Update operations according to the table fields.
+	batchNum         *gtype.Int       // Default record count of each statement for batch operations.
+	nodeBatchNum     int              // BatchNum of the configuration node, which SetBatchNum resets to.
+	cache            *gcache.Cache    // Cache manager.
+	moneyColumns     *gmap.StrIntMap  // Registered money columns, key is "table.column" and value is the scale.
+	typeConverters   *gmap.StrAnyMap  // Registered converters of field types, key is the lower case type name and value is *Converter.
+	columnConverters *gmap.StrAnyMap  // Registered converters of columns, key is "table.column" and value is *Converter.
+	cacheTags        *gmap.StrAnyMap  // Tagged cache keys of tables, key is the table and value is *cacheTag.
+	tableVersions    *gmap.StrIntMap  // Versions of tables increased by each writing, see clearTableCache.
+	tableFieldsLocks *gmap.StrAnyMap  // Locks for retrieving table fields, key is the cache key and value is *sync.Mutex.
+	masterBreaker    *breaker         // Circuit breaker for the master node.
+	replicaLags      *replicaLags     // Last-known replication lags of the slave nodes, see SetReplicaLagMonitor.
+	inflight         *inflight        // In-flight operations, see Close.
+	sqlCollectors    *gset.Set        // Attached collectors of the executed statements, see CatchSql.
+	sqlHistory       *sqlHistory      // Recent executed statements, see SetSqlHistory.
+	sensitiveColumns *gtype.Interface // Sensitive column names, which is type of map[string]struct{} with lower case keys.
+	tableResolver    *gtype.Interface // Resolver of the physical table names, which is type of TableResolver.
+	tableRedirects   *gmap.StrStrMap  // Redirected table names, key is the table and value is the target table, see SetTableRedirect.
+	sqlComment       *gtype.String    // Comment prepended to the executed statements, see SetSqlComment.
+	noQuote          bool             // Whether the identifiers are used verbatim without quote chars, see NoQuote.
+	logSampleRate    *gtype.Float64   // Sample rate for logging successful statements in debug mode.
+	logSlowTime      *gtype.Int64     // Statements costing more milliseconds are always logged in debug mode.
+	pingTimeout      *gtype.Int64     // Timeout in milliseconds of PingMaster/PingSlave.
+	nodePingTimeout  time.Duration    // PingTimeout of the configuration node, which SetPingTimeout resets to.
+	schema           *gtype.String    // Custom schema for this object.
+	version          *gtype.String    // Cached version of the database server, see Version.
+	prefix           string           // Table prefix.
+	logger           *glog.Logger     // Logger.
+	maxIdleConnCount int              // Max idle connection count.
+	maxOpenConnCount int              // Max open connection count.
+	maxConnLifetime  time.Duration    // Max TTL for a connection.
+}
+
+// NodeHealth is the health status of a configured node, see DB.ClusterHealth.
+type NodeHealth struct {
+	Node    string        // Node configuration string, in which the password is not contained.
+	Role    string        // Role of the node, "master" or "slave".
+	Ok      bool          // Whether the node is pinged successfully.
+	Error   error         // Error of the ping, which is nil if Ok.
+	Latency time.Duration // Latency of the ping.
 }
 
 // Sql is the sql recording struct.
@@ -150,6 +292,16 @@ type TableField struct {
 	Comment string      // Comment.
 }
 
+// Converter is the column value converter, see RegisterTypeConverter/RegisterColumnConverter.
+type Converter struct {
+	Decode func(value []byte) interface{}      // Converts the non-NULL value read from database.
+	Encode func(value interface{}) interface{} // Converts the non-NULL value for writing, which is optional.
+}
+
+// TableResolver resolves the logical table name to the physical one, which can be qualified
+// with schema, eg: "user" to "tenant_001.user". See SetTableResolver/WithTableResolver.
+type TableResolver func(table string) string
+
 // Value is the field value type.
 type Value = *gvar.Var
 
@@ -166,18 +318,65 @@ type Map = map[string]interface{}
 // List is type of map array.
 type List = []Map
 
+// Raw is the raw sql expression for the value of Insert/Update data, which is used in the
+// statement literally instead of being bound as a parameter, eg:
+// g.Map{"views": gdb.Raw("views+1"), "update_time": gdb.Raw("NOW()")}
+//
+// Note that Raw is NOT escaped in any way, so never build it from user input,
+// or else it causes sql injection.
+type Raw string
+
 const (
 	gINSERT_OPTION_DEFAULT      = 0
 	gINSERT_OPTION_REPLACE      = 1
 	gINSERT_OPTION_SAVE         = 2
 	gINSERT_OPTION_IGNORE       = 3
-	gDEFAULT_BATCH_NUM          = 10 // Per count for batch insert/replace/save
-	gDEFAULT_CONN_MAX_LIFE_TIME = 30 // Max life time for per connection in pool in seconds.
+	gDEFAULT_BATCH_NUM          = 10    // Per count for batch insert/replace/save
+	gMAX_PLACEHOLDER_COUNT      = 65535 // Max place holder count in one statement of mysql and pgsql.
+	gDEFAULT_CONN_MAX_LIFE_TIME = 30    // Max life time for per connection in pool in seconds.
+	gDEFAULT_PING_TIMEOUT       = 5     // Timeout for PingMaster/PingSlave in seconds.
 )
 
+// gSAVE_ID_ALIAS is the alias of the primary key for querying the ids of saved records.
+const gSAVE_ID_ALIAS = "gf_save_id"
+
+// gQUERY_CACHE_KEY_PREFIX is the prefix of the default cache key for GetAllCache/GetOneCache.
+const gQUERY_CACHE_KEY_PREFIX = "gdb_query:"
+
+// gSAVEPOINT_PREFIX is the name prefix of the savepoints created by TX.Run.
+const gSAVEPOINT_PREFIX = "gf_savepoint_"
+
+// gTABLE_FIELDS_CACHE_KEY_PREFIX is the cache key prefix for the fields of tables.
+const gTABLE_FIELDS_CACHE_KEY_PREFIX = "gdb_table_fields:"
+
+// gCACHE_TAG_PRUNE_SIZE is the initial key count of each table triggering the pruning of
+// the expired cache keys tagged with the table, see TagCache.
+const gCACHE_TAG_PRUNE_SIZE = 1024
+
+// gUNION_ALIAS_PREFIX is the alias prefix of the subqueries for Union.
+const gUNION_ALIAS_PREFIX = "gf_union_"
+
+// gPAGE_TOTAL_ALIAS is the alias of the total count for GetPageWithAggregates.
+const gPAGE_TOTAL_ALIAS = "gf_page_total"
+
+// Status of single record saving, which is returned by SaveAndGetStatus.
+const (
+	SAVE_STATUS_UNCHANGED = 0 // The record exists and nothing is changed.
+	SAVE_STATUS_INSERTED  = 1 // The record does not exist and it is inserted.
+	SAVE_STATUS_UPDATED   = 2 // The record exists and it is updated.
+	SAVE_STATUS_SAVED     = 3 // The record is inserted or updated, which cannot be distinguished by the driver.
+)
+
+// LIKE_ESCAPE is the ESCAPE clause for the LIKE patterns escaped by LikePattern.
+const LIKE_ESCAPE = "ESCAPE '!'"
+
 var (
 	// Instance map.
 	instances = gmap.NewStrAnyMap(true)
+
+	// ErrNoRows is returned by GetStruct/GetStructs/Find, etc. if there's no record retrieved.
+	// It is the same error as sql.ErrNoRows, so the comparisons with sql.ErrNoRows still work.
+	ErrNoRows = sql.ErrNoRows
 )
 
 // New creates and returns an ORM object with global configurations.
@@ -203,6 +402,31 @@ func New(name ...string) (db DB, err error) {
 				schema: gtype.NewString(),
 				logger: glog.New(),
 				prefix: node.Prefix,
+				// Full table operations are allowed in default for compatibility.
+				protectFullTable: gtype.NewBool(),
+				filterColumns:    gtype.NewBool(),
+				batchNum:         gtype.NewInt(node.BatchNum),
+				nodeBatchNum:     node.BatchNum,
+				moneyColumns:     gmap.NewStrIntMap(true),
+				typeConverters:   gmap.NewStrAnyMap(true),
+				columnConverters: gmap.NewStrAnyMap(true),
+				cacheTags:        gmap.NewStrAnyMap(true),
+				tableVersions:    gmap.NewStrIntMap(true),
+				tableFieldsLocks: gmap.NewStrAnyMap(true),
+				masterBreaker:    newBreaker(),
+				replicaLags:      newReplicaLags(),
+				inflight:         newInflight(),
+				sqlCollectors:    gset.New(true),
+				sqlHistory:       newSqlHistory(),
+				sensitiveColumns: gtype.NewInterface(),
+				tableResolver:    gtype.NewInterface(),
+				tableRedirects:   gmap.NewStrStrMap(true),
+				sqlComment:       gtype.NewString(),
+				logSampleRate:    gtype.NewFloat64(1),
+				logSlowTime:      gtype.NewInt64(),
+				pingTimeout:      gtype.NewInt64(int64(node.PingTimeout / time.Millisecond)),
+				nodePingTimeout:  node.PingTimeout,
+				version:          gtype.NewString(),
 				// Default max connection life time if user does not configure.
 				maxConnLifetime: gDEFAULT_CONN_MAX_LIFE_TIME,
 			}
@@ -218,7 +442,11 @@ func New(name ...string) (db DB, err error) {
 			case "oracle":
 				base.db = &dbOracle{dbBase: base}
 			default:
-				return nil, errors.New(fmt.Sprintf(`unsupported database type "%s"`, node.Type))
+				driver := getDriver(node.Type)
+				if driver == nil {
+					return nil, errors.New(fmt.Sprintf(`unsupported database type "%s"`, node.Type))
+				}
+				base.db = &dbDriver{dbBase: base, driver: driver}
 			}
 			return base.db, nil
 		} else {
@@ -322,11 +550,45 @@ func getConfigNodeByWeight(cg ConfigGroup) *ConfigNode {
 // The parameter <master> specifies whether retrieves master node connection if
 // master-slave nodes are configured.
 func (bs *dbBase) getSqlDb(master bool, schema ...string) (sqlDb *sql.DB, err error) {
+	// Fails fast if the master node is down.
+	probe := false
+	if master {
+		allowed := false
+		if allowed, probe = bs.masterBreaker.allow(); !allowed {
+			return nil, ErrMasterUnavailable
+		}
+	}
 	// Load balance.
 	node, err := getConfigNodeByGroup(bs.group, master)
 	if err != nil {
+		if probe {
+			bs.masterBreaker.failProbe()
+		}
 		return nil, err
 	}
+	sqlDb, err = bs.getSqlDbByNode(node, schema...)
+	if node.Debug {
+		bs.db.SetDebug(node.Debug)
+	}
+	if master {
+		if sqlDb != nil {
+			bs.masterBreaker.addLink(sqlDb)
+		}
+		// Probes the master node after the backoff window.
+		if probe {
+			if sqlDb == nil || sqlDb.Ping() != nil {
+				bs.masterBreaker.failProbe()
+				return nil, ErrMasterUnavailable
+			}
+			bs.masterBreaker.succeed()
+		}
+	}
+	return
+}
+
+// getSqlDbByNode retrieves and returns the underlying database connection object of <node>,
+// which is opened and cached by node in the first retrieving.
+func (bs *dbBase) getSqlDbByNode(node *ConfigNode, schema ...string) (sqlDb *sql.DB, err error) {
 	// Default value checks.
 	if node.Charset == "" {
 		node.Charset = "utf8"
@@ -370,9 +632,6 @@ func (bs *dbBase) getSqlDb(master bool, schema ...string) (sqlDb *sql.DB, err er
 	if v != nil && sqlDb == nil {
 		sqlDb = v.(*sql.DB)
 	}
-	if node.Debug {
-		bs.db.SetDebug(node.Debug)
-	}
 	return
 }
 
@@ -395,6 +654,195 @@ func (bs *dbBase) Slave() (*sql.DB, error) {
 	return bs.getSqlDb(false, bs.schema.Val())
 }
 
+// MasterChecked acts like function Master, but it pings the returned connection in the ping timeout,
+// which fails fast with the connection error if the master node is not reachable. It is commonly used
+// by the health-sensitive callers, eg: connectivity checks in startup. Also see SetPingTimeout.
+func (bs *dbBase) MasterChecked() (*sql.DB, error) {
+	master, err := bs.db.Master()
+	if err != nil {
+		return nil, err
+	}
+	if err = checkSqlDb(master, bs.getPingTimeout()); err != nil {
+		return nil, err
+	}
+	return master, nil
+}
+
+// SlaveChecked acts like function Slave, but it pings the returned connection in the ping timeout,
+// which fails fast with the connection error if the slave node is not reachable.
+// Also see MasterChecked.
+func (bs *dbBase) SlaveChecked() (*sql.DB, error) {
+	slave, err := bs.db.Slave()
+	if err != nil {
+		return nil, err
+	}
+	if err = checkSqlDb(slave, bs.getPingTimeout()); err != nil {
+		return nil, err
+	}
+	return slave, nil
+}
+
+// checkSqlDb pings <sqlDb> in <timeout>, which also checks out a connection from the pool.
+func checkSqlDb(sqlDb *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return sqlDb.PingContext(ctx)
+}
+
+// WarmUp primes the connection pools of all the master and slave nodes of the configuration group,
+// which creates and pings <n> connections of each node in the ping timeout, so that the first burst
+// of requests does not pay the connection establishment cost. It is commonly called after New in startup.
+//
+// The connection count <n> is limited by the max open connection count of the node, and note that the
+// connections exceeding the max idle connection count are closed when they're put back to the pool.
+// It returns the error of the first failed node. Also see SetMaxOpenConnCount and SetMaxIdleConnCount.
+func (bs *dbBase) WarmUp(n int) error {
+	list, ok := getConfigGroup(bs.group)
+	if !ok {
+		return errors.New(fmt.Sprintf("empty database configuration for item name '%s'", bs.group))
+	}
+	for i := range list {
+		node := list[i]
+		sqlDb, err := bs.getSqlDbByNode(&node, bs.schema.Val())
+		if err == nil {
+			err = warmUpSqlDb(sqlDb, n, bs.getPingTimeout())
+		}
+		if err != nil {
+			return errors.New(fmt.Sprintf("warm up node '%s' failed: %s", node.String(), err.Error()))
+		}
+	}
+	return nil
+}
+
+// ExecOnAll executes the statement <query> on every node of the configuration group, including the
+// master and all the slave nodes, which is commonly used for the administration and maintenance
+// tasks on the whole cluster, eg: changing the global variables of mysql on all the nodes.
+//
+// It continues executing on the other nodes if it fails on any node, and returns the failures as
+// NodeErrors, which tells the failed nodes. Note that the statement is executed on one connection of
+// the connection pool of each node, so it should not be used for the session settings.
+func (bs *dbBase) ExecOnAll(query string, args ...interface{}) error {
+	list, ok := getConfigGroup(bs.group)
+	if !ok {
+		return errors.New(fmt.Sprintf("empty database configuration for item name '%s'", bs.group))
+	}
+	var nodeErrors NodeErrors
+	for i := range list {
+		node := list[i]
+		sqlDb, err := bs.getSqlDbByNode(&node, bs.schema.Val())
+		if err == nil {
+			_, err = bs.db.doExec(sqlDb, query, args...)
+		}
+		if err != nil {
+			nodeErrors = append(nodeErrors, &NodeError{Node: node.String(), Err: err})
+		}
+	}
+	if len(nodeErrors) > 0 {
+		return nodeErrors
+	}
+	return nil
+}
+
+// warmUpSqlDb checks out <n> connections of <sqlDb> at the same time and pings them in <timeout>,
+// then puts them back to the pool. The count <n> is limited by the max open connection count.
+func warmUpSqlDb(sqlDb *sql.DB, n int, timeout time.Duration) error {
+	if max := sqlDb.Stats().MaxOpenConnections; max > 0 && n > max {
+		n = max
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	conns := make([]*sql.Conn, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	for i := 0; i < n; i++ {
+		conn, err := sqlDb.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		conns = append(conns, conn)
+		if err = conn.PingContext(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetTableResolver sets the resolver of the physical table names for all the operations, which is
+// called with the prefixed table name before quoting, eg: routing "user" to "tenant_001.user".
+// The table names qualified with schema are not resolved. The nil <resolver> removes the resolver.
+// Use WithTableResolver for routing the concurrent requests to different tables.
+func (bs *dbBase) SetTableResolver(resolver TableResolver) {
+	bs.tableResolver.Set(resolver)
+}
+
+// WithTableResolver returns a new DB object using <resolver> for resolving the physical table names,
+// which is commonly used for routing each request to its tenant schema, eg:
+// db.WithTableResolver(func(table string) string { return tenant + "." + table }).Table("user").All().
+//
+// The returned DB object shares the connections, caches and configurations with the current one,
+// so it's cheap to create it for each request. Also see SetTableResolver.
+func (bs *dbBase) WithTableResolver(resolver TableResolver) DB {
+	return bs.cloneDB(func(base *dbBase) {
+		base.tableResolver = gtype.NewInterface(resolver)
+	})
+}
+
+// SetTableRedirect redirects all the operations on <table> to <target> transparently, which is
+// commonly used for the shadow tables in the online schema migrations, eg: redirecting "orders"
+// to "_orders_new" in the cut-over window without changing the call sites. The empty <target>
+// removes the redirection of <table>. It can be changed at runtime safely, and it takes effect
+// on the DB objects created by WithTableResolver/WithSqlComment/NoQuote too.
+//
+// Note that the <table> and <target> are the table names with prefix, and the table names qualified
+// with schema are not redirected. The <target> is resolved by the table resolver if it's set, and
+// the table fields of <table> are retrieved from <target> too, see TableFields.
+func (bs *dbBase) SetTableRedirect(table string, target string) {
+	if target == "" {
+		bs.tableRedirects.Remove(table)
+	} else {
+		bs.tableRedirects.Set(table, target)
+	}
+}
+
+// NoQuote returns a new DB object which does not quote the identifiers automatically, of which the table
+// names, fields and columns are used verbatim in the statements, eg: db.NoQuote().Table("user").Fields("count(*)").
+// It is commonly used for the identifiers which are known to be safe but broken by quoting.
+// The table prefix is still added to the table names.
+//
+// The returned DB object shares the connections, caches and configurations with the current one,
+// so it's cheap to create it for each call.
+func (bs *dbBase) NoQuote() DB {
+	return bs.cloneDB(func(base *dbBase) {
+		base.noQuote = true
+	})
+}
+
+// cloneDB returns a new DB object of the same driver with a copy of the base struct, which is
+// changed by <modify>. The new DB object shares the connections, caches and the configurations
+// not changed by <modify> with the current one.
+func (bs *dbBase) cloneDB(modify func(base *dbBase)) DB {
+	base := *bs
+	modify(&base)
+	switch db := bs.db.(type) {
+	case *dbMysql:
+		base.db = &dbMysql{dbBase: &base}
+	case *dbPgsql:
+		base.db = &dbPgsql{dbBase: &base}
+	case *dbMssql:
+		base.db = &dbMssql{dbBase: &base}
+	case *dbSqlite:
+		base.db = &dbSqlite{dbBase: &base}
+	case *dbOracle:
+		base.db = &dbOracle{dbBase: &base}
+	case *dbDriver:
+		base.db = &dbDriver{dbBase: &base, driver: db.driver}
+	}
+	return base.db
+}
+
 // getMaster acts like function Master but with additional <schema> parameter specifying
 // the schema for the connection. It is defined for internal usage.
 // Also see Master.
@@ -8,18 +8,25 @@
 package gdb
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gogf/gf/container/gvar"
 	"github.com/gogf/gf/os/gcache"
 	"github.com/gogf/gf/os/gtime"
 	"github.com/gogf/gf/text/gregex"
+	"github.com/gogf/gf/text/gstr"
 	"github.com/gogf/gf/util/gconv"
+	"github.com/gogf/gf/util/grand"
 )
 
 const (
@@ -33,7 +40,9 @@ var (
 )
 
 // Query commits one query SQL to underlying driver and returns the execution result.
-// It is most commonly used for data querying.
+// It is most commonly used for data querying. Note that the returned rows should be closed,
+// which holds the connection until then. The rows are not counted as in-flight operation, but
+// Close waits for the connection to be put back before closing the connection pool.
 func (bs *dbBase) Query(query string, args ...interface{}) (rows *sql.Rows, err error) {
 	link, err := bs.db.Slave()
 	if err != nil {
@@ -45,28 +54,41 @@ func (bs *dbBase) Query(query string, args ...interface{}) (rows *sql.Rows, err
 // doQuery commits the query string and its arguments to underlying driver
 // through given link object and returns the execution result.
 func (bs *dbBase) doQuery(link dbLink, query string, args ...interface{}) (rows *sql.Rows, err error) {
+	release, err := bs.acquireLink(link)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	query, args = formatQuery(query, args)
-	query = bs.db.handleSqlBeforeExec(query)
-	if bs.db.getDebug() {
+	query = bs.addSqlComment(bs.db.handleSqlBeforeExec(query))
+	args, logArgs := unwrapSensitiveArgs(args)
+	if bs.isRecordingSql() {
 		mTime1 := gtime.TimestampMilli()
-		rows, err = link.Query(query, args...)
+		err = retryOnBadConn(link, func() (e error) {
+			rows, e = link.Query(query, args...)
+			return
+		})
 		mTime2 := gtime.TimestampMilli()
 		s := &Sql{
 			Sql:    query,
-			Args:   args,
-			Format: bindArgsToQuery(query, args),
+			Args:   logArgs,
+			Format: bindArgsToQuery(query, logArgs),
 			Error:  err,
 			Start:  mTime1,
 			End:    mTime2,
 		}
-		bs.printSql(s)
+		bs.recordSql(s)
 	} else {
-		rows, err = link.Query(query, args...)
+		err = retryOnBadConn(link, func() (e error) {
+			rows, e = link.Query(query, args...)
+			return
+		})
 	}
+	bs.masterBreaker.record(link, err)
 	if err == nil {
 		return rows, nil
 	} else {
-		err = formatError(err, query, args...)
+		err = formatError(bs.db, err, query, logArgs...)
 	}
 	return nil, err
 }
@@ -84,25 +106,64 @@ func (bs *dbBase) Exec(query string, args ...interface{}) (result sql.Result, er
 // doExec commits the query string and its arguments to underlying driver
 // through given link object and returns the execution result.
 func (bs *dbBase) doExec(link dbLink, query string, args ...interface{}) (result sql.Result, err error) {
+	if _, ok := link.(*readOnlyTxLink); ok {
+		return nil, ErrReadOnlyTx
+	}
+	release, err := bs.acquireLink(link)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	query, args = formatQuery(query, args)
-	query = bs.db.handleSqlBeforeExec(query)
-	if bs.db.getDebug() {
+	query = bs.addSqlComment(bs.db.handleSqlBeforeExec(query))
+	args, logArgs := unwrapSensitiveArgs(args)
+	if bs.isRecordingSql() {
 		mTime1 := gtime.TimestampMilli()
 		result, err = link.Exec(query, args...)
 		mTime2 := gtime.TimestampMilli()
 		s := &Sql{
 			Sql:    query,
-			Args:   args,
-			Format: bindArgsToQuery(query, args),
+			Args:   logArgs,
+			Format: bindArgsToQuery(query, logArgs),
 			Error:  err,
 			Start:  mTime1,
 			End:    mTime2,
 		}
-		bs.printSql(s)
+		bs.recordSql(s)
 	} else {
 		result, err = link.Exec(query, args...)
 	}
-	return result, formatError(err, query, args...)
+	bs.masterBreaker.record(link, err)
+	return result, formatError(bs.db, err, query, logArgs...)
+}
+
+// acquireLink adds one in-flight operation if <link> is the connection pool, and returns the
+// function removing it. The operations on transaction are tracked by the transaction itself.
+// It returns ErrClosed if the DB object is closed. Also see Close.
+func (bs *dbBase) acquireLink(link dbLink) (release func(), err error) {
+	if _, ok := link.(*sql.DB); !ok {
+		return func() {}, nil
+	}
+	if err = bs.inflight.acquire(); err != nil {
+		return nil, err
+	}
+	return bs.inflight.release, nil
+}
+
+// retryOnBadConn calls query <f> and retries it once if it fails with driver.ErrBadConn and <link>
+// is the connection pool, which re-acquires a connection from the pool for the retry, eg: the pooled
+// connections are closed after the server restarts. It does not retry on transaction or single
+// connection, of which the connection cannot be changed.
+//
+// The drivers return driver.ErrBadConn only if the statement is not sent to the server, so it is
+// safe to retry. Note that it must not be used for Exec statements, and other connection errors
+// like "invalid connection" of mysql are not retried, as the statement might be already executed.
+func retryOnBadConn(link dbLink, f func() error) error {
+	err := f()
+	if _, ok := link.(*sql.DB); ok && err == driver.ErrBadConn {
+		err = f()
+	}
+	return err
 }
 
 // Prepare creates a prepared statement for later queries or executions.
@@ -129,8 +190,13 @@ func (bs *dbBase) Prepare(query string, execOnMaster ...bool) (*sql.Stmt, error)
 }
 
 // doPrepare calls prepare function on given link object and returns the statement object.
+// The error is wrapped with the statement like the one of doQuery/doExec.
 func (bs *dbBase) doPrepare(link dbLink, query string) (*sql.Stmt, error) {
-	return link.Prepare(query)
+	stmt, err := link.Prepare(query)
+	if err != nil {
+		return nil, formatError(bs.db, err, query)
+	}
+	return stmt, nil
 }
 
 // GetAll queries and returns data records from database.
@@ -146,12 +212,131 @@ func (bs *dbBase) doGetAll(link dbLink, query string, args ...interface{}) (resu
 			return nil, err
 		}
 	}
+	// The in-flight operation is held until the rows are read, see Close.
+	release, err := bs.acquireLink(link)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	rows, err := bs.doQuery(link, query, args...)
 	if err != nil || rows == nil {
 		return nil, err
 	}
 	defer rows.Close()
-	return bs.db.rowsToResult(rows)
+	return bs.db.rowsToResult(rows, query)
+}
+
+// GetAllCache acts like GetAll, but it caches the result for <duration>, which means the
+// same query within <duration> reads and returns the result from cache instead of database.
+//
+// The parameter <key> specifies the cache key, which can be used to clear the cache with
+// ClearCache. If <key> is empty, it uses the hash of the query and its arguments as the key.
+//
+// The cache is tagged with the tables parsed from "FROM" and "JOIN" clauses of <query>, and
+// it is cleared automatically after any Insert/Update/Delete operation on these tables, and
+// again after the transaction of the operation is committed. The result is not cached if any
+// of these tables is written during the query. Use TagCache for the tables that cannot be parsed,
+// eg: the tables following a sub query in the FROM list like "FROM (SELECT ...) t, user", or the
+// tables of the views.
+func (bs *dbBase) GetAllCache(duration time.Duration, key string, query string, args ...interface{}) (Result, error) {
+	if key == "" {
+		key = getQueryCacheKey(query, args)
+	}
+	if v := bs.cache.Get(key); v != nil {
+		if result, ok := v.(Result); ok {
+			return result, nil
+		}
+	}
+	tables := bs.db.getQueryTables(query)
+	versions := bs.getTableVersions(tables)
+	result, err := bs.db.doGetAll(nil, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	bs.setQueryCache(key, result, duration, tables, versions)
+	return result, nil
+}
+
+// getTableVersions returns the versions of <tables>, which are increased by clearTableCache.
+func (bs *dbBase) getTableVersions(tables []string) []int {
+	versions := make([]int, len(tables))
+	for i, table := range tables {
+		versions[i] = bs.tableVersions.Get(bs.getTableKey(table))
+	}
+	return versions
+}
+
+// setQueryCache caches <result> with <key> for <duration>, and tags the cache with <tables> of
+// the query. The <versions> are the versions of <tables> retrieved before the query, and the result
+// is discarded if any of <tables> is written since then, as it may be stale.
+func (bs *dbBase) setQueryCache(key string, result Result, duration time.Duration, tables []string, versions []int) {
+	isChanged := func() bool {
+		for i, version := range bs.getTableVersions(tables) {
+			if version != versions[i] {
+				return true
+			}
+		}
+		return false
+	}
+	if isChanged() {
+		return
+	}
+	bs.cache.Set(key, result, duration)
+	bs.TagCache(key, tables...)
+	// The table may be written before it's tagged, of which the cache clearing misses the key.
+	if isChanged() {
+		bs.cache.Remove(key)
+	}
+}
+
+// GetOneCache acts like GetOne, but it caches the result for <duration>.
+// Also see GetAllCache.
+func (bs *dbBase) GetOneCache(duration time.Duration, key string, query string, args ...interface{}) (Record, error) {
+	list, err := bs.GetAllCache(duration, key, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) > 0 {
+		return list[0], nil
+	}
+	return nil, nil
+}
+
+// ClearCache removes the cached query result with <key> from cache.
+// It is commonly used after Insert/Update/Delete on the table of the cached query.
+func (bs *dbBase) ClearCache(key string) {
+	bs.cache.Remove(key)
+}
+
+// TagCache tags the cache <key> with <tables>, which means the cache is cleared after
+// any Insert/Update/Delete operation on these tables. The tagged keys of which the caches
+// are expired are pruned automatically.
+func (bs *dbBase) TagCache(key string, tables ...string) {
+	for _, table := range tables {
+		bs.cacheTags.GetOrSetFuncLock(bs.getTableKey(table), func() interface{} {
+			return newCacheTag()
+		}).(*cacheTag).add(key, bs.cache)
+	}
+}
+
+// clearTableCache removes all the caches tagged with <table>, see TagCache. It also increases the
+// version of <table>, so that the caching queries in progress discard their results.
+// If <link> is of transaction, <table> is recorded for clearing the caches again after committing,
+// as the caches may be filled with the data before committing by the concurrent queries.
+func (bs *dbBase) clearTableCache(link dbLink, table string) {
+	if l, ok := link.(*txLink); ok {
+		l.addTable(table)
+	}
+	key := bs.getTableKey(table)
+	bs.tableVersions.LockFunc(func(m map[string]int) {
+		m[key]++
+	})
+	if bs.cacheTags.Size() == 0 {
+		return
+	}
+	if v := bs.cacheTags.Remove(key); v != nil {
+		v.(*cacheTag).clear(bs.cache)
+	}
 }
 
 // GetOne queries and returns one record from database.
@@ -166,6 +351,19 @@ func (bs *dbBase) GetOne(query string, args ...interface{}) (Record, error) {
 	return nil, nil
 }
 
+// GetOneOrErr queries and returns one record from database, which is the same as GetOne
+// but it returns ErrNoRows if there's no record retrieved.
+func (bs *dbBase) GetOneOrErr(query string, args ...interface{}) (Record, error) {
+	one, err := bs.GetOne(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(one) == 0 {
+		return nil, ErrNoRows
+	}
+	return one, nil
+}
+
 // GetStruct queries one record from database and converts it to given struct.
 // The parameter <pointer> should be a pointer to struct.
 func (bs *dbBase) GetStruct(pointer interface{}, query string, args ...interface{}) error {
@@ -174,7 +372,7 @@ func (bs *dbBase) GetStruct(pointer interface{}, query string, args ...interface
 		return err
 	}
 	if len(one) == 0 {
-		return sql.ErrNoRows
+		return ErrNoRows
 	}
 	return one.Struct(pointer)
 }
@@ -187,17 +385,35 @@ func (bs *dbBase) GetStructs(pointer interface{}, query string, args ...interfac
 		return err
 	}
 	if len(all) == 0 {
-		return sql.ErrNoRows
+		return ErrNoRows
 	}
 	return all.Structs(pointer)
 }
 
-// GetScan queries one or more records from database and converts them to given struct or
-// struct array.
+// GetMapStructs queries records from database and converts them to a map of structs of which
+// key is the value of column <key>, eg: loading users into map[int]*User keyed by "id":
+// var users map[int]*User; err := db.GetMapStructs(&users, "id", "SELECT * FROM user").
+// The parameter <pointer> should be type of *map[K]struct or *map[K]*struct.
+// It returns ErrNoRows if there's no record retrieved. Also see Result.MapKeyStructs.
+func (bs *dbBase) GetMapStructs(pointer interface{}, key string, query string, args ...interface{}) error {
+	all, err := bs.GetAll(query, args...)
+	if err != nil {
+		return err
+	}
+	return all.MapKeyStructs(pointer, key)
+}
+
+// GetScan queries one or more records from database and converts them to given struct,
+// struct array, map or map array.
 //
 // If parameter <pointer> is type of struct pointer, it calls GetStruct internally for
 // the conversion. If parameter <pointer> is type of slice, it calls GetStructs internally
 // for conversion.
+//
+// The parameter <pointer> can also be type of *map[string]interface{}/*Record for one record,
+// or *[]map[string]interface{}/*Result for all records.
+//
+// It returns ErrNoRows if there's no record retrieved, and <pointer> is not changed.
 func (bs *dbBase) GetScan(pointer interface{}, query string, args ...interface{}) error {
 	t := reflect.TypeOf(pointer)
 	k := t.Kind()
@@ -207,11 +423,104 @@ func (bs *dbBase) GetScan(pointer interface{}, query string, args ...interface{}
 	k = t.Elem().Kind()
 	switch k {
 	case reflect.Array, reflect.Slice:
+		if t.Elem().Elem().Kind() == reflect.Map {
+			all, err := bs.db.GetAll(query, args...)
+			if err != nil {
+				return err
+			}
+			if len(all) == 0 {
+				return ErrNoRows
+			}
+			return scanMaps(pointer, all, all.List())
+		}
 		return bs.db.GetStructs(pointer, query, args...)
+	case reflect.Map:
+		one, err := bs.db.GetOne(query, args...)
+		if err != nil {
+			return err
+		}
+		if len(one) == 0 {
+			return ErrNoRows
+		}
+		return scanMaps(pointer, one, one.Map())
 	case reflect.Struct:
 		return bs.db.GetStruct(pointer, query, args...)
 	}
-	return fmt.Errorf("element type should be type of struct/slice, unsupported: %v", k)
+	return fmt.Errorf("element type should be type of struct/slice/map, unsupported: %v", k)
+}
+
+// scanMaps assigns the first one of <values> that is assignable to the element of <pointer>.
+// The <values> are the same records in different types, eg: Record and Map.
+func scanMaps(pointer interface{}, values ...interface{}) error {
+	rv := reflect.ValueOf(pointer).Elem()
+	for _, value := range values {
+		if v := reflect.ValueOf(value); v.Type().AssignableTo(rv.Type()) {
+			rv.Set(v)
+			return nil
+		}
+	}
+	return fmt.Errorf("element type should be type of map[string]interface{}/[]map[string]interface{}, unsupported: %v", rv.Type())
+}
+
+// Find queries one record from <table> by its primary key and converts it to given struct.
+// The parameter <pointer> should be a pointer to struct.
+//
+// The parameter <primary> is the value of the primary key, of which the primary key column is
+// retrieved from the table fields. For table having composite primary keys, the parameter
+// <primary> should be a map containing all the primary key columns and their values,
+// eg: g.Map{"uid": 1, "gid": 2}.
+//
+// Note that it does not use cached prepared statement, and the query is executed like GetStruct,
+// so that it is logged, retried and routed to the slave node like the other queries.
+//
+// It returns ErrNoRows if there's no record found with given primary key.
+func (bs *dbBase) Find(pointer interface{}, table string, primary interface{}) error {
+	query, args, err := getFindQuery(bs.db, table, primary)
+	if err != nil {
+		return err
+	}
+	return bs.db.GetStruct(pointer, query, args...)
+}
+
+// getFindQuery returns the query and its arguments for Find, which selects one record
+// from <table> by its primary key. Also see Find.
+func getFindQuery(db DB, table string, primary interface{}) (string, []interface{}, error) {
+	table = db.handleTableName(table)
+	where := primary
+	rv := reflect.ValueOf(primary)
+	kind := rv.Kind()
+	if kind == reflect.Ptr {
+		rv = rv.Elem()
+		kind = rv.Kind()
+	}
+	if kind != reflect.Map {
+		fields, err := db.TableFields(table)
+		if err != nil {
+			return "", nil, err
+		}
+		keys := make([]string, 0)
+		for name, field := range fields {
+			if gstr.ContainsI(field.Key, "pri") {
+				keys = append(keys, name)
+			}
+		}
+		switch len(keys) {
+		case 0:
+			return "", nil, errors.New(fmt.Sprintf(`primary key not found for table %s`, table))
+		case 1:
+			where = map[string]interface{}{keys[0]: primary}
+		default:
+			return "", nil, errors.New(fmt.Sprintf(
+				`table %s has composite primary keys "%s", map parameter is required`,
+				table, strings.Join(keys, ","),
+			))
+		}
+	}
+	condition, args := formatWhere(db, where, nil, false)
+	if condition == "" {
+		return "", nil, errors.New("primary key condition cannot be empty")
+	}
+	return fmt.Sprintf("SELECT * FROM %s WHERE %s", table, condition) + db.getLimit(0, 1), args, nil
 }
 
 // GetValue queries and returns the field value from database.
@@ -228,6 +537,112 @@ func (bs *dbBase) GetValue(query string, args ...interface{}) (Value, error) {
 	return nil, nil
 }
 
+// GetScalar queries one field of one record from database and assigns it to the variable <pointer>,
+// which avoids the Value indirection for the common queries like "SELECT COUNT(*)", eg:
+// var max int; err := db.GetScalar(&max, "SELECT MAX(id) FROM user").
+// The parameter <pointer> can be a pointer to basic type, []byte, time.Time, gtime.Time,
+// or the nullable types like **int and *sql.NullString for NULL value.
+//
+// The sql should query only one field from database. It returns ErrNoRows if there's no record retrieved.
+func (bs *dbBase) GetScalar(pointer interface{}, query string, args ...interface{}) error {
+	one, err := bs.GetOneOrErr(query, args...)
+	if err != nil {
+		return err
+	}
+	for _, v := range one {
+		return scanValue(pointer, v.Val())
+	}
+	return nil
+}
+
+// GetArray queries and returns the values of the first column of all records from database.
+// It returns an empty slice if there's no record found.
+func (bs *dbBase) GetArray(query string, args ...interface{}) ([]Value, error) {
+	link, err := bs.db.Slave()
+	if err != nil {
+		return nil, err
+	}
+	return doGetArray(bs.db, link, query, args...)
+}
+
+// doGetArray queries and returns the values of the first column of all records using <link>.
+// Also see GetArray.
+func doGetArray(db DB, link dbLink, query string, args ...interface{}) ([]Value, error) {
+	// The in-flight operation is held until the rows are read, see Close.
+	release, err := db.acquireLink(link)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	rows, err := db.doQuery(link, query, args...)
+	if err != nil || rows == nil {
+		return []Value{}, err
+	}
+	defer rows.Close()
+	// The Result is type of map, so it needs the column name to retrieve the first column.
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result, err := db.rowsToResult(rows, query)
+	if err != nil {
+		return nil, err
+	}
+	array := make([]Value, len(result))
+	for i, record := range result {
+		array[i] = record[columns[0]]
+	}
+	return array, nil
+}
+
+// GetInts queries and returns the values of the first column of all records as []int.
+// Also see GetArray.
+func (bs *dbBase) GetInts(query string, args ...interface{}) ([]int, error) {
+	link, err := bs.db.Slave()
+	if err != nil {
+		return nil, err
+	}
+	return doGetInts(bs.db, link, query, args...)
+}
+
+// doGetInts queries and returns the values of the first column of all records as []int
+// using <link>. Also see GetInts.
+func doGetInts(db DB, link dbLink, query string, args ...interface{}) ([]int, error) {
+	array, err := doGetArray(db, link, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	ints := make([]int, len(array))
+	for i, v := range array {
+		ints[i] = v.Int()
+	}
+	return ints, nil
+}
+
+// GetStrings queries and returns the values of the first column of all records as []string.
+// Also see GetArray.
+func (bs *dbBase) GetStrings(query string, args ...interface{}) ([]string, error) {
+	link, err := bs.db.Slave()
+	if err != nil {
+		return nil, err
+	}
+	return doGetStrings(bs.db, link, query, args...)
+}
+
+// doGetStrings queries and returns the values of the first column of all records as []string
+// using <link>. Also see GetStrings.
+func doGetStrings(db DB, link dbLink, query string, args ...interface{}) ([]string, error) {
+	array, err := doGetArray(db, link, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, len(array))
+	for i, v := range array {
+		values[i] = v.String()
+	}
+	return values, nil
+}
+
 // GetCount queries and returns the count from database.
 func (bs *dbBase) GetCount(query string, args ...interface{}) (int, error) {
 	// If the query fields do not contains function "COUNT",
@@ -242,42 +657,543 @@ func (bs *dbBase) GetCount(query string, args ...interface{}) (int, error) {
 	return value.Int(), nil
 }
 
+// Explain queries and returns the execution plan of <query> on slave node, which prefixes <query>
+// with the "EXPLAIN" statement of the driver, eg: "EXPLAIN" of mysql and pgsql, "EXPLAIN QUERY PLAN"
+// of sqlite. The <query> is not executed, and only the SELECT/INSERT/UPDATE/DELETE statements are
+// supported. It returns error if the driver does not support "EXPLAIN", eg: mssql and oracle.
+func (bs *dbBase) Explain(query string, args ...interface{}) (Result, error) {
+	explainSql, err := bs.db.getExplainSql(query)
+	if err != nil {
+		return nil, err
+	}
+	return bs.db.doGetAll(nil, explainSql, args...)
+}
+
+// getExplainSql returns the statement querying the execution plan of <query>, which is
+// "EXPLAIN <query>" in default. Also see Explain.
+func (bs *dbBase) getExplainSql(query string) (string, error) {
+	if err := checkExplainable(query); err != nil {
+		return "", err
+	}
+	return "EXPLAIN " + query, nil
+}
+
+// checkExplainable checks whether <query> is the statement supported by "EXPLAIN",
+// which guards the other statements from being executed by the drivers not supporting them.
+func checkExplainable(query string) error {
+	if !explainableReg.MatchString(query) {
+		return errors.New(fmt.Sprintf("statement is not supported by EXPLAIN: %s", query))
+	}
+	return nil
+}
+
+// CountTable queries and returns the count of records of <table> matching <condition>,
+// which builds statement "SELECT COUNT(1) FROM <table> WHERE ...", eg:
+// db.CountTable("user", "status=?", 1), db.CountTable("user", g.Map{"status": 1}).
+//
+// The parameter <condition> is the same as the one of Update/Delete.
+func (bs *dbBase) CountTable(table string, condition interface{}, args ...interface{}) (int, error) {
+	link, err := bs.db.Slave()
+	if err != nil {
+		return 0, err
+	}
+	value, err := doAggregate(bs.db, link, table, "COUNT(1)", condition, args...)
+	if err != nil {
+		return 0, err
+	}
+	return value.Int(), nil
+}
+
+// CountDistinct queries and returns the count of distinct non-NULL values of <column> of
+// the records of <table> matching <condition>, which builds statement
+// "SELECT COUNT(DISTINCT <column>) FROM <table> WHERE ...".
+//
+// The parameter <condition> is the same as the one of Update/Delete.
+func (bs *dbBase) CountDistinct(table string, column string, condition interface{}, args ...interface{}) (int, error) {
+	link, err := bs.db.Slave()
+	if err != nil {
+		return 0, err
+	}
+	expression := fmt.Sprintf("COUNT(DISTINCT %s)", bs.db.quoteWord(column))
+	value, err := doAggregate(bs.db, link, table, expression, condition, args...)
+	if err != nil {
+		return 0, err
+	}
+	return value.Int(), nil
+}
+
+// Sum queries and returns the sum of <column> of the records of <table> matching <condition>,
+// which builds statement "SELECT SUM(<column>) FROM <table> WHERE ...".
+// The result is a Value of nil if there's no matched record, of which Int/Float returns 0.
+//
+// The parameter <condition> is the same as the one of Update/Delete.
+func (bs *dbBase) Sum(table string, column string, condition interface{}, args ...interface{}) (Value, error) {
+	return bs.aggregate("SUM", table, column, condition, args...)
+}
+
+// Avg queries and returns the average of <column> of the records of <table> matching <condition>.
+// Also see Sum.
+func (bs *dbBase) Avg(table string, column string, condition interface{}, args ...interface{}) (Value, error) {
+	return bs.aggregate("AVG", table, column, condition, args...)
+}
+
+// Min queries and returns the minimum of <column> of the records of <table> matching <condition>.
+// Also see Sum.
+func (bs *dbBase) Min(table string, column string, condition interface{}, args ...interface{}) (Value, error) {
+	return bs.aggregate("MIN", table, column, condition, args...)
+}
+
+// Max queries and returns the maximum of <column> of the records of <table> matching <condition>.
+// Also see Sum.
+func (bs *dbBase) Max(table string, column string, condition interface{}, args ...interface{}) (Value, error) {
+	return bs.aggregate("MAX", table, column, condition, args...)
+}
+
+// aggregate queries and returns the value of aggregate <function> over the quoted <column>
+// of the records of <table> matching <condition> on slave node.
+func (bs *dbBase) aggregate(function string, table string, column string, condition interface{}, args ...interface{}) (Value, error) {
+	link, err := bs.db.Slave()
+	if err != nil {
+		return nil, err
+	}
+	expression := fmt.Sprintf("%s(%s)", function, bs.db.quoteWord(column))
+	return doAggregate(bs.db, link, table, expression, condition, args...)
+}
+
+// doAggregate queries and returns the value of aggregate <expression> over the records of <table>
+// matching <condition> using <link>, eg: "COUNT(1)". The NULL result is returned as a Value of nil.
+func doAggregate(db DB, link dbLink, table string, expression string, condition interface{}, args ...interface{}) (Value, error) {
+	newWhere, newArgs := formatWhere(db, condition, args, false)
+	if newWhere != "" {
+		newWhere = " WHERE " + newWhere
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s%s", expression, db.handleTableName(table), newWhere)
+	array, err := doGetArray(db, link, query, newArgs...)
+	if err != nil {
+		return nil, err
+	}
+	if len(array) == 0 || array[0] == nil {
+		return gvar.New(nil), nil
+	}
+	return array[0], nil
+}
+
+// Paginate queries and returns one page of records from database along with the total count
+// of records that the <query> matches.
+//
+// The parameter <page> is started from 1 for paging, and <size> specifies the record count of
+// each page. The limit statement is appended to <query> using the syntax of current driver,
+// and the total count is queried with <query> as sub query, in which its trailing ORDER BY
+// clause is removed.
+func (bs *dbBase) Paginate(query string, page int, size int, args ...interface{}) (result Result, total int, err error) {
+	link, err := bs.db.Slave()
+	if err != nil {
+		return nil, 0, err
+	}
+	return doPaginate(bs.db, link, query, page, size, args...)
+}
+
+// doPaginate queries and returns one page of records along with the total count using <link>.
+// Also see Paginate.
+func doPaginate(db DB, link dbLink, query string, page int, size int, args ...interface{}) (result Result, total int, err error) {
+	if size <= 0 {
+		return nil, 0, errors.New(fmt.Sprintf("invalid page size: %d", size))
+	}
+	if page <= 0 {
+		page = 1
+	}
+	// The ORDER BY clause is useless for counting and rejected in sub query by mssql.
+	countResult, err := db.doGetAll(link, fmt.Sprintf("SELECT COUNT(1) FROM (%s) count_alias", removeOrderBy(query)), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(countResult) > 0 {
+		for _, v := range countResult[0] {
+			total = v.Int()
+		}
+	}
+	if total == 0 {
+		return nil, 0, nil
+	}
+	result, err = db.doGetAll(link, query+db.getLimit((page-1)*size, size), args...)
+	return result, total, err
+}
+
+// Chunk queries and processes the records of <query> page by page with <size> records per page
+// using "LIMIT ... OFFSET ...", which avoids loading all the records of a large table into memory.
+// The <callback> is called with each page until the page has fewer than <size> records, and
+// returning error from <callback> stops the iteration with the error returned.
+//
+// The <query> should have "ORDER BY" clause on unique columns for stable pages, and the pages
+// become slower as the offset grows. Use ChunkByKey for large tables instead.
+func (bs *dbBase) Chunk(size int, callback func(result Result) error, query string, args ...interface{}) error {
+	link, err := bs.db.Slave()
+	if err != nil {
+		return err
+	}
+	return doChunk(bs.db, link, size, "", callback, query, args...)
+}
+
+// ChunkByKey acts like Chunk, but it pages the records using keyset pagination on the unique
+// column <key>, eg: "id", which retrieves the next page by the <key> value of the last record
+// of the current page. Its performance does not decrease as the pages go deeper.
+//
+// The <query> is used as a subquery, so it should select column <key> and should not have
+// "ORDER BY" or "LIMIT" clause, eg: "SELECT * FROM user WHERE status=?".
+func (bs *dbBase) ChunkByKey(key string, size int, callback func(result Result) error, query string, args ...interface{}) error {
+	link, err := bs.db.Slave()
+	if err != nil {
+		return err
+	}
+	return doChunk(bs.db, link, size, key, callback, query, args...)
+}
+
+// doChunk queries and processes the records page by page using <link>, which uses keyset
+// pagination on column <key> if <key> is given, or else uses offset pagination.
+// Also see Chunk and ChunkByKey.
+func doChunk(db DB, link dbLink, size int, key string, callback func(result Result) error, query string, args ...interface{}) error {
+	if size <= 0 {
+		return errors.New(fmt.Sprintf("invalid chunk size: %d", size))
+	}
+	var (
+		page     = 0
+		last     interface{}
+		pageArgs []interface{}
+	)
+	for {
+		pageQuery := query + db.getLimit(page*size, size)
+		pageArgs = args
+		if key != "" {
+			column := db.quoteWord(key)
+			if last == nil {
+				pageQuery = fmt.Sprintf(
+					"SELECT * FROM (%s) chunk_alias ORDER BY %s%s", query, column, db.getLimit(0, size),
+				)
+			} else {
+				pageQuery = fmt.Sprintf(
+					"SELECT * FROM (%s) chunk_alias WHERE %s>? ORDER BY %s%s", query, column, column, db.getLimit(0, size),
+				)
+				pageArgs = make([]interface{}, 0, len(args)+1)
+				pageArgs = append(pageArgs, args...)
+				pageArgs = append(pageArgs, last)
+			}
+		}
+		result, err := db.doGetAll(link, pageQuery, pageArgs...)
+		if err != nil {
+			return err
+		}
+		if len(result) == 0 {
+			return nil
+		}
+		if key != "" {
+			v, ok := result[len(result)-1][key]
+			if !ok || v.IsNil() {
+				return errors.New(fmt.Sprintf(`invalid key column "%s" for chunking, which should be selected and not null`, key))
+			}
+			last = v.Val()
+		}
+		if err = callback(result); err != nil {
+			return err
+		}
+		if len(result) < size {
+			return nil
+		}
+		page++
+	}
+}
+
+// Union composes the subqueries with "UNION" and returns the query and its arguments,
+// which can be used by GetAll/GetOne, etc. The arguments of the subqueries are merged
+// in the order of the subqueries.
+//
+// The subquery can be type of *Model, *Sql (using its Sql and Args attributes) or string, eg:
+// Union(db.Table("user_2019").Fields("id,name").Where("status", 1), db.Table("user_2020").Fields("id,name"))
+//
+// Each subquery is wrapped as "SELECT * FROM (subquery) alias", which supports ORDER BY and
+// LIMIT statements in subquery for mysql, pgsql and sqlite. Note that mssql rejects ORDER BY
+// in a subquery unless it also contains TOP or OFFSET.
+// It returns error if any subquery is of unsupported type.
+func (bs *dbBase) Union(subs ...interface{}) (query string, args []interface{}, err error) {
+	return bs.doUnion("UNION", subs)
+}
+
+// UnionAll composes the subqueries with "UNION ALL" and returns the query and its arguments.
+// Also see Union.
+func (bs *dbBase) UnionAll(subs ...interface{}) (query string, args []interface{}, err error) {
+	return bs.doUnion("UNION ALL", subs)
+}
+
+// doUnion composes the subqueries with <operator>.
+func (bs *dbBase) doUnion(operator string, subs []interface{}) (query string, args []interface{}, err error) {
+	queries := make([]string, len(subs))
+	for i, sub := range subs {
+		var subQuery string
+		var subArgs []interface{}
+		switch v := sub.(type) {
+		case *Model:
+			subQuery, subArgs = v.getSelectSql(false)
+		case *Sql:
+			subQuery, subArgs = v.Sql, v.Args
+		case string:
+			subQuery = v
+		default:
+			return "", nil, errors.New(fmt.Sprintf(`unsupported subquery type "%T" for union`, sub))
+		}
+		// It expands the slice arguments here for the consistent arguments order.
+		subQuery, subArgs = handleArguments(subQuery, subArgs)
+		queries[i] = fmt.Sprintf("SELECT * FROM (%s) %s%d", subQuery, gUNION_ALIAS_PREFIX, i+1)
+		args = append(args, subArgs...)
+	}
+	return strings.Join(queries, " "+operator+" "), args, nil
+}
+
+// GetPageWithAggregates queries and returns one page of records from <from> along with the total
+// count and the aggregate values of all records matching the condition <where>, which is commonly
+// used for dashboards. The count query and the page query are executed in one read-only
+// transaction on slave node for consistent results, see Begin.
+//
+// The parameter <where> and <args> are the same as the condition of Update/Delete, which can also
+// contain "ORDER BY" clause for the page, eg: "status=? ORDER BY id DESC".
+// The parameter <aggregates> maps the alias to its aggregate expression, eg:
+// g.MapStrStr{"amount_sum": "SUM(amount)", "amount_avg": "AVG(amount)"}, and the returned
+// <values> contains the computed value of each alias. Note that the expressions are used
+// literally without any escaping, so never build them from user input.
+func (bs *dbBase) GetPageWithAggregates(page int, size int, from string, where interface{}, args []interface{}, aggregates map[string]string) (result Result, total int, values Record, err error) {
+	tx, err := bs.db.Begin(true)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	return doGetPageWithAggregates(bs.db, tx.link, page, size, from, where, args, aggregates)
+}
+
+// doGetPageWithAggregates queries and returns one page of records along with the total count and
+// the aggregate values using <link>. Also see GetPageWithAggregates.
+func doGetPageWithAggregates(db DB, link dbLink, page int, size int, from string, where interface{}, args []interface{}, aggregates map[string]string) (result Result, total int, values Record, err error) {
+	if size <= 0 {
+		return nil, 0, nil, errors.New(fmt.Sprintf("invalid page size: %d", size))
+	}
+	if page <= 0 {
+		page = 1
+	}
+	condition, conditionArgs := formatWhere(db, where, args, false)
+	if condition != "" {
+		if !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(condition)), "ORDER BY") {
+			condition = " WHERE " + condition
+		} else {
+			condition = " " + condition
+		}
+	}
+	fields := []string{"COUNT(1) " + db.quoteWord(gPAGE_TOTAL_ALIAS)}
+	for alias, expression := range aggregates {
+		fields = append(fields, expression+" "+db.quoteWord(alias))
+	}
+	table := db.handleTableName(from)
+	// The ORDER BY clause is useless for the aggregates and removed for compatibility.
+	countCondition := removeOrderBy(condition)
+	countResult, err := db.doGetAll(link, fmt.Sprintf(
+		"SELECT %s FROM %s%s", strings.Join(fields, ","), table, countCondition,
+	), conditionArgs...)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	values = make(Record, len(aggregates))
+	if len(countResult) > 0 {
+		for k, v := range countResult[0] {
+			if k == gPAGE_TOTAL_ALIAS {
+				total = v.Int()
+			} else {
+				values[k] = v
+			}
+		}
+	}
+	if total == 0 {
+		return nil, 0, values, nil
+	}
+	// The "ORDER BY" clause required by the limit statement of mssql is added by its handleSqlBeforeExec.
+	result, err = db.doGetAll(link, fmt.Sprintf(
+		"SELECT * FROM %s%s%s", table, condition, db.getLimit((page-1)*size, size),
+	), conditionArgs...)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return result, total, values, nil
+}
+
 // PingMaster pings the master node to check authentication or keeps the connection alive.
+// It fails if the ping is not done in the timeout, see SetPingTimeout.
 func (bs *dbBase) PingMaster() error {
+	ctx, cancel := context.WithTimeout(context.Background(), bs.getPingTimeout())
+	defer cancel()
+	return bs.db.PingMasterContext(ctx)
+}
+
+// PingSlave pings the slave node to check authentication or keeps the connection alive.
+// It fails if the ping is not done in the timeout, see SetPingTimeout.
+func (bs *dbBase) PingSlave() error {
+	ctx, cancel := context.WithTimeout(context.Background(), bs.getPingTimeout())
+	defer cancel()
+	return bs.db.PingSlaveContext(ctx)
+}
+
+// PingMasterContext pings the master node using context <ctx>, which can be used to
+// cancel the ping or set its deadline.
+func (bs *dbBase) PingMasterContext(ctx context.Context) error {
 	if master, err := bs.db.Master(); err != nil {
 		return err
 	} else {
-		return master.Ping()
+		return master.PingContext(ctx)
 	}
 }
 
-// PingSlave pings the slave node to check authentication or keeps the connection alive.
-func (bs *dbBase) PingSlave() error {
+// PingSlaveContext pings the slave node using context <ctx>, which can be used to
+// cancel the ping or set its deadline.
+func (bs *dbBase) PingSlaveContext(ctx context.Context) error {
 	if slave, err := bs.db.Slave(); err != nil {
 		return err
 	} else {
-		return slave.Ping()
+		return slave.PingContext(ctx)
+	}
+}
+
+// ClusterHealth pings the master and all the slave nodes of the configuration group, and returns
+// the health status of each node in the configured order, which is commonly used for the health
+// check endpoints. The nodes are pinged concurrently, each in the timeout, see SetPingTimeout.
+// The failed nodes are reported in the status without failing the others.
+func (bs *dbBase) ClusterHealth() []NodeHealth {
+	list, _ := getConfigGroup(bs.group)
+	healths := make([]NodeHealth, len(list))
+	wg := sync.WaitGroup{}
+	for i := range list {
+		node := list[i]
+		healths[i] = NodeHealth{
+			Node: node.String(),
+			Role: "master",
+		}
+		if node.Role == "slave" {
+			healths[i].Role = "slave"
+		}
+		// The connection objects are retrieved sequentially, which are opened lazily without connecting.
+		sqlDb, err := bs.getSqlDbByNode(&node, bs.schema.Val())
+		if err != nil {
+			healths[i].Error = err
+			continue
+		}
+		wg.Add(1)
+		go func(health *NodeHealth, sqlDb *sql.DB) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), bs.getPingTimeout())
+			defer cancel()
+			start := time.Now()
+			err := sqlDb.PingContext(ctx)
+			health.Latency = time.Since(start)
+			health.Ok = err == nil
+			health.Error = err
+		}(&healths[i], sqlDb)
+	}
+	wg.Wait()
+	return healths
+}
+
+// Version returns the version of the database server, eg: "5.7.30-log" of mysql, "3.31.1" of sqlite,
+// which is commonly used for gating the features by the server version. It queries the master node
+// in the first calling, and the version is cached for the later callings.
+func (bs *dbBase) Version() (string, error) {
+	if version := bs.version.Val(); version != "" {
+		return version, nil
+	}
+	master, err := bs.db.Master()
+	if err != nil {
+		return "", err
+	}
+	result, err := bs.db.doGetAll(master, bs.db.getVersionSql())
+	if err != nil {
+		return "", err
+	}
+	if len(result) == 0 {
+		return "", errors.New("no version retrieved from the database server")
 	}
+	for _, value := range result[0] {
+		bs.version.Set(value.String())
+	}
+	return bs.version.Val(), nil
 }
 
 // Begin starts and returns the transaction object.
 // You should call Commit or Rollback functions of the transaction object
 // if you no longer use the transaction. Commit or Rollback functions will also
 // close the transaction automatically.
-func (bs *dbBase) Begin() (*TX, error) {
-	if master, err := bs.db.Master(); err != nil {
+//
+// The optional parameter <readOnly> specifies starting a read-only transaction on slave node
+// if master-slave configured, which is commonly used for the long-running reports offloading
+// the master node. All the statements of the read-only transaction are executed on the same
+// slave node. The transaction is started in read-only access mode, so the database rejects any
+// writing statement, and the Exec statements like Insert/Update/Delete return ErrReadOnlyTx
+// without sending them to the database.
+func (bs *dbBase) Begin(readOnly ...bool) (*TX, error) {
+	if err := bs.inflight.acquire(); err != nil {
 		return nil, err
+	}
+	var (
+		isReadOnly = len(readOnly) > 0 && readOnly[0]
+		link       *sql.DB
+		err        error
+	)
+	if isReadOnly {
+		link, err = bs.db.Slave()
 	} else {
-		if tx, err := master.Begin(); err == nil {
-			return &TX{
-				db:     bs.db,
-				tx:     tx,
-				master: master,
-			}, nil
+		link, err = bs.db.Master()
+	}
+	if err != nil {
+		bs.inflight.release()
+		return nil, err
+	}
+	sqlTx, err := link.BeginTx(context.Background(), bs.db.getTxOptions(isReadOnly))
+	if err != nil {
+		bs.inflight.release()
+		return nil, err
+	}
+	tx := &TX{
+		db:       bs.db,
+		tx:       sqlTx,
+		link:     &txLink{Tx: sqlTx},
+		master:   link,
+		inflight: bs.inflight,
+	}
+	if isReadOnly {
+		tx.link = &readOnlyTxLink{sqlTx}
+	}
+	return tx, nil
+}
+
+// Transaction wraps the operations in <f> with a transaction. It commits the transaction if
+// <f> returns nil, or else it rolls back the transaction and returns the error of <f>.
+// It also rolls back the transaction if <f> panics, and then panics again.
+//
+// Use TX.Run in <f> for partial rollback of the transaction.
+func (bs *dbBase) Transaction(f func(tx *TX) error) (err error) {
+	tx, err := bs.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			tx.Rollback()
+			panic(e)
+		}
+		if err != nil {
+			tx.Rollback()
 		} else {
-			return nil, err
+			err = tx.Commit()
 		}
-	}
+	}()
+	return f(tx)
 }
 
 // Insert does "INSERT INTO ..." statement for the table.
@@ -295,6 +1211,7 @@ func (bs *dbBase) Insert(table string, data interface{}, batch ...int) (sql.Resu
 
 // InsertIgnore does "INSERT IGNORE INTO ..." statement for the table.
 // If there's already one unique record of the data in the table, it ignores the inserting.
+// It uses "INSERT OR IGNORE" for sqlite and "INSERT ... ON CONFLICT DO NOTHING" for pgsql.
 //
 // The parameter <data> can be type of map/gmap/struct/*struct/[]map/[]struct, etc.
 // Eg:
@@ -333,10 +1250,94 @@ func (bs *dbBase) Replace(table string, data interface{}, batch ...int) (sql.Res
 //
 // If given data is type of slice, it then does batch saving, and the optional parameter
 // <batch> specifies the batch operation count.
+//
+// For pgsql and sqlite, it uses "ON CONFLICT ... DO UPDATE" statement with all the columns of the
+// primary key as the conflict target, which supports composite primary key. Use InsertOnConflict
+// or Model.OnConflict to specify the conflict target explicitly.
 func (bs *dbBase) Save(table string, data interface{}, batch ...int) (sql.Result, error) {
 	return bs.db.doInsert(nil, table, data, gINSERT_OPTION_SAVE, batch...)
 }
 
+// InsertOnConflict does "INSERT ... ON CONFLICT (conflict) DO UPDATE SET ..." statement for the
+// table, which updates the existing record conflicting on the <conflict> columns with <data>,
+// or else inserts a new record. The <conflict> columns are not updated, and they should be
+// the columns of a primary key or unique index of the table, which is required by pgsql.
+//
+// It is supported by pgsql and sqlite(3.24.0+). For mysql it does the same as Save, as the conflict
+// target is determined by all the unique indexes of the table.
+//
+// The parameter <data> can be type of map/gmap/struct/*struct/[]map/[]struct, etc.
+// If given data is type of slice, it then does batch saving, and the optional parameter
+// <batch> specifies the batch operation count.
+func (bs *dbBase) InsertOnConflict(table string, data interface{}, conflict []string, batch ...int) (sql.Result, error) {
+	return bs.db.doInsert(nil, table, withConflict(data, conflict), gINSERT_OPTION_SAVE, batch...)
+}
+
+// SaveAndGetStatus does the same as Save except that it saves only single record, and returns
+// the saving status indicating whether the record is inserted or updated.
+//
+// The returned status is interpreted from the affected rows number by the driver:
+// mysql: 1 for SAVE_STATUS_INSERTED, 2 for SAVE_STATUS_UPDATED, and 0 for SAVE_STATUS_UNCHANGED;
+// pgsql: 1 for SAVE_STATUS_SAVED, as the affected rows number of both inserting and updating is 1.
+//
+// The parameter <data> can be type of map/gmap/struct/*struct.
+func (bs *dbBase) SaveAndGetStatus(table string, data interface{}) (int, error) {
+	return doSaveAndGetStatus(bs.db, nil, table, data)
+}
+
+// doSaveAndGetStatus saves single record and returns its saving status.
+// Also see SaveAndGetStatus.
+func doSaveAndGetStatus(db DB, link dbLink, table string, data interface{}) (int, error) {
+	switch reflect.Indirect(reflect.ValueOf(data)).Kind() {
+	case reflect.Map, reflect.Struct:
+	default:
+		return SAVE_STATUS_UNCHANGED, errors.New("only single record of map/struct is supported")
+	}
+	r, err := db.doInsert(link, table, data, gINSERT_OPTION_SAVE)
+	if err != nil {
+		return SAVE_STATUS_UNCHANGED, err
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return SAVE_STATUS_UNCHANGED, err
+	}
+	return db.getSaveStatus(n), nil
+}
+
+// getSaveStatus interprets the affected rows number <affected> of single record saving as
+// the saving status, using the "ON DUPLICATE KEY UPDATE" semantics of mysql in default.
+func (bs *dbBase) getSaveStatus(affected int64) int {
+	switch affected {
+	case 0:
+		return SAVE_STATUS_UNCHANGED
+	case 1:
+		return SAVE_STATUS_INSERTED
+	default:
+		return SAVE_STATUS_UPDATED
+	}
+}
+
+// getSaveCounts interprets the affected rows number <affected> of saving <count> records as the
+// counts of the inserted, updated and indistinguishable saved records, using the "ON DUPLICATE KEY
+// UPDATE" semantics of mysql in default, which assumes no unchanged record. Also see SaveResult.
+func (bs *dbBase) getSaveCounts(affected int64, count int) (inserted int64, updated int64, saved int64) {
+	if affected <= int64(count) {
+		return affected, 0, 0
+	}
+	updated = affected - int64(count)
+	if updated > int64(count) {
+		updated = int64(count)
+	}
+	return int64(count) - updated, updated, 0
+}
+
+// getSaveReturningSql returns the "RETURNING" clause of the saving statement, which returns one
+// boolean column for each record, which is true if the record is inserted or false if updated.
+// It returns empty string in default, which means the counts are derived by getSaveCounts.
+func (bs *dbBase) getSaveReturningSql() string {
+	return ""
+}
+
 // doInsert inserts or updates data for given table.
 //
 // The parameter <option> values are as follows:
@@ -346,10 +1347,10 @@ func (bs *dbBase) Save(table string, data interface{}, batch ...int) (sql.Result
 // 3: ignore:  if there's unique/primary key in the data, it ignores the inserting;
 func (bs *dbBase) doInsert(link dbLink, table string, data interface{}, option int, batch ...int) (result sql.Result, err error) {
 	var fields []string
-	var values []string
-	var params []interface{}
 	var dataMap Map
 	table = bs.db.handleTableName(table)
+	defer bs.clearTableCache(link, table)
+	data, ordered := getOrderedData(data)
 	rv := reflect.ValueOf(data)
 	kind := rv.Kind()
 	if kind == reflect.Ptr {
@@ -358,52 +1359,108 @@ func (bs *dbBase) doInsert(link dbLink, table string, data interface{}, option i
 	}
 	switch kind {
 	case reflect.Slice, reflect.Array:
-		return bs.db.doBatchInsert(link, table, data, option, batch...)
+		return bs.db.doBatchInsert(link, table, ordered.withData(data), option, batch...)
 	case reflect.Map, reflect.Struct:
-		dataMap = varToMapDeep(data)
+		if dataMap, err = varToMapDeep(data); err != nil {
+			return nil, err
+		}
 	default:
 		return result, errors.New(fmt.Sprint("unsupported data type:", kind))
 	}
+	dataMap = bs.filterColumnData(table, dataMap)
 	if len(dataMap) == 0 {
 		return nil, errors.New("data cannot be empty")
 	}
-	charL, charR := bs.db.getChars()
-	for k, v := range dataMap {
+	dataMap = bs.convertData(table, dataMap)
+	columns, err := getOrderedColumns(dataMap, ordered)
+	if err != nil {
+		return nil, err
+	}
+	charL, charR := bs.db.getQuoteChars()
+	for _, k := range columns {
 		fields = append(fields, charL+k+charR)
-		values = append(values, "?")
-		params = append(params, v)
 	}
-	operation := getInsertOperationByOption(option)
+	values, params := getRowHolder(dataMap, columns)
+	operation := bs.db.getInsertOperation(option)
 	updateStr := ""
-	if option == gINSERT_OPTION_SAVE {
-		for k, _ := range dataMap {
-			if len(updateStr) > 0 {
-				updateStr += ","
-			}
-			updateStr += fmt.Sprintf("%s%s%s=VALUES(%s%s%s)",
-				charL, k, charR,
-				charL, k, charR,
-			)
+	switch option {
+	case gINSERT_OPTION_SAVE:
+		if updateStr, err = bs.db.getSaveSql(table, columns, ordered.getConflict()); err != nil {
+			return nil, err
+		}
+	case gINSERT_OPTION_IGNORE:
+		updateStr = bs.db.getIgnoreSql(ordered.getConflict())
+	}
+	if link == nil {
+		if link, err = bs.db.Master(); err != nil {
+			return nil, err
 		}
-		updateStr = fmt.Sprintf("ON DUPLICATE KEY UPDATE %s", updateStr)
+	}
+	return bs.db.doExec(link, fmt.Sprintf("%s INTO %s(%s) VALUES%s %s",
+		operation, table, strings.Join(fields, ","),
+		values, updateStr),
+		params...)
+}
+
+// InsertSelect does "INSERT INTO table(columns) SELECT ..." statement, which inserts the records
+// queried by <selectQuery> into <table>, without round-tripping the data through the application.
+// The parameter <columns> specifies the target columns in the order of the selected columns,
+// which can be empty for all the columns of the table. The parameter <args> are the arguments
+// of the place holders in <selectQuery>. Eg:
+// InsertSelect("user_archive", []string{"id", "name"}, "SELECT id, name FROM user WHERE status=?", 0)
+func (bs *dbBase) InsertSelect(table string, columns []string, selectQuery string, args ...interface{}) (sql.Result, error) {
+	return bs.db.doInsertSelect(nil, table, columns, selectQuery, args...)
+}
+
+// doInsertSelect does "INSERT INTO table(columns) SELECT ..." statement for the table.
+// Also see InsertSelect.
+func (bs *dbBase) doInsertSelect(link dbLink, table string, columns []string, selectQuery string, args ...interface{}) (result sql.Result, err error) {
+	if strings.TrimSpace(selectQuery) == "" {
+		return nil, errors.New("select query cannot be empty")
 	}
 	if link == nil {
 		if link, err = bs.db.Master(); err != nil {
 			return nil, err
 		}
 	}
-	return bs.db.doExec(link, fmt.Sprintf("%s INTO %s(%s) VALUES(%s) %s",
-		operation, table, strings.Join(fields, ","),
-		strings.Join(values, ","), updateStr),
-		params...)
+	table = bs.db.handleTableName(table)
+	defer bs.clearTableCache(link, table)
+	fields := ""
+	if len(columns) > 0 {
+		quoted := make([]string, len(columns))
+		for i, column := range columns {
+			quoted[i] = bs.db.quoteWord(column)
+		}
+		fields = "(" + strings.Join(quoted, ",") + ")"
+	}
+	return bs.db.doExec(link, fmt.Sprintf("INSERT INTO %s%s %s", table, fields, selectQuery), args...)
 }
 
 // BatchInsert batch inserts data.
 // The parameter <list> must be type of slice of map or struct.
+//
+// Note that each chunk of <batch> records is inserted by a separate statement which is committed
+// individually, so a failed chunk leaves the previous chunks inserted, which bounds the size of
+// the transaction for very large batch. Use BatchInsertTx for inserting atomically.
 func (bs *dbBase) BatchInsert(table string, list interface{}, batch ...int) (sql.Result, error) {
 	return bs.db.doBatchInsert(nil, table, list, gINSERT_OPTION_DEFAULT, batch...)
 }
 
+// BatchInsertTx batch inserts data like BatchInsert, but all the chunks are inserted within
+// one transaction, so that the batch is atomic, which is rolled back if any chunk fails.
+// The parameter <list> must be type of slice of map or struct.
+func (bs *dbBase) BatchInsertTx(table string, list interface{}, batch ...int) (sql.Result, error) {
+	var result sql.Result
+	err := bs.db.Transaction(func(tx *TX) (err error) {
+		result, err = tx.BatchInsert(table, list, batch...)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 // BatchInsert batch inserts data with ignore option.
 // The parameter <list> must be type of slice of map or struct.
 func (bs *dbBase) BatchInsertIgnore(table string, list interface{}, batch ...int) (sql.Result, error) {
@@ -422,43 +1479,190 @@ func (bs *dbBase) BatchSave(table string, list interface{}, batch ...int) (sql.R
 	return bs.db.doBatchInsert(nil, table, list, gINSERT_OPTION_SAVE, batch...)
 }
 
+// BatchSaveAndGetIds batch saves <list> and returns the primary key values of all the records
+// in the order of <list>, no matter whether they are inserted or updated.
+//
+// The parameter <primary> specifies the primary key column, which should be auto-increment
+// or integer type. The parameter <keys> specifies the unique columns identifying the records,
+// which are required in each item of <list>. It uses <primary> as the unique column if <keys>
+// is not given.
+//
+// It uses "RETURNING" clause for pgsql. For other databases it does the saving and then queries
+// the primary key values by <keys> in a transaction, which costs an extra SELECT statement per
+// batch of records.
+func (bs *dbBase) BatchSaveAndGetIds(table string, list interface{}, primary string, keys ...string) (ids []int64, err error) {
+	tx, err := bs.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	return bs.db.doBatchSaveAndGetIds(tx.link, table, list, primary, keys)
+}
+
+// doBatchSaveAndGetIds batch saves <list> and then queries the primary key values of the records.
+// Also see BatchSaveAndGetIds.
+func (bs *dbBase) doBatchSaveAndGetIds(link dbLink, table string, list interface{}, primary string, keys []string) ([]int64, error) {
+	listMap, err := varToList(list)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		keys = []string{primary}
+	}
+	if err = checkListKeys(listMap, keys); err != nil {
+		return nil, err
+	}
+	if _, err = bs.db.doBatchInsert(link, table, listMap, gINSERT_OPTION_SAVE); err != nil {
+		return nil, err
+	}
+	var (
+		ids      = make([]int64, len(listMap))
+		fields   = make([]string, len(keys))
+		holders  = make([]string, len(keys))
+		idColumn = bs.db.quoteWord(primary)
+	)
+	for i, k := range keys {
+		fields[i] = bs.db.quoteWord(k)
+		holders[i] = fields[i] + "=?"
+	}
+	condition := "(" + strings.Join(holders, " AND ") + ")"
+	batchNum := bs.getBatchNum(len(keys), nil)
+	for start := 0; start < len(listMap); start += batchNum {
+		end := start + batchNum
+		if end > len(listMap) {
+			end = len(listMap)
+		}
+		conditions := make([]string, 0, end-start)
+		params := make([]interface{}, 0, (end-start)*len(keys))
+		for _, item := range listMap[start:end] {
+			conditions = append(conditions, condition)
+			for _, k := range keys {
+				params = append(params, item[k])
+			}
+		}
+		result, err := bs.db.doGetAll(link, fmt.Sprintf(
+			"SELECT %s AS %s,%s FROM %s WHERE %s",
+			idColumn, bs.db.quoteWord(gSAVE_ID_ALIAS), strings.Join(fields, ","),
+			bs.db.handleTableName(table), strings.Join(conditions, " OR "),
+		), params...)
+		if err != nil {
+			return nil, err
+		}
+		idMap := make(map[string]int64, len(result))
+		for _, record := range result {
+			idMap[getListKeyValue(record.Map(), keys)] = record[gSAVE_ID_ALIAS].Int64()
+		}
+		for i := start; i < end; i++ {
+			id, ok := idMap[getListKeyValue(listMap[i], keys)]
+			if !ok {
+				return nil, errors.New(fmt.Sprintf(`saved record not found by keys: %s`, strings.Join(keys, ",")))
+			}
+			ids[i] = id
+		}
+	}
+	return ids, nil
+}
+
+// BatchInsertAndGetIds batch inserts <list> and returns the primary key values of all the records
+// in the order of <list>, which is commonly used for associating the child records.
+//
+// The parameter <primary> specifies the primary key column. The values of <primary> in the items of
+// <list> are returned directly, or else the auto-increment values generated by database are returned.
+// Note that empty value like 0 of <primary> is treated as generated, and it returns error if some
+// items of <list> have the value of <primary> but others not.
+// The optional parameter <batch> specifies the record count of each inserting statement.
+//
+// It uses "RETURNING" clause for pgsql. For mysql, it computes the values from the first value
+// generated by each statement, which requires the auto-increment values of the statement are
+// consecutive, that is the default for innodb_autoinc_lock_mode 0 and 1. For sqlite, it computes
+// the values from the last generated value of each statement.
+//
+// Note that all the items of <list> should have the same columns, as the inserting columns are
+// determined by the first item.
+func (bs *dbBase) BatchInsertAndGetIds(table string, list interface{}, primary string, batch ...int) (ids []int64, err error) {
+	tx, err := bs.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	return bs.db.doBatchInsertAndGetIds(tx.link, table, list, primary, batch...)
+}
+
+// doBatchInsertAndGetIds batch inserts <list> and computes the primary key values of the records
+// from the generated values of each inserting statement. Also see BatchInsertAndGetIds.
+func (bs *dbBase) doBatchInsertAndGetIds(link dbLink, table string, list interface{}, primary string, batch ...int) ([]int64, error) {
+	list, ids, err := getPrimaryIds(list, primary)
+	if err != nil {
+		return nil, err
+	}
+	if ids != nil {
+		_, err = bs.db.doBatchInsert(link, table, list, gINSERT_OPTION_DEFAULT, batch...)
+		return ids, err
+	}
+	list, ordered := getOrderedData(list)
+	listMap, err := varToList(list)
+	if err != nil {
+		return nil, err
+	}
+	batchNum := bs.getBatchNum(len(listMap[0]), batch)
+	ids = make([]int64, 0, len(listMap))
+	for start := 0; start < len(listMap); start += batchNum {
+		end := start + batchNum
+		if end > len(listMap) {
+			end = len(listMap)
+		}
+		// The items are inserted in one statement as its size is not more than <batchNum>.
+		result, err := bs.db.doBatchInsert(link, table, ordered.withData(listMap[start:end]), gINSERT_OPTION_DEFAULT, batchNum)
+		if err != nil {
+			return nil, err
+		}
+		generated, err := bs.db.getInsertIds(result, end-start)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, generated...)
+	}
+	return ids, nil
+}
+
+// getBatchData converts <list> to List for batch inserting into <table>, which filters and converts
+// the values of each item for the columns of <table>. It also returns the column order of <list>.
+func (bs *dbBase) getBatchData(table string, list interface{}) (List, *orderedData, error) {
+	list, ordered := getOrderedData(list)
+	listMap, err := varToList(list)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(listMap) < 1 {
+		return nil, nil, errors.New("data list cannot be empty")
+	}
+	for i, v := range listMap {
+		listMap[i] = bs.convertData(table, bs.filterColumnData(table, v))
+	}
+	return listMap, ordered, nil
+}
+
 // doBatchInsert batch inserts/replaces/saves data.
 func (bs *dbBase) doBatchInsert(link dbLink, table string, list interface{}, option int, batch ...int) (result sql.Result, err error) {
 	var keys, values []string
 	var params []interface{}
 	table = bs.db.handleTableName(table)
-	listMap := (List)(nil)
-	switch v := list.(type) {
-	case Result:
-		listMap = v.List()
-	case Record:
-		listMap = List{v.Map()}
-	case List:
-		listMap = v
-	case Map:
-		listMap = List{v}
-	default:
-		rv := reflect.ValueOf(list)
-		kind := rv.Kind()
-		if kind == reflect.Ptr {
-			rv = rv.Elem()
-			kind = rv.Kind()
-		}
-		switch kind {
-		// If it's slice type, it then converts it to List type.
-		case reflect.Slice, reflect.Array:
-			listMap = make(List, rv.Len())
-			for i := 0; i < rv.Len(); i++ {
-				listMap[i] = varToMapDeep(rv.Index(i).Interface())
-			}
-		case reflect.Map, reflect.Struct:
-			listMap = List{varToMapDeep(list)}
-		default:
-			return result, errors.New(fmt.Sprint("unsupported list type:", kind))
-		}
-	}
-	if len(listMap) < 1 {
-		return result, errors.New("data list cannot be empty")
+	defer bs.clearTableCache(link, table)
+	listMap, ordered, err := bs.getBatchData(table, list)
+	if err != nil {
+		return nil, err
 	}
 	if link == nil {
 		if link, err = bs.db.Master(); err != nil {
@@ -466,44 +1670,69 @@ func (bs *dbBase) doBatchInsert(link dbLink, table string, list interface{}, opt
 		}
 	}
 	// Handle the field names and place holders.
-	holders := []string(nil)
-	for k, _ := range listMap[0] {
-		keys = append(keys, k)
-		holders = append(holders, "?")
+	if keys, err = getOrderedColumns(listMap[0], ordered); err != nil {
+		return nil, err
 	}
 	// Prepare the result pointer.
 	batchResult := new(batchSqlResult)
-	charL, charR := bs.db.getChars()
+	charL, charR := bs.db.getQuoteChars()
 	keysStr := charL + strings.Join(keys, charR+","+charL) + charR
-	valueHolderStr := "(" + strings.Join(holders, ",") + ")"
 
-	operation := getInsertOperationByOption(option)
+	operation := bs.db.getInsertOperation(option)
 	updateStr := ""
-	if option == gINSERT_OPTION_SAVE {
-		for _, k := range keys {
-			if len(updateStr) > 0 {
-				updateStr += ","
-			}
-			updateStr += fmt.Sprintf("%s%s%s=VALUES(%s%s%s)",
-				charL, k, charR,
-				charL, k, charR,
-			)
+	switch option {
+	case gINSERT_OPTION_SAVE:
+		if updateStr, err = bs.db.getSaveSql(table, keys, ordered.getConflict()); err != nil {
+			return nil, err
 		}
-		updateStr = fmt.Sprintf("ON DUPLICATE KEY UPDATE %s", updateStr)
+	case gINSERT_OPTION_IGNORE:
+		updateStr = bs.db.getIgnoreSql(ordered.getConflict())
 	}
-	batchNum := gDEFAULT_BATCH_NUM
-	if len(batch) > 0 && batch[0] > 0 {
-		batchNum = batch[0]
+	// The driver counting the inserted and updated records with "RETURNING" clause.
+	returning := ""
+	if option == gINSERT_OPTION_SAVE {
+		returning = bs.db.getSaveReturningSql()
 	}
+	batchNum := bs.getBatchNum(len(keys), batch)
 	listMapLen := len(listMap)
 	for i := 0; i < listMapLen; i++ {
 		// Note that the map type is unordered,
 		// so it should use slice+key to retrieve the value.
-		for _, k := range keys {
-			params = append(params, listMap[i][k])
-		}
-		values = append(values, valueHolderStr)
+		holder, rowParams := getRowHolder(listMap[i], keys)
+		params = append(params, rowParams...)
+		values = append(values, holder)
 		if len(values) == batchNum || (i == listMapLen-1 && len(values) > 0) {
+			if returning != "" {
+				rows, err := bs.db.doGetAll(
+					link,
+					fmt.Sprintf(
+						"%s INTO %s(%s) VALUES%s %s%s",
+						operation,
+						table,
+						keysStr,
+						strings.Join(values, ","),
+						updateStr,
+						returning,
+					),
+					params...,
+				)
+				if err != nil {
+					return nil, err
+				}
+				for _, row := range rows {
+					for _, v := range row {
+						if v.Bool() {
+							batchResult.insertedRows++
+						} else {
+							batchResult.updatedRows++
+						}
+					}
+				}
+				batchResult.rowsAffected += int64(len(rows))
+				params = params[:0]
+				values = values[:0]
+				continue
+			}
 			r, err := bs.db.doExec(
 				link,
 				fmt.Sprintf(
@@ -524,6 +1753,12 @@ func (bs *dbBase) doBatchInsert(link dbLink, table string, list interface{}, opt
 			} else {
 				batchResult.lastResult = r
 				batchResult.rowsAffected += n
+				if option == gINSERT_OPTION_SAVE {
+					inserted, updated, saved := bs.db.getSaveCounts(n, len(values))
+					batchResult.insertedRows += inserted
+					batchResult.updatedRows += updated
+					batchResult.savedRows += saved
+				}
 			}
 			params = params[:0]
 			values = values[:0]
@@ -546,6 +1781,11 @@ func (bs *dbBase) doBatchInsert(link dbLink, table string, list interface{}, opt
 // "status IN (?)", g.Slice{1,2,3}
 // "age IN(?,?)", 18, 50
 // User{ Id : 1, UserName : "john"}
+//
+// Note that the nil value in map/struct <data> sets the column NULL, eg:
+// g.Map{"nickname": nil} produces "SET `nickname`=NULL". To leave a column untouched,
+// just do not put it in <data>. The value of type Raw is used as the sql expression
+// literally, eg: g.Map{"views": gdb.Raw("views+1")} produces "SET `views`=views+1".
 func (bs *dbBase) Update(table string, data interface{}, condition interface{}, args ...interface{}) (sql.Result, error) {
 	newWhere, newArgs := formatWhere(bs.db, condition, args, false)
 	if newWhere != "" {
@@ -554,10 +1794,17 @@ func (bs *dbBase) Update(table string, data interface{}, condition interface{},
 	return bs.db.doUpdate(nil, table, data, newWhere, newArgs...)
 }
 
+// UpdateAndCount does "UPDATE ... " statement for the table like Update,
+// but returns the number of the affected rows.
+func (bs *dbBase) UpdateAndCount(table string, data interface{}, condition interface{}, args ...interface{}) (int64, error) {
+	return getRowsAffected(bs.db.Update(table, data, condition, args...))
+}
+
 // doUpdate does "UPDATE ... " statement for the table.
 // Also see Update.
 func (bs *dbBase) doUpdate(link dbLink, table string, data interface{}, condition string, args ...interface{}) (result sql.Result, err error) {
 	table = bs.db.handleTableName(table)
+	defer bs.clearTableCache(link, table)
 	updates := ""
 	rv := reflect.ValueOf(data)
 	kind := rv.Kind()
@@ -569,7 +1816,24 @@ func (bs *dbBase) doUpdate(link dbLink, table string, data interface{}, conditio
 	switch kind {
 	case reflect.Map, reflect.Struct:
 		var fields []string
-		for k, v := range varToMapDeep(data) {
+		dataMap, err := varToMapDeep(data)
+		if err != nil {
+			return nil, err
+		}
+		dataMap = bs.convertData(table, bs.filterColumnData(table, dataMap))
+		// The columns are sorted to produce the same statement for the same data.
+		columns, _ := getOrderedColumns(dataMap, nil)
+		for _, k := range columns {
+			v := dataMap[k]
+			// Nil value sets the column NULL directly, as some drivers reject nil parameter.
+			if isNilValue(v) {
+				fields = append(fields, bs.db.quoteWord(k)+"=NULL")
+				continue
+			}
+			if raw, ok := v.(Raw); ok {
+				fields = append(fields, bs.db.quoteWord(k)+"="+string(raw))
+				continue
+			}
 			fields = append(fields, bs.db.quoteWord(k)+"=?")
 			params = append(params, v)
 		}
@@ -583,6 +1847,9 @@ func (bs *dbBase) doUpdate(link dbLink, table string, data interface{}, conditio
 	if len(params) > 0 {
 		args = append(params, args...)
 	}
+	if err = bs.checkFullTableOps("UPDATE", table, condition); err != nil {
+		return nil, err
+	}
 	// If no link passed, it then uses the master link.
 	if link == nil {
 		if link, err = bs.db.Master(); err != nil {
@@ -592,6 +1859,89 @@ func (bs *dbBase) doUpdate(link dbLink, table string, data interface{}, conditio
 	return bs.db.doExec(link, fmt.Sprintf("UPDATE %s SET %s%s", table, updates, condition), args...)
 }
 
+// BatchUpdate updates <column> of multiple records with different values in batch, using
+// "UPDATE ... SET column=CASE key WHEN ? THEN ? ... END WHERE key IN(...)" statement.
+//
+// The parameter <key> specifies the column identifying the records, which is commonly the
+// primary key of the table. The parameter <data> should be type of map, of which the map key
+// is the value of <key> column and the map value is the new value of <column>.
+// Eg: BatchUpdate("user", "score", "id", g.MapIntInt{1: 100, 2: 99})
+//
+// The optional parameter <batch> specifies the record count of each update statement.
+func (bs *dbBase) BatchUpdate(table string, column string, key string, data interface{}, batch ...int) (sql.Result, error) {
+	return bs.db.doBatchUpdate(nil, table, column, key, data, batch...)
+}
+
+// doBatchUpdate does "UPDATE ... SET column=CASE ..." statement for the table in batch.
+// Also see BatchUpdate.
+func (bs *dbBase) doBatchUpdate(link dbLink, table string, column string, key string, data interface{}, batch ...int) (result sql.Result, err error) {
+	rv := reflect.ValueOf(data)
+	kind := rv.Kind()
+	if kind == reflect.Ptr {
+		rv = rv.Elem()
+		kind = rv.Kind()
+	}
+	if kind != reflect.Map {
+		return nil, errors.New(fmt.Sprint("unsupported data type:", kind))
+	}
+	if rv.Len() == 0 {
+		return nil, errors.New("data cannot be empty")
+	}
+	// The map keys are sorted to produce the same sql for the same data.
+	mapKeys := rv.MapKeys()
+	sort.Slice(mapKeys, func(i, j int) bool {
+		return gconv.String(mapKeys[i].Interface()) < gconv.String(mapKeys[j].Interface())
+	})
+	if link == nil {
+		if link, err = bs.db.Master(); err != nil {
+			return nil, err
+		}
+	}
+	table = bs.db.handleTableName(table)
+	defer bs.clearTableCache(link, table)
+	column = bs.db.quoteWord(column)
+	key = bs.db.quoteWord(key)
+	// Each record uses three place holders: two for the CASE clause and one for the IN clause.
+	batchNum := bs.getBatchNum(3, batch)
+	var (
+		cases       []string
+		holders     []string
+		caseParams  []interface{}
+		whereParams []interface{}
+		batchResult = new(batchSqlResult)
+	)
+	for i, k := range mapKeys {
+		cases = append(cases, "WHEN ? THEN ?")
+		holders = append(holders, "?")
+		caseParams = append(caseParams, k.Interface(), rv.MapIndex(k).Interface())
+		whereParams = append(whereParams, k.Interface())
+		if len(holders) == batchNum || i == len(mapKeys)-1 {
+			r, err := bs.db.doExec(
+				link,
+				fmt.Sprintf(
+					"UPDATE %s SET %s=CASE %s %s END WHERE %s IN(%s)",
+					table, column, key, strings.Join(cases, " "), key, strings.Join(holders, ","),
+				),
+				append(caseParams, whereParams...)...,
+			)
+			if err != nil {
+				return r, err
+			}
+			if n, err := r.RowsAffected(); err != nil {
+				return r, err
+			} else {
+				batchResult.lastResult = r
+				batchResult.rowsAffected += n
+			}
+			cases = cases[:0]
+			holders = holders[:0]
+			caseParams = caseParams[:0]
+			whereParams = whereParams[:0]
+		}
+	}
+	return batchResult, nil
+}
+
 // Delete does "DELETE FROM ... " statement for the table.
 //
 // The parameter <condition> can be type of string/map/gmap/slice/struct/*struct, etc.
@@ -611,6 +1961,46 @@ func (bs *dbBase) Delete(table string, condition interface{}, args ...interface{
 	return bs.db.doDelete(nil, table, newWhere, newArgs...)
 }
 
+// DeleteAndCount does "DELETE FROM ... " statement for the table like Delete,
+// but returns the number of the affected rows.
+func (bs *dbBase) DeleteAndCount(table string, condition interface{}, args ...interface{}) (int64, error) {
+	return getRowsAffected(bs.db.Delete(table, condition, args...))
+}
+
+// ReplaceSet replaces the record set of <table> matching <condition> with <list> in a transaction.
+// It deletes the records matching <condition> and then batch inserts <list>, and rolls back
+// both if any of them fails. It is commonly used to replace the child records of a parent record.
+//
+// The parameter <condition> is the same as the one of Delete, and the parameter <list> is the same
+// as the one of BatchInsert. It only deletes the matched records if <list> is empty.
+func (bs *dbBase) ReplaceSet(table string, condition interface{}, list interface{}) (err error) {
+	tx, err := bs.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	return tx.ReplaceSet(table, condition, list)
+}
+
+// Truncate removes all records of <table> using "TRUNCATE TABLE" statement on master node,
+// or "DELETE FROM" statement if TRUNCATE is not supported by the driver.
+// The table name is handled with the configured prefix and quote chars like Insert/Delete.
+func (bs *dbBase) Truncate(table string) (result sql.Result, err error) {
+	link, err := bs.db.Master()
+	if err != nil {
+		return nil, err
+	}
+	table = bs.db.handleTableName(table)
+	defer bs.clearTableCache(link, table)
+	return bs.db.doExec(link, bs.db.getTruncateSql(table))
+}
+
 // doDelete does "DELETE FROM ... " statement for the table.
 // Also see Delete.
 func (bs *dbBase) doDelete(link dbLink, table string, condition string, args ...interface{}) (result sql.Result, err error) {
@@ -620,9 +2010,27 @@ func (bs *dbBase) doDelete(link dbLink, table string, condition string, args ...
 		}
 	}
 	table = bs.db.handleTableName(table)
+	defer bs.clearTableCache(link, table)
+	if err = bs.checkFullTableOps("DELETE", table, condition); err != nil {
+		return nil, err
+	}
 	return bs.db.doExec(link, fmt.Sprintf("DELETE FROM %s%s", table, condition), args...)
 }
 
+// checkFullTableOps checks the <operation> on <table>, which affects all records of the table
+// if there's no WHERE statement in <condition>. It returns error if the full table operation
+// protection is enabled, or else it logs a warning.
+func (bs *dbBase) checkFullTableOps(operation string, table string, condition string) error {
+	if gregex.IsMatchString(`(?i)^\s*WHERE\s`, condition) {
+		return nil
+	}
+	if bs.protectFullTable.Val() {
+		return errors.New(fmt.Sprintf(`%s operation on table %s without WHERE condition is forbidden`, operation, table))
+	}
+	bs.logger.StackWithFilter(gPATH_FILTER_KEY).Warningf(`%s operation on table %s without WHERE condition`, operation, table)
+	return nil
+}
+
 // getCache returns the internal cache object.
 func (bs *dbBase) getCache() *gcache.Cache {
 	return bs.cache
@@ -633,8 +2041,185 @@ func (bs *dbBase) getPrefix() string {
 	return bs.prefix
 }
 
+// getLimit returns the limit statement for the driver, which fetches <limit> records
+// starting from the position <start>. It uses "LIMIT ... OFFSET ..." syntax in default,
+// which is supported by mysql, pgsql and sqlite.
+func (bs *dbBase) getLimit(start int, limit int) string {
+	if start > 0 {
+		return fmt.Sprintf(" LIMIT %d OFFSET %d", limit, start)
+	}
+	return fmt.Sprintf(" LIMIT %d", limit)
+}
+
+// getSavepointSql returns the statements creating savepoint <name>, rolling back to it and
+// releasing it. The <release> statement is empty if it is not supported by the driver.
+func (bs *dbBase) getSavepointSql(name string) (save string, rollback string, release string) {
+	return "SAVEPOINT " + name, "ROLLBACK TO SAVEPOINT " + name, "RELEASE SAVEPOINT " + name
+}
+
+// getTxOptions returns the options for starting transaction, which specifies the read-only
+// access mode if <readOnly> is true.
+func (bs *dbBase) getTxOptions(readOnly bool) *sql.TxOptions {
+	return &sql.TxOptions{ReadOnly: readOnly}
+}
+
+// getBatchNum returns the effective record count of each statement for batch operations.
+// The parameter <placeholders> specifies the place holder count of each record, and the
+// parameter <batch> is the optional batch count passed by caller.
+//
+// It uses the default batch count if <batch> is not given or <= 0, and it reduces the batch
+// count if the place holder count of one statement exceeds the max place holder count of the
+// driver, which is regardless of the default or explicit batch count.
+func (bs *dbBase) getBatchNum(placeholders int, batch []int) int {
+	batchNum := bs.batchNum.Val()
+	if batchNum <= 0 {
+		batchNum = gDEFAULT_BATCH_NUM
+	}
+	explicit := len(batch) > 0 && batch[0] > 0
+	if explicit {
+		batchNum = batch[0]
+	}
+	maxPlaceholders := bs.db.getMaxPlaceholders()
+	if placeholders > 0 && batchNum*placeholders > maxPlaceholders {
+		maxNum := maxPlaceholders / placeholders
+		if maxNum < 1 {
+			maxNum = 1
+		}
+		if explicit {
+			bs.logger.StackWithFilter(gPATH_FILTER_KEY).Warningf(
+				`batch count %d exceeds the max place holder count %d, it is reduced to %d`,
+				batchNum, maxPlaceholders, maxNum,
+			)
+		}
+		batchNum = maxNum
+	}
+	return batchNum
+}
+
+// getMaxPlaceholders returns the max place holder count in one statement, which is 65535
+// for both mysql and pgsql.
+func (bs *dbBase) getMaxPlaceholders() int {
+	return gMAX_PLACEHOLDER_COUNT
+}
+
+// getInsertIds returns the <count> auto-increment values generated by the inserting statement
+// of <result>. It computes the values from the first generated value, which is the LastInsertId
+// of mysql for multiple records inserting.
+func (bs *dbBase) getInsertIds(result sql.Result, count int) ([]int64, error) {
+	first, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, count)
+	for i := range ids {
+		ids[i] = first + int64(i)
+	}
+	return ids, nil
+}
+
+// getInsertOperation returns proper insert operation with given parameter <option>.
+func (bs *dbBase) getInsertOperation(option int) string {
+	switch option {
+	case gINSERT_OPTION_REPLACE:
+		return "REPLACE"
+	case gINSERT_OPTION_IGNORE:
+		return "INSERT IGNORE"
+	default:
+		return "INSERT"
+	}
+}
+
+// getIgnoreSql returns the clause of the inserting statement for ignoring the records conflicting
+// with the existing ones. It returns empty string in default, as the ignoring is done by the insert
+// operation like "INSERT IGNORE" of mysql and "INSERT OR IGNORE" of sqlite. See getInsertOperation.
+func (bs *dbBase) getIgnoreSql(conflict []string) string {
+	return ""
+}
+
+// getSaveSql returns the clause of the inserting statement for saving, which updates <columns>
+// of the existing record conflicting with the inserting one. The parameter <table> is handled
+// with prefix and quote chars already.
+//
+// It uses "ON DUPLICATE KEY UPDATE" clause in default, in which the conflict target is determined
+// by all the unique indexes of the table, so the parameter <conflict> is ignored.
+func (bs *dbBase) getSaveSql(table string, columns []string, conflict []string) (string, error) {
+	updates := make([]string, len(columns))
+	for i, k := range columns {
+		updates[i] = fmt.Sprintf("%s=VALUES(%s)", bs.db.quoteWord(k), bs.db.quoteWord(k))
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(updates, ","), nil
+}
+
+// getOnConflictSaveSql returns the "ON CONFLICT (conflict) DO UPDATE SET ..." clause for saving,
+// which updates the columns except the <conflict> ones. It uses the primary key of <table> as
+// the conflict target if <conflict> is empty. Also see getSaveSql.
+func (bs *dbBase) getOnConflictSaveSql(table string, columns []string, conflict []string) (string, error) {
+	if len(conflict) == 0 {
+		fields, err := bs.db.TableFields(table)
+		if err != nil {
+			return "", err
+		}
+		primaries := make([]*TableField, 0)
+		for _, field := range fields {
+			if gstr.ContainsI(field.Key, "pri") {
+				primaries = append(primaries, field)
+			}
+		}
+		sort.Slice(primaries, func(i, j int) bool {
+			return primaries[i].Index < primaries[j].Index
+		})
+		for _, field := range primaries {
+			conflict = append(conflict, field.Name)
+		}
+		if len(conflict) == 0 {
+			return "", errors.New(fmt.Sprintf(`no primary key found as the conflict target for saving on table %s`, table))
+		}
+	}
+	var (
+		targets = make([]string, len(conflict))
+		updates = make([]string, 0, len(columns))
+		keySet  = make(map[string]struct{}, len(conflict))
+	)
+	for i, k := range conflict {
+		targets[i] = bs.db.quoteWord(k)
+		keySet[k] = struct{}{}
+	}
+	for _, k := range columns {
+		if _, ok := keySet[k]; !ok {
+			updates = append(updates, fmt.Sprintf("%s=EXCLUDED.%s", bs.db.quoteWord(k), bs.db.quoteWord(k)))
+		}
+	}
+	if len(updates) == 0 {
+		return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", strings.Join(targets, ",")), nil
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(targets, ","), strings.Join(updates, ",")), nil
+}
+
+// getLockSql returns the locking clause for "SELECT" statement. The parameter <shared>
+// specifies whether it returns the shared lock clause or the exclusive one. It returns
+// error if the locking is not supported by the driver.
+func (bs *dbBase) getLockSql(shared bool) (string, error) {
+	if shared {
+		return " LOCK IN SHARE MODE", nil
+	}
+	return " FOR UPDATE", nil
+}
+
+// getTruncateSql returns the statement removing all records of <table>,
+// which is handled with prefix and quote chars already.
+func (bs *dbBase) getTruncateSql(table string) string {
+	return "TRUNCATE TABLE " + table
+}
+
+// getCollate returns the COLLATE clause for the driver using <collation>.
+// It uses unquoted collation name in default, which is supported by mysql, mssql and sqlite.
+func (bs *dbBase) getCollate(collation string) string {
+	return " COLLATE " + collation
+}
+
 // rowsToResult converts underlying data record type sql.Rows to Result type.
-func (bs *dbBase) rowsToResult(rows *sql.Rows) (Result, error) {
+// The parameter <query> is used for retrieving the registered money columns of its tables.
+func (bs *dbBase) rowsToResult(rows *sql.Rows, query string) (Result, error) {
 	if !rows.Next() {
 		return nil, nil
 	}
@@ -649,6 +2234,8 @@ func (bs *dbBase) rowsToResult(rows *sql.Rows) (Result, error) {
 		columnTypes[k] = v.DatabaseTypeName()
 		columnNames[k] = v.Name()
 	}
+	moneyScales := bs.getMoneyScales(query)
+	converters := bs.getConverters(query, columnNames, columnTypes)
 	values := make([]sql.RawBytes, len(columnNames))
 	records := make(Result, 0)
 	scanArgs := make([]interface{}, len(values))
@@ -671,7 +2258,13 @@ func (bs *dbBase) rowsToResult(rows *sql.Rows) (Result, error) {
 				// it should do a copy of it.
 				v := make([]byte, len(value))
 				copy(v, value)
-				row[columnNames[i]] = gvar.New(bs.db.convertValue(v, columnTypes[i]))
+				if scale, ok := moneyScales[columnNames[i]]; ok {
+					row[columnNames[i]] = gvar.New(decimalToCents(string(v), scale))
+				} else if converter, ok := converters[columnNames[i]]; ok {
+					row[columnNames[i]] = gvar.New(converter.Decode(v))
+				} else {
+					row[columnNames[i]] = gvar.New(bs.db.convertValue(v, columnTypes[i]))
+				}
 			}
 		}
 		records = append(records, row)
@@ -688,22 +2281,82 @@ func (bs *dbBase) rowsToResult(rows *sql.Rows) (Result, error) {
 // Note that, this will automatically checks the table prefix whether already added, if true it does
 // nothing to the table name, or else adds the prefix to the table name.
 func (bs *dbBase) handleTableName(table string) string {
-	charLeft, charRight := bs.db.getChars()
+	charLeft, charRight := bs.db.getQuoteChars()
 	prefix := bs.db.getPrefix()
-	return doHandleTableName(table, prefix, charLeft, charRight)
+	return doHandleTableName(table, prefix, charLeft, charRight, bs.getTableResolver())
+}
+
+// getTableResolver returns the resolver of the physical table names, which redirects the table
+// names first if any redirection is set. It returns nil if neither is set.
+func (bs *dbBase) getTableResolver() TableResolver {
+	resolver, _ := bs.tableResolver.Val().(TableResolver)
+	if bs.tableRedirects.Size() > 0 {
+		resolver = redirectTableResolver(bs.tableRedirects.Map(), resolver)
+	}
+	return resolver
+}
+
+// getTableName returns the table name with prefix but without quote chars, which is commonly
+// used as the parameter of metadata queries. The table name is redirected and resolved like
+// handleTableName, and the <schema> is the schema qualifying the table name, eg: "tenant_001"
+// of "tenant_001.user", which is empty if the table name is not qualified with schema.
+func (bs *dbBase) getTableName(table string) (name string, schema string) {
+	charLeft, charRight := bs.db.getChars()
+	array := gstr.Split(gstr.Trim(table), ".")
+	for i, v := range array {
+		array[i] = gstr.Trim(v, charLeft+charRight)
+	}
+	name = array[len(array)-1]
+	if prefix := bs.db.getPrefix(); !gstr.HasPrefix(name, prefix) {
+		name = prefix + name
+	}
+	if len(array) > 1 {
+		return name, array[len(array)-2]
+	}
+	// The resolver is only called for the table names without schema.
+	if resolver := bs.getTableResolver(); resolver != nil {
+		if array = gstr.Split(resolver(name), "."); len(array) > 1 {
+			return gstr.Trim(array[len(array)-1], charLeft+charRight), gstr.Trim(array[len(array)-2], charLeft+charRight)
+		}
+		name = gstr.Trim(array[0], charLeft+charRight)
+	}
+	return name, ""
+}
+
+// getTableSchema returns the table name with prefix and the schema for the metadata queries of
+// <table>, which is the given <schema>, or else the schema qualifying the table name resolved by
+// getTableName, or else the configured one. It is commonly used by the TableFields of the drivers.
+func (bs *dbBase) getTableSchema(table string, schema ...string) (string, string) {
+	table, checkSchema := bs.getTableName(table)
+	if len(schema) > 0 && schema[0] != "" {
+		checkSchema = schema[0]
+	}
+	if checkSchema == "" {
+		checkSchema = bs.schema.Val()
+	}
+	return table, checkSchema
+}
+
+// getQuoteChars returns the security chars for quoting the identifiers, which are empty if
+// the quoting is disabled by NoQuote. Also see getChars.
+func (bs *dbBase) getQuoteChars() (charLeft string, charRight string) {
+	if bs.noQuote {
+		return "", ""
+	}
+	return bs.db.getChars()
 }
 
 // quoteWord checks given string <s> a word, if true quotes it with security chars of the database
 // and returns the quoted string; or else return <s> without any change.
 func (bs *dbBase) quoteWord(s string) string {
-	charLeft, charRight := bs.db.getChars()
+	charLeft, charRight := bs.db.getQuoteChars()
 	return doQuoteWord(s, charLeft, charRight)
 }
 
 // quoteString quotes string with quote chars. Strings like:
 // "user", "user u", "user,user_detail", "user u, user_detail ut", "u.id asc".
 func (bs *dbBase) quoteString(s string) string {
-	charLeft, charRight := bs.db.getChars()
+	charLeft, charRight := bs.db.getQuoteChars()
 	return doQuoteString(s, charLeft, charRight)
 }
 
@@ -714,7 +2367,16 @@ func (bs *dbBase) printSql(v *Sql) {
 	if v.Error != nil {
 		s += "\nError: " + v.Error.Error()
 		bs.logger.StackWithFilter(gPATH_FILTER_KEY).Error(s)
-	} else {
+		return
+	}
+	// The errors and slow statements are always logged, others are sampled.
+	slowTime := bs.logSlowTime.Val()
+	if slowTime > 0 && v.End-v.Start >= slowTime {
 		bs.logger.StackWithFilter(gPATH_FILTER_KEY).Debug(s)
+		return
+	}
+	if rate := bs.logSampleRate.Val(); rate < 1 && !grand.MeetProb(float32(rate)) {
+		return
 	}
+	bs.logger.StackWithFilter(gPATH_FILTER_KEY).Debug(s)
 }
@@ -6,11 +6,42 @@
 
 package gdb
 
-import "database/sql"
+import (
+	"database/sql"
+	"errors"
+)
+
+// SaveResult is the result of BatchSave, which reports the counts of the inserted and updated
+// records besides sql.Result, eg:
+// r, err := db.BatchSave("user", list)
+// if s, ok := r.(gdb.SaveResult); ok { fmt.Println(s.InsertedRows(), s.UpdatedRows()) }
+//
+// The counts are derived from the affected rows number of each batch statement in default, which
+// uses the "ON DUPLICATE KEY UPDATE" semantics of mysql: 1 for each inserted record, 2 for each
+// updated record and 0 for each unchanged record. As the unchanged records cannot be told apart,
+// they are exact only if there's no unchanged record, or else the batch is counted as all inserted
+// for the affected number less than the records count. The pgsql driver counts them exactly with
+// "RETURNING" clause. The drivers which cannot distinguish them report the count by SavedRows.
+type SaveResult interface {
+	sql.Result
+
+	// InsertedRows returns the count of the inserted records.
+	InsertedRows() int64
+
+	// UpdatedRows returns the count of the updated records.
+	UpdatedRows() int64
+
+	// SavedRows returns the count of the records which are inserted or updated,
+	// but cannot be distinguished by the driver.
+	SavedRows() int64
+}
 
 // batchSqlResult is execution result for batch operations.
 type batchSqlResult struct {
 	rowsAffected int64
+	insertedRows int64
+	updatedRows  int64
+	savedRows    int64
 	lastResult   sql.Result
 }
 
@@ -21,5 +52,23 @@ func (r *batchSqlResult) RowsAffected() (int64, error) {
 
 // see sql.Result.LastInsertId
 func (r *batchSqlResult) LastInsertId() (int64, error) {
+	if r.lastResult == nil {
+		return 0, errors.New("LastInsertId is not supported by this result")
+	}
 	return r.lastResult.LastInsertId()
 }
+
+// see SaveResult.InsertedRows
+func (r *batchSqlResult) InsertedRows() int64 {
+	return r.insertedRows
+}
+
+// see SaveResult.UpdatedRows
+func (r *batchSqlResult) UpdatedRows() int64 {
+	return r.updatedRows
+}
+
+// see SaveResult.SavedRows
+func (r *batchSqlResult) SavedRows() int64 {
+	return r.savedRows
+}
@@ -0,0 +1,179 @@
+// Copyright 2020 gf Author(https://github.com/gogf/gf). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/gogf/gf.
+
+package gdb
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/container/gtype"
+	"github.com/gogf/gf/text/gstr"
+)
+
+// ErrMasterUnavailable is returned by the operations on master node in the backoff window
+// after the master node is detected down. See DB.SetMasterBreaker.
+var ErrMasterUnavailable = errors.New("master node is unavailable")
+
+// breaker is the circuit breaker for the master node.
+//
+// It opens after <threshold> consecutive connection failures on the master node, and the
+// operations on master node fail fast with ErrMasterUnavailable in the backoff window.
+// After the backoff window, it allows one probe pinging the master node, which closes the
+// breaker if it succeeds, or else opens it again with doubled backoff duration.
+type breaker struct {
+	mu         sync.Mutex
+	threshold  *gtype.Int           // Consecutive failure count opening the breaker, 0 means disabled.
+	minBackoff time.Duration        // Backoff duration for the first opening.
+	maxBackoff time.Duration        // Max backoff duration.
+	backoff    time.Duration        // Current backoff duration.
+	failures   int                  // Consecutive failure count.
+	openUntil  time.Time            // The breaker is open until this time.
+	probing    bool                 // Whether there's a probe in progress.
+	links      map[*sql.DB]struct{} // Links of the master node.
+}
+
+// newBreaker creates and returns a disabled breaker.
+func newBreaker() *breaker {
+	return &breaker{
+		threshold: gtype.NewInt(),
+		links:     make(map[*sql.DB]struct{}),
+	}
+}
+
+// set configures the breaker. It disables the breaker if <threshold> <= 0.
+func (b *breaker) set(threshold int, minBackoff, maxBackoff time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if maxBackoff < minBackoff {
+		maxBackoff = minBackoff
+	}
+	b.minBackoff = minBackoff
+	b.maxBackoff = maxBackoff
+	b.backoff = 0
+	b.failures = 0
+	b.probing = false
+	b.threshold.Set(threshold)
+}
+
+// enabled checks and returns whether the breaker is enabled.
+func (b *breaker) enabled() bool {
+	return b.threshold.Val() > 0
+}
+
+// allow checks whether the operation on master node is allowed. The returned <probe>
+// is true if the operation should probe the master node before using it.
+func (b *breaker) allow() (allowed bool, probe bool) {
+	if !b.enabled() {
+		return true, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold.Val() {
+		return true, false
+	}
+	if b.probing || time.Now().Before(b.openUntil) {
+		return false, false
+	}
+	b.probing = true
+	return true, true
+}
+
+// addLink marks <link> as a link of master node.
+func (b *breaker) addLink(link *sql.DB) {
+	if !b.enabled() {
+		return
+	}
+	b.mu.Lock()
+	b.links[link] = struct{}{}
+	b.mu.Unlock()
+}
+
+// record records the result <err> of the operation on <link>,
+// which is counted only if <link> is a link of master node.
+func (b *breaker) record(link dbLink, err error) {
+	if !b.enabled() {
+		return
+	}
+	db, ok := link.(*sql.DB)
+	if !ok {
+		return
+	}
+	b.mu.Lock()
+	_, ok = b.links[db]
+	b.mu.Unlock()
+	if !ok {
+		return
+	}
+	if err == nil {
+		b.succeed()
+	} else if isConnError(err) {
+		b.fail()
+	}
+}
+
+// succeed closes the breaker.
+func (b *breaker) succeed() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.backoff = 0
+	b.probing = false
+}
+
+// fail counts a failure, and opens the breaker with the min backoff duration if the
+// consecutive failure count reaches the threshold. The failures of the in-flight operations
+// after the breaker opened do not extend the backoff duration.
+func (b *breaker) fail() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures < b.threshold.Val() || b.backoff > 0 {
+		return
+	}
+	b.backoff = b.minBackoff
+	b.openUntil = time.Now().Add(b.backoff)
+}
+
+// failProbe opens the breaker again with doubled backoff duration as the probe fails.
+func (b *breaker) failProbe() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if b.failures < b.threshold.Val() {
+		b.failures = b.threshold.Val()
+	}
+	if b.backoff == 0 {
+		b.backoff = b.minBackoff
+	} else if b.backoff *= 2; b.backoff > b.maxBackoff {
+		b.backoff = b.maxBackoff
+	}
+	b.openUntil = time.Now().Add(b.backoff)
+}
+
+// isConnError checks and returns whether <err> is a connection error,
+// which means the database node might be down.
+func isConnError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if err == driver.ErrBadConn {
+		return true
+	}
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+	s := err.Error()
+	return gstr.ContainsI(s, "bad connection") ||
+		gstr.ContainsI(s, "invalid connection") ||
+		gstr.ContainsI(s, "connection refused") ||
+		gstr.ContainsI(s, "connection reset") ||
+		gstr.ContainsI(s, "broken pipe")
+}
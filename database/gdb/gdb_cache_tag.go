@@ -0,0 +1,62 @@
+// Copyright 2020 gf Author(https://github.com/gogf/gf). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/gogf/gf.
+
+package gdb
+
+import (
+	"sync"
+
+	"github.com/gogf/gf/os/gcache"
+)
+
+// cacheTag is the set of the cache keys tagged with a table, see DB.TagCache.
+//
+// The keys of which the caches are expired are pruned when the key count reaches the limit,
+// so that the set does not grow without bound for the tables rarely written.
+type cacheTag struct {
+	mu    sync.Mutex
+	keys  map[string]struct{} // Tagged cache keys.
+	limit int                 // Key count triggering the pruning.
+}
+
+// newCacheTag creates and returns an empty cacheTag.
+func newCacheTag() *cacheTag {
+	return &cacheTag{
+		keys:  make(map[string]struct{}),
+		limit: gCACHE_TAG_PRUNE_SIZE,
+	}
+}
+
+// add adds <key> to the tag, and prunes the keys which no longer exist in <cache> if the
+// key count reaches the limit. The limit is doubled if more than half of the keys are still
+// cached after pruning, or else it's restored, so that the pruning costs amortized constant time.
+func (t *cacheTag) add(key string, cache *gcache.Cache) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.keys[key] = struct{}{}
+	if len(t.keys) < t.limit {
+		return
+	}
+	for k := range t.keys {
+		if !cache.Contains(k) {
+			delete(t.keys, k)
+		}
+	}
+	if len(t.keys) > t.limit/2 {
+		t.limit *= 2
+	} else if len(t.keys) < gCACHE_TAG_PRUNE_SIZE/2 {
+		t.limit = gCACHE_TAG_PRUNE_SIZE
+	}
+}
+
+// clear removes the caches of all the tagged keys from <cache>.
+func (t *cacheTag) clear(cache *gcache.Cache) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for k := range t.keys {
+		cache.Remove(k)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 gf Author(https://github.com/gogf/gf). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/gogf/gf.
+
+package gdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by the operations starting after the DB object is closed. See DB.Close.
+var ErrClosed = errors.New("database is closed")
+
+// inflight tracks the in-flight operations for closing gracefully.
+type inflight struct {
+	mu     sync.Mutex
+	closed bool          // Whether it's closed, which rejects new operations.
+	count  int           // Count of the in-flight operations.
+	done   chan struct{} // Closed when there's no in-flight operation after closing.
+}
+
+// newInflight creates and returns an inflight tracker.
+func newInflight() *inflight {
+	return &inflight{
+		done: make(chan struct{}),
+	}
+}
+
+// acquire adds one in-flight operation. It returns ErrClosed if it's closed.
+func (f *inflight) acquire() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return ErrClosed
+	}
+	f.count++
+	return nil
+}
+
+// release removes one in-flight operation.
+func (f *inflight) release() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.count--
+	if f.closed && f.count == 0 {
+		close(f.done)
+	}
+}
+
+// close rejects the new operations, and returns the channel which is closed when
+// all the in-flight operations finish.
+func (f *inflight) close() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.closed {
+		f.closed = true
+		if f.count == 0 {
+			close(f.done)
+		}
+	}
+	return f.done
+}
+
+// Close closes the DB object gracefully, which is commonly used for draining in deploys.
+// It rejects the new operations with ErrClosed, and waits for the in-flight statements and
+// transactions to finish until <ctx> is done, and then closes all the underlying connection pools.
+// It returns the error of <ctx> if it's done before all the in-flight operations finish, but the
+// connection pools are still closed.
+//
+// The statements of the in-flight transactions are allowed until they're committed or rolled back,
+// and the rows returned by Query are allowed to be read until they're closed, as the connection pools
+// are closed after all the connections are put back. It also stops the replica lag monitor, see SetReplicaLagMonitor.
+// Note that the other DB objects of the same configuration group are not closed, and the closed
+// DB object is removed from the instances, so Instance creates a new one.
+func (bs *dbBase) Close(ctx context.Context) (err error) {
+	bs.replicaLags.monitor(0, nil)
+	sqlDbs := make([]*sql.DB, 0)
+	for _, v := range bs.cache.Values() {
+		if sqlDb, ok := v.(*sql.DB); ok {
+			sqlDbs = append(sqlDbs, sqlDb)
+		}
+	}
+	select {
+	case <-bs.inflight.close():
+		err = waitConnsReleased(ctx, sqlDbs)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	for _, sqlDb := range sqlDbs {
+		if e := sqlDb.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	if v := instances.Get(bs.group); v == bs.db {
+		instances.Remove(bs.group)
+	}
+	return err
+}
+
+// waitConnsReleased waits until all the connections of <sqlDbs> in use are put back to the pools,
+// eg: the connection of the rows returned by Query is put back when the rows are closed.
+// It returns the error of <ctx> if it's done before that.
+func waitConnsReleased(ctx context.Context, sqlDbs []*sql.DB) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for _, sqlDb := range sqlDbs {
+		for sqlDb.Stats().InUse > 0 {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
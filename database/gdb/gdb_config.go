@@ -41,6 +41,8 @@ type ConfigNode struct {
 	MaxIdleConnCount int           // (Optional) Max idle connection configuration for underlying connection pool.
 	MaxOpenConnCount int           // (Optional) Max open connection configuration for underlying connection pool.
 	MaxConnLifetime  time.Duration // (Optional) Max connection TTL configuration for underlying connection pool.
+	BatchNum         int           // (Optional, 10 in default) Default record count of each statement for batch operations.
+	PingTimeout      time.Duration // (Optional, 5 seconds in default) Timeout of PingMaster/PingSlave.
 }
 
 // configs is internal used configuration object.
@@ -97,6 +99,20 @@ func GetConfig(group string) ConfigGroup {
 	return configs.config[group]
 }
 
+// getConfigGroup returns a copy of the configuration nodes of given group, which is safe for
+// iterating without the lock and changing the nodes without affecting the configuration.
+func getConfigGroup(group string) (ConfigGroup, bool) {
+	configs.RLock()
+	defer configs.RUnlock()
+	list, ok := configs.config[group]
+	if !ok {
+		return nil, false
+	}
+	nodes := make(ConfigGroup, len(list))
+	copy(nodes, list)
+	return nodes, true
+}
+
 // SetDefaultGroup sets the group name for default configuration.
 func SetDefaultGroup(name string) {
 	defer instances.Clear()
@@ -140,9 +156,10 @@ func (bs *dbBase) SetMaxConnLifetime(d time.Duration) {
 }
 
 // String returns the node as string.
+// Note that the password is never contained in the returned string.
 func (node *ConfigNode) String() string {
 	if node.LinkInfo != "" {
-		return node.LinkInfo
+		return maskDSN(node.LinkInfo)
 	}
 	return fmt.Sprintf(
 		`%s@%s:%s,%s,%s,%s,%s,%v,%d-%d-%d`,
@@ -162,6 +179,82 @@ func (bs *dbBase) SetDebug(debug bool) {
 	bs.debug.Set(debug)
 }
 
+// SetProtectFullTableOps enables/disables the protection for Update/Delete operations
+// without WHERE condition, which affect all records of the table.
+//
+// If it's enabled, these operations return error. Or else, which is the default, they are
+// executed but a warning is logged. To explicitly operate on all records in protected mode,
+// use condition like "1=1" or Model.AllowFullTable.
+func (bs *dbBase) SetProtectFullTableOps(protect bool) {
+	bs.protectFullTable.Set(protect)
+}
+
+// SetFilterUnknownColumns enables/disables filtering the data of Insert/Update operations
+// according to the table fields, which is disabled in default.
+//
+// If it's enabled, the keys of the data which are not the columns of the table are removed
+// before building the statement, so a struct with transient attributes can be inserted/updated
+// directly. It only works for single table operations, and the table fields are retrieved using
+// TableFields. Note that it also hides misspelled column names, which returns error in default.
+func (bs *dbBase) SetFilterUnknownColumns(filter bool) {
+	bs.filterColumns.Set(filter)
+}
+
+// SetBatchNum sets the default record count of each statement for batch operations,
+// which is used if no batch count is passed. It uses the configured BatchNum or 10 if <n> <= 0.
+func (bs *dbBase) SetBatchNum(n int) {
+	if n <= 0 {
+		n = bs.nodeBatchNum
+	}
+	bs.batchNum.Set(n)
+}
+
+// SetPingTimeout sets the timeout of PingMaster/PingSlave, which is commonly used for health checks.
+// It uses the configured PingTimeout or 5 seconds if <timeout> <= 0.
+func (bs *dbBase) SetPingTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = bs.nodePingTimeout
+	}
+	bs.pingTimeout.Set(int64(timeout / time.Millisecond))
+}
+
+// getPingTimeout returns the effective timeout of PingMaster/PingSlave.
+func (bs *dbBase) getPingTimeout() time.Duration {
+	if timeout := bs.pingTimeout.Val(); timeout > 0 {
+		return time.Duration(timeout) * time.Millisecond
+	}
+	return gDEFAULT_PING_TIMEOUT * time.Second
+}
+
+// SetMasterBreaker enables the circuit breaker for the master node, which is disabled in default.
+//
+// After <threshold> consecutive connection failures on the master node, the operations on
+// master node fail fast with ErrMasterUnavailable in the backoff window, which starts from
+// <backoff> and doubles for each failed probe up to <maxBackoff>. After the backoff window,
+// the master node is probed with ping, and the operations resume if it is recovered.
+// It disables the breaker if <threshold> <= 0.
+func (bs *dbBase) SetMasterBreaker(threshold int, backoff time.Duration, maxBackoff time.Duration) {
+	bs.masterBreaker.set(threshold, backoff, maxBackoff)
+}
+
+// SetLogSampleRate sets the sample rate for logging successful statements in debug mode,
+// which is between 0 and 1. It is 1 in default, which means all statements are logged.
+// The failed statements and slow statements are always logged, see SetLogSlowThreshold.
+func (bs *dbBase) SetLogSampleRate(rate float64) {
+	if rate < 0 {
+		rate = 0
+	} else if rate > 1 {
+		rate = 1
+	}
+	bs.logSampleRate.Set(rate)
+}
+
+// SetLogSlowThreshold sets the threshold of slow statements, which are always logged in
+// debug mode despite the sample rate. It is 0 in default, which means no statement is slow.
+func (bs *dbBase) SetLogSlowThreshold(threshold time.Duration) {
+	bs.logSlowTime.Set(int64(threshold / time.Millisecond))
+}
+
 // getDebug returns the debug value.
 func (bs *dbBase) getDebug() bool {
 	return bs.debug.Val()
@@ -0,0 +1,155 @@
+// Copyright 2020 gf Author(https://github.com/gogf/gf). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/gogf/gf.
+
+package gdb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/gogf/gf/container/gmap"
+	"github.com/gogf/gf/text/gstr"
+)
+
+// Driver is the interface for integrating a database backend which is not built in the package,
+// eg: ClickHouse. The driver is registered with a database type by RegisterDriver, and used by
+// the configuration nodes of the same type. It is the minimal set of the backend specific features,
+// and all the other features like Query/Exec, Model, transactions and master-slave routing are
+// provided by the package using the connections opened by the driver.
+type Driver interface {
+	// Open creates and returns an underlying database connection with given configuration,
+	// commonly using sql.Open with the registered database/sql driver of the backend.
+	Open(config *ConfigNode) (*sql.DB, error)
+
+	// GetChars returns the chars quoting the field and table names, eg: "`" and "`" for mysql.
+	GetChars() (charLeft string, charRight string)
+
+	// HandleSqlBeforeExec handles the statement before it is committed to the underlying driver,
+	// eg: replacing the placeholders "?" with the ones the backend uses. It can return <sql> as it is.
+	HandleSqlBeforeExec(sql string) string
+
+	// Tables retrieves and returns the tables of given schema, which is the configured one if
+	// <schema> is not given. The statements should be executed using <db>.
+	Tables(db DB, schema ...string) (tables []string, err error)
+
+	// TableFields retrieves and returns the fields of <table> of given schema, which is the configured
+	// one if <schema> is not given. The statements should be executed using <db>.
+	// The result is cached by the package, so it is not necessary to cache it in the driver.
+	TableFields(db DB, table string, schema ...string) (fields map[string]*TableField, err error)
+}
+
+// DriverValueConverter is the optional interface for Driver, which converts the field value
+// retrieved from database to the Go value according to its database type, eg: "INT" to int.
+// The default converting of the package is used if the driver does not implement it.
+type DriverValueConverter interface {
+	ConvertValue(fieldValue []byte, fieldType string) interface{}
+}
+
+// DriverPlaceholderLimiter is the optional interface for Driver, which returns the max place holder
+// count in one statement of the backend, limiting the record count of each statement for batch
+// operations. The default limit 65535 of the package is used if the driver does not implement it.
+type DriverPlaceholderLimiter interface {
+	MaxPlaceholders() int
+}
+
+// drivers is the registered drivers, the key is the database type.
+var drivers = gmap.NewStrAnyMap(true)
+
+// RegisterDriver registers <driver> for the database type <name>, which is the "type" of
+// the configuration node, eg: "clickhouse". It returns error if <name> is one of the built-in
+// types or it is already registered. It's commonly called in the init function of the driver package.
+func RegisterDriver(name string, driver Driver) error {
+	if name == "" || driver == nil {
+		return errors.New("driver name and driver cannot be empty")
+	}
+	switch name {
+	case "mysql", "pgsql", "mssql", "sqlite", "oracle":
+		return errors.New(fmt.Sprintf(`database type "%s" is built-in and cannot be registered`, name))
+	}
+	if !drivers.SetIfNotExist(name, driver) {
+		return errors.New(fmt.Sprintf(`driver for database type "%s" is already registered`, name))
+	}
+	return nil
+}
+
+// getDriver returns the registered driver of database type <name>, or nil if it's not registered.
+func getDriver(name string) Driver {
+	if v := drivers.Get(name); v != nil {
+		return v.(Driver)
+	}
+	return nil
+}
+
+// dbDriver is the DB object of the database types registered by RegisterDriver,
+// which implements the backend specific features using the registered Driver.
+type dbDriver struct {
+	*dbBase
+	driver Driver
+}
+
+// Open creates and returns a underlying database connection with given configuration.
+func (db *dbDriver) Open(config *ConfigNode) (*sql.DB, error) {
+	return db.driver.Open(config)
+}
+
+func (db *dbDriver) getChars() (charLeft string, charRight string) {
+	return db.driver.GetChars()
+}
+
+func (db *dbDriver) handleSqlBeforeExec(sql string) string {
+	return db.driver.HandleSqlBeforeExec(sql)
+}
+
+// classifyError does not classify the errors of the registered driver,
+// of which the error codes are unknown.
+func (db *dbDriver) classifyError(err error) (kind error, code string) {
+	return nil, ""
+}
+
+// convertValue converts the field value using the driver if it implements DriverValueConverter.
+func (db *dbDriver) convertValue(fieldValue []byte, fieldType string) interface{} {
+	if converter, ok := db.driver.(DriverValueConverter); ok {
+		return converter.ConvertValue(fieldValue, fieldType)
+	}
+	return db.dbBase.convertValue(fieldValue, fieldType)
+}
+
+// getMaxPlaceholders returns the max place holder count of the driver if it implements
+// DriverPlaceholderLimiter.
+func (db *dbDriver) getMaxPlaceholders() int {
+	if limiter, ok := db.driver.(DriverPlaceholderLimiter); ok && limiter.MaxPlaceholders() > 0 {
+		return limiter.MaxPlaceholders()
+	}
+	return db.dbBase.getMaxPlaceholders()
+}
+
+// Tables retrieves and returns the tables of current schema using the driver.
+func (db *dbDriver) Tables(schema ...string) (tables []string, err error) {
+	return db.driver.Tables(db, schema...)
+}
+
+// TableFields retrieves and returns the fields of given table using the driver,
+// which are cached like the built-in types. Also see dbBase.TableFields.
+func (db *dbDriver) TableFields(table string, schema ...string) (fields map[string]*TableField, err error) {
+	table = gstr.Trim(table)
+	if gstr.Contains(table, " ") {
+		panic("function TableFields supports only single table operations")
+	}
+	table, checkSchema := db.getTableSchema(table, schema...)
+	v := db.getOrSetTableFields(
+		db.getTableFieldsCacheKey(table, checkSchema), func() interface{} {
+			fields, err = db.driver.TableFields(db, table, checkSchema)
+			if err != nil {
+				return nil
+			}
+			return fields
+		})
+	if err == nil {
+		fields, _ = v.(map[string]*TableField)
+	}
+	return
+}
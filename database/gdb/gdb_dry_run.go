@@ -0,0 +1,93 @@
+// Copyright 2020 gf Author(https://github.com/gogf/gf). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/gogf/gf.
+
+package gdb
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// sqlCatcher is the database link for dry run, which catches the statements
+// instead of executing them.
+type sqlCatcher struct {
+	sqls []*Sql
+}
+
+// dryRunResult is the execution result of dry run, which affects nothing.
+type dryRunResult struct{}
+
+// Query is not supported in dry run.
+func (c *sqlCatcher) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("query is not supported in dry run")
+}
+
+// Exec catches the statement <query> with its arguments <args>.
+func (c *sqlCatcher) Exec(query string, args ...interface{}) (sql.Result, error) {
+	c.sqls = append(c.sqls, &Sql{
+		Sql:    query,
+		Args:   args,
+		Format: bindArgsToQuery(query, args),
+	})
+	return dryRunResult{}, nil
+}
+
+// Prepare is not supported in dry run.
+func (c *sqlCatcher) Prepare(query string) (*sql.Stmt, error) {
+	return nil, errors.New("prepare is not supported in dry run")
+}
+
+// see sql.Result.RowsAffected
+func (r dryRunResult) RowsAffected() (int64, error) {
+	return 0, nil
+}
+
+// see sql.Result.LastInsertId
+func (r dryRunResult) LastInsertId() (int64, error) {
+	return 0, nil
+}
+
+// InsertSql returns the statements that Insert would execute with the same parameters,
+// without executing them. It returns multiple statements if <data> is a list and its size
+// is more than <batch>.
+//
+// The Sql and Args attributes of returned Sql are the statement and arguments passed to the
+// driver, and the Format attribute is the readable statement with arguments bound.
+func (bs *dbBase) InsertSql(table string, data interface{}, batch ...int) ([]*Sql, error) {
+	catcher := new(sqlCatcher)
+	if _, err := bs.db.doInsert(catcher, table, data, gINSERT_OPTION_DEFAULT, batch...); err != nil {
+		return nil, err
+	}
+	return catcher.sqls, nil
+}
+
+// UpdateSql returns the statement that Update would execute with the same parameters,
+// without executing it. Also see InsertSql.
+func (bs *dbBase) UpdateSql(table string, data interface{}, condition interface{}, args ...interface{}) (*Sql, error) {
+	newWhere, newArgs := formatWhere(bs.db, condition, args, false)
+	if newWhere != "" {
+		newWhere = " WHERE " + newWhere
+	}
+	catcher := new(sqlCatcher)
+	if _, err := bs.db.doUpdate(catcher, table, data, newWhere, newArgs...); err != nil {
+		return nil, err
+	}
+	return catcher.sqls[0], nil
+}
+
+// DeleteSql returns the statement that Delete would execute with the same parameters,
+// without executing it. Also see InsertSql.
+func (bs *dbBase) DeleteSql(table string, condition interface{}, args ...interface{}) (*Sql, error) {
+	newWhere, newArgs := formatWhere(bs.db, condition, args, false)
+	if newWhere != "" {
+		newWhere = " WHERE " + newWhere
+	}
+	catcher := new(sqlCatcher)
+	if _, err := bs.db.doDelete(catcher, table, newWhere, newArgs...); err != nil {
+		return nil, err
+	}
+	return catcher.sqls[0], nil
+}
@@ -0,0 +1,204 @@
+// Copyright 2020 gf Author(https://github.com/gogf/gf). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/gogf/gf.
+
+package gdb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUniqueViolation is the kind of the errors violating unique constraint, eg: duplicate key.
+	ErrUniqueViolation = errors.New("unique constraint violation")
+
+	// ErrForeignKeyViolation is the kind of the errors violating foreign key constraint.
+	ErrForeignKeyViolation = errors.New("foreign key constraint violation")
+
+	// ErrNotNullViolation is the kind of the errors violating not null constraint.
+	ErrNotNullViolation = errors.New("not null constraint violation")
+)
+
+// Error is the error of the failed statement, which is classified into the common conditions
+// using the error codes of the driver, eg: ErrUniqueViolation for MySQL error 1062 and PostgreSQL
+// SQLSTATE 23505. Use IsUniqueViolation/IsForeignKeyViolation/IsNotNullViolation for checking.
+//
+// It wraps the error of the driver, which can be retrieved by Unwrap, or by errors.As of Go 1.13+,
+// eg: errors.As(err, &mysqlErr). The message contains the failed statement with the arguments bound,
+// of which the sensitive arguments are masked. See Sensitive.
+type Error struct {
+	kind  error  // Classified kind of the error, which is nil if it's not classified.
+	code  string // Error code of the driver, eg: "1062" of mysql, "23505" of pgsql.
+	err   error  // Error of the driver.
+	query string // Failed statement with the arguments bound.
+}
+
+// Error returns the error message of the driver along with the failed statement.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s, %s", e.err.Error(), e.query)
+}
+
+// Sql returns the failed statement with the arguments bound.
+func (e *Error) Sql() string {
+	return e.query
+}
+
+// Unwrap returns the error of the driver, which is used by errors.Is/errors.As.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Code returns the error code of the driver, eg: "1062" of mysql, "23505" of pgsql,
+// or an empty string if the driver error has no code.
+func (e *Error) Code() string {
+	return e.code
+}
+
+// Kind returns the classified kind of the error, eg: ErrUniqueViolation,
+// or nil if it's not classified.
+func (e *Error) Kind() error {
+	return e.kind
+}
+
+// Is checks and returns whether the error is the kind <target>, which is used by errors.Is.
+// The error of the driver is checked by errors.Is through Unwrap.
+func (e *Error) Is(target error) bool {
+	return e.kind != nil && e.kind == target
+}
+
+// NodeError is the error of the operation failing on a node. See DB.ExecOnAll.
+type NodeError struct {
+	Node string // Node configuration string, in which the password is not contained.
+	Err  error  // Error of the operation on the node.
+}
+
+// Error returns the error message along with the node.
+func (e *NodeError) Error() string {
+	return fmt.Sprintf("node '%s' failed: %s", e.Node, e.Err.Error())
+}
+
+// Unwrap returns the error of the operation on the node.
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// NodeErrors is the errors of the operation failing on multiple nodes. See DB.ExecOnAll.
+type NodeErrors []*NodeError
+
+// Error returns the error messages of all the failed nodes joined with "; ".
+func (e NodeErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// isError checks and returns whether any error in the chain of <err> matches <target> like errors.Is
+// of Go 1.13+, which is not used for compatibility. The chain consists of <err> and the errors
+// retrieved by repeatedly calling Unwrap, and all the errors of NodeErrors are checked.
+func isError(err error, target error) bool {
+	for err != nil {
+		// The NodeErrors is checked first as it's not comparable.
+		if errs, ok := err.(NodeErrors); ok {
+			for _, e := range errs {
+				if isError(e, target) {
+					return true
+				}
+			}
+			return false
+		}
+		if err == target {
+			return true
+		}
+		if e, ok := err.(interface{ Is(error) bool }); ok && e.Is(target) {
+			return true
+		}
+		e, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return false
+		}
+		err = e.Unwrap()
+	}
+	return false
+}
+
+// IsUniqueViolation checks and returns whether <err> violates unique constraint, eg: duplicate key.
+func IsUniqueViolation(err error) bool {
+	return isErrorKind(err, ErrUniqueViolation)
+}
+
+// IsForeignKeyViolation checks and returns whether <err> violates foreign key constraint.
+func IsForeignKeyViolation(err error) bool {
+	return isErrorKind(err, ErrForeignKeyViolation)
+}
+
+// IsNotNullViolation checks and returns whether <err> violates not null constraint.
+func IsNotNullViolation(err error) bool {
+	return isErrorKind(err, ErrNotNullViolation)
+}
+
+// isErrorKind checks and returns whether <err> or any error it wraps is the *Error of <kind>.
+func isErrorKind(err error, kind error) bool {
+	return isError(err, kind)
+}
+
+// formatError customizes and returns the SQL error, which is *Error classified by the driver of <db>.
+func formatError(db DB, err error, query string, args ...interface{}) error {
+	if err != nil && err != ErrNoRows {
+		kind, code := db.classifyError(err)
+		return &Error{
+			kind:  kind,
+			code:  code,
+			err:   err,
+			query: bindArgsToQuery(query, args),
+		}
+	}
+	return err
+}
+
+// classifyError returns the kind and the error code of driver error <err> using MySQL error numbers.
+func (bs *dbBase) classifyError(err error) (kind error, code string) {
+	code = getDriverErrorCode(err, "Number")
+	switch code {
+	case "1062", "1586":
+		kind = ErrUniqueViolation
+	case "1216", "1217", "1451", "1452":
+		kind = ErrForeignKeyViolation
+	case "1048", "1364":
+		kind = ErrNotNullViolation
+	}
+	return
+}
+
+// getDriverErrorCode returns the error code in attribute <field> of driver error <err>, eg: attribute
+// "Number" of mysql error. It uses reflection as the drivers are not imported by this package.
+// It returns an empty string if there's no such attribute of integer or string type.
+func getDriverErrorCode(err error, field string) string {
+	rv := reflect.ValueOf(err)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return ""
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return ""
+	}
+	fv := rv.FieldByName(field)
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(fv.Uint(), 10)
+	}
+	return ""
+}
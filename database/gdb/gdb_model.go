@@ -11,8 +11,9 @@ import (
 	"errors"
 	"fmt"
 	"github.com/gogf/gf/container/garray"
-	"github.com/gogf/gf/container/gmap"
 	"reflect"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/gogf/gf/container/gset"
@@ -27,6 +28,7 @@ type Model struct {
 	tx            *TX            // Underlying TX interface.
 	schema        string         // Custom database schema.
 	linkType      int            // Mark for operation on master or slave.
+	maxStaleness  time.Duration  // Max replication lag of the slave node for reading, see MaxStaleness.
 	tablesInit    string         // Table names when model initialization.
 	tables        string         // Operation table names, which can be more than one table names and aliases, like: "user", "user u", "user u, user_detail ud".
 	fields        string         // Operation fields, multiple fields joined using char ','.
@@ -41,7 +43,12 @@ type Model struct {
 	offset        int            // Offset statement for some databases grammar.
 	data          interface{}    // Data for operation, which can be type of map/[]map/struct/*struct/string, etc.
 	batch         int            // Batch number for batch Insert/Replace/Save operations.
+	columns       []string       // Explicit column order for Insert/Replace/Save operations.
+	conflict      []string       // Conflict target columns for Save/InsertIgnore operation, see OnConflict.
 	filter        bool           // Filter data and where key-value pairs according to the fields of the table.
+	lock          string         // Locking clause for "SELECT" statement, eg: " FOR UPDATE".
+	lockErr       error          // Error of the locking not supported by the driver, which is returned by the queries.
+	dataErr       error          // Error of converting the operation data, which is returned by the writing operations.
 	cacheEnabled  bool           // Enable sql result cache feature.
 	cacheDuration time.Duration  // Cache TTL duration.
 	cacheName     string         // Cache name for custom operation.
@@ -63,6 +70,8 @@ const (
 	gWHERE_HOLDER_OR    = 3
 	OPTION_OMITEMPTY    = 1 << iota
 	OPTION_ALLOWEMPTY
+	OPTION_ALLOWFULLTABLE
+	OPTION_APPENDCOLUMNS
 )
 
 // Table creates and returns a new ORM model from given schema.
@@ -193,6 +202,22 @@ func (m *Model) Slave() *Model {
 	return model
 }
 
+// MaxStaleness sets the staleness hint of the following reading operation, which is routed to the
+// slave node only if the last-known replication lag of the chosen slave node does not exceed
+// <maxStaleness>, or else to the master node. It is commonly used for the reads which must see the
+// recent writes, eg: MaxStaleness(time.Second). The <maxStaleness> <= 0 marks it on master node.
+//
+// Note that the replication lags are sampled by the monitor, and the slave node of unknown lag is
+// considered stale, eg: the monitor is not started. See DB.SetReplicaLagMonitor.
+func (m *Model) MaxStaleness(maxStaleness time.Duration) *Model {
+	if maxStaleness <= 0 {
+		return m.Master()
+	}
+	model := m.getModel()
+	model.maxStaleness = maxStaleness
+	return model
+}
+
 // Safe marks this model safe or unsafe. If safe is true, it clones and returns a new model object
 // whenever the operation done, or else it changes the attribute of current model.
 func (m *Model) Safe(safe ...bool) *Model {
@@ -238,7 +263,8 @@ func (m *Model) InnerJoin(table string, on string) *Model {
 // Fields sets the operation fields of the model, multiple fields joined using char ','.
 func (m *Model) Fields(fields string) *Model {
 	model := m.getModel()
-	model.fields = fields
+	charLeft, charRight := m.db.getQuoteChars()
+	model.fields = doQuoteFieldAlias(fields, charLeft, charRight)
 	return model
 }
 
@@ -265,6 +291,45 @@ func (m *Model) FieldsEx(fields string) *Model {
 	return model
 }
 
+// FieldsOf sets the operation fields of the model to the table columns mapped by the attributes
+// of struct <pointer>, which queries only the columns used by the struct for Struct/Structs/Scan, eg:
+// db.Table("user").FieldsOf(&User{}).Where("id", 1).Struct(user).
+// The parameter <pointer> can be type of struct, *struct, []struct or []*struct.
+//
+// The attribute is mapped to the column by its "orm" tag, or else by its name case-insensitively
+// ignoring chars like "_", eg: attribute NickName is mapped to column "nick_name". The attributes
+// of embedded struct are also mapped. The attributes with tag "-" or not mapped to any column are
+// skipped. It does nothing if none of the attributes is mapped.
+func (m *Model) FieldsOf(pointer interface{}) *Model {
+	if gstr.Contains(m.tables, " ") {
+		panic("function FieldsOf supports only single table operations")
+	}
+	t := reflect.TypeOf(pointer)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("function FieldsOf supports only struct parameter, but got: %T", pointer))
+	}
+	model := m.getModel()
+	fields, err := m.db.TableFields(m.tables)
+	if err != nil || len(fields) == 0 {
+		return model
+	}
+	columns := getStructColumns(t, fields)
+	if len(columns) == 0 {
+		return model
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		return fields[columns[i]].Index < fields[columns[j]].Index
+	})
+	for i, column := range columns {
+		columns[i] = m.db.quoteWord(column)
+	}
+	model.fields = strings.Join(columns, ",")
+	return model
+}
+
 // FieldsStr retrieves and returns all fields from the table, joined with char ','.
 // The optional parameter <prefix> specifies the prefix for each field, eg: FieldsStr("u.").
 func (m *Model) FieldsStr(prefix ...string) string {
@@ -322,10 +387,42 @@ func (m *Model) OptionOmitEmpty() *Model {
 
 // OmitEmpty sets OPTION_OMITEMPTY option for the model, which automatically filers
 // the data and where attributes for empty values.
+//
+// It is useful for inserting/updating with struct, as the zero value attributes like
+// empty string, 0 and zero time are not written, which lets the database apply the
+// default values of the columns, eg: auto-increment id or DEFAULT CURRENT_TIMESTAMP.
 func (m *Model) OmitEmpty() *Model {
 	return m.Option(OPTION_OMITEMPTY)
 }
 
+// AllowFullTable sets OPTION_ALLOWFULLTABLE option for the model, which allows the Update/Delete
+// operations without WHERE condition even if the full table operation protection is enabled.
+// See DB.SetProtectFullTableOps.
+func (m *Model) AllowFullTable() *Model {
+	return m.Option(OPTION_ALLOWFULLTABLE)
+}
+
+// LockUpdate sets the exclusive lock for the selected records using "FOR UPDATE" clause,
+// which locks the records until the transaction ends.
+//
+// It makes sense only for the model of transaction, like tx.Table("user").LockUpdate().One(),
+// and the locking query is always executed on master node, which never reads from cache.
+// The queries return error for the drivers not supporting the clause, eg: mssql and sqlite.
+func (m *Model) LockUpdate() *Model {
+	model := m.getModel()
+	model.lock, model.lockErr = m.db.getLockSql(false)
+	return model
+}
+
+// LockShared sets the shared lock for the selected records, using "LOCK IN SHARE MODE" clause
+// for mysql and "FOR SHARE" clause for pgsql, which prevents the records from being modified by
+// other transactions until the transaction ends. Also see LockUpdate.
+func (m *Model) LockShared() *Model {
+	model := m.getModel()
+	model.lock, model.lockErr = m.db.getLockSql(true)
+	return model
+}
+
 // Filter marks filtering the fields which does not exist in the fields of the operated table.
 func (m *Model) Filter() *Model {
 	if gstr.Contains(m.tables, " ") {
@@ -472,6 +569,33 @@ func (m *Model) ForPage(page, limit int) *Model {
 	return m.Page(page, limit)
 }
 
+// ColumnOrder sets the explicit column order for Insert/Replace/Save operations of the model,
+// so the columns of the generated sql appear in the order of <columns>. The columns of <columns>
+// which are not in the data are ignored.
+//
+// It returns error in the operations if there are columns in the data but not in <columns>,
+// or else they are appended in alphabetical order if OPTION_APPENDCOLUMNS option is set.
+func (m *Model) ColumnOrder(columns ...string) *Model {
+	model := m.getModel()
+	model.columns = columns
+	return model
+}
+
+// OnConflict sets the conflict target columns for Save operation of the model, which should be
+// the columns of a primary key or unique index of the table, eg: the two columns of composite
+// primary key OnConflict("user_id", "group_id"). The conflict target columns are not updated.
+//
+// It is required by pgsql and sqlite for the unique index other than the primary key, as their
+// "ON CONFLICT" clause uses the primary key as the conflict target in default. For mysql it is
+// ignored, as the conflict target is determined by all the unique indexes of the table.
+// It also limits the conflicts ignored by InsertIgnore of pgsql to the unique index of the columns.
+// Also see DB.InsertOnConflict.
+func (m *Model) OnConflict(columns ...string) *Model {
+	model := m.getModel()
+	model.conflict = columns
+	return model
+}
+
 // Batch sets the batch operation number for the model.
 func (m *Model) Batch(batch int) *Model {
 	model := m.getModel()
@@ -539,12 +663,12 @@ func (m *Model) Data(data ...interface{}) *Model {
 			switch kind {
 			case reflect.Slice, reflect.Array:
 				list := make(List, rv.Len())
-				for i := 0; i < rv.Len(); i++ {
-					list[i] = varToMapDeep(rv.Index(i).Interface())
+				for i := 0; i < rv.Len() && model.dataErr == nil; i++ {
+					list[i], model.dataErr = varToMapDeep(rv.Index(i).Interface())
 				}
 				model.data = list
 			case reflect.Map, reflect.Struct:
-				model.data = varToMapDeep(data[0])
+				model.data, model.dataErr = varToMapDeep(data[0])
 			default:
 				model.data = data[0]
 			}
@@ -560,7 +684,9 @@ func (m *Model) Insert(data ...interface{}) (result sql.Result, err error) {
 	return m.doInsertWithOption(gINSERT_OPTION_DEFAULT, data...)
 }
 
-// InsertIgnore does "INSERT IGNORE INTO ..." statement for the model.
+// InsertIgnore does "INSERT IGNORE INTO ..." statement for the model, which ignores the records
+// conflicting with the existing ones. It uses "INSERT OR IGNORE" for sqlite and "INSERT ... ON
+// CONFLICT DO NOTHING" for pgsql.
 // The optional parameter <data> is the same as the parameter of Model.Data function,
 // see Model.Data.
 func (m *Model) InsertIgnore(data ...interface{}) (result sql.Result, err error) {
@@ -570,7 +696,7 @@ func (m *Model) InsertIgnore(data ...interface{}) (result sql.Result, err error)
 // doInsertWithOption inserts data with option parameter.
 func (m *Model) doInsertWithOption(option int, data ...interface{}) (result sql.Result, err error) {
 	if len(data) > 0 {
-		return m.Data(data...).Insert()
+		return m.Data(data...).doInsertWithOption(option)
 	}
 	defer func() {
 		if err == nil {
@@ -580,25 +706,28 @@ func (m *Model) doInsertWithOption(option int, data ...interface{}) (result sql.
 	if m.data == nil {
 		return nil, errors.New("inserting into table with empty data")
 	}
+	if m.dataErr != nil {
+		return nil, m.dataErr
+	}
+	link, err := m.getLink(true)
+	if err != nil {
+		return nil, err
+	}
 	if list, ok := m.data.(List); ok {
 		// Batch insert.
-		batch := 10
-		if m.batch > 0 {
-			batch = m.batch
-		}
 		return m.db.doBatchInsert(
-			m.getLink(true),
+			link,
 			m.tables,
-			m.filterDataForInsertOrUpdate(list),
+			withConflict(m.withColumnOrder(m.filterDataForInsertOrUpdate(list)), m.conflict),
 			option,
-			batch,
+			m.batch,
 		)
 	} else if data, ok := m.data.(Map); ok {
 		// Single insert.
 		return m.db.doInsert(
-			m.getLink(true),
+			link,
 			m.tables,
-			m.filterDataForInsertOrUpdate(data),
+			withConflict(m.withColumnOrder(m.filterDataForInsertOrUpdate(data)), m.conflict),
 			option,
 		)
 	}
@@ -620,25 +749,28 @@ func (m *Model) Replace(data ...interface{}) (result sql.Result, err error) {
 	if m.data == nil {
 		return nil, errors.New("replacing into table with empty data")
 	}
+	if m.dataErr != nil {
+		return nil, m.dataErr
+	}
+	link, err := m.getLink(true)
+	if err != nil {
+		return nil, err
+	}
 	if list, ok := m.data.(List); ok {
 		// Batch replace.
-		batch := 10
-		if m.batch > 0 {
-			batch = m.batch
-		}
 		return m.db.doBatchInsert(
-			m.getLink(true),
+			link,
 			m.tables,
-			m.filterDataForInsertOrUpdate(list),
+			m.withColumnOrder(m.filterDataForInsertOrUpdate(list)),
 			gINSERT_OPTION_REPLACE,
-			batch,
+			m.batch,
 		)
 	} else if data, ok := m.data.(Map); ok {
 		// Single insert.
 		return m.db.doInsert(
-			m.getLink(true),
+			link,
 			m.tables,
-			m.filterDataForInsertOrUpdate(data),
+			m.withColumnOrder(m.filterDataForInsertOrUpdate(data)),
 			gINSERT_OPTION_REPLACE,
 		)
 	}
@@ -663,25 +795,28 @@ func (m *Model) Save(data ...interface{}) (result sql.Result, err error) {
 	if m.data == nil {
 		return nil, errors.New("saving into table with empty data")
 	}
+	if m.dataErr != nil {
+		return nil, m.dataErr
+	}
+	link, err := m.getLink(true)
+	if err != nil {
+		return nil, err
+	}
 	if list, ok := m.data.(List); ok {
 		// Batch save.
-		batch := gDEFAULT_BATCH_NUM
-		if m.batch > 0 {
-			batch = m.batch
-		}
 		return m.db.doBatchInsert(
-			m.getLink(true),
+			link,
 			m.tables,
-			m.filterDataForInsertOrUpdate(list),
+			withConflict(m.withColumnOrder(m.filterDataForInsertOrUpdate(list)), m.conflict),
 			gINSERT_OPTION_SAVE,
-			batch,
+			m.batch,
 		)
 	} else if data, ok := m.data.(Map); ok {
 		// Single save.
 		return m.db.doInsert(
-			m.getLink(true),
+			link,
 			m.tables,
-			m.filterDataForInsertOrUpdate(data),
+			withConflict(m.withColumnOrder(m.filterDataForInsertOrUpdate(data)), m.conflict),
 			gINSERT_OPTION_SAVE,
 		)
 	}
@@ -711,9 +846,16 @@ func (m *Model) Update(dataAndWhere ...interface{}) (result sql.Result, err erro
 	if m.data == nil {
 		return nil, errors.New("updating table with empty data")
 	}
-	condition, conditionArgs := m.formatCondition(false)
+	if m.dataErr != nil {
+		return nil, m.dataErr
+	}
+	link, err := m.getLink(true)
+	if err != nil {
+		return nil, err
+	}
+	condition, conditionArgs := m.formatFullTableCondition()
 	return m.db.doUpdate(
-		m.getLink(true),
+		link,
 		m.tables,
 		m.filterDataForInsertOrUpdate(m.data),
 		condition,
@@ -733,8 +875,24 @@ func (m *Model) Delete(where ...interface{}) (result sql.Result, err error) {
 			m.checkAndRemoveCache()
 		}
 	}()
-	condition, conditionArgs := m.formatCondition(false)
-	return m.db.doDelete(m.getLink(true), m.tables, condition, conditionArgs...)
+	link, err := m.getLink(true)
+	if err != nil {
+		return nil, err
+	}
+	condition, conditionArgs := m.formatFullTableCondition()
+	return m.db.doDelete(link, m.tables, condition, conditionArgs...)
+}
+
+// UpdateAndCount does "UPDATE ... " statement for the model like Update,
+// but returns the number of the affected rows.
+func (m *Model) UpdateAndCount(dataAndWhere ...interface{}) (int64, error) {
+	return getRowsAffected(m.Update(dataAndWhere...))
+}
+
+// DeleteAndCount does "DELETE FROM ... " statement for the model like Delete,
+// but returns the number of the affected rows.
+func (m *Model) DeleteAndCount(where ...interface{}) (int64, error) {
+	return getRowsAffected(m.Delete(where...))
 }
 
 // Select is alias of Model.All.
@@ -754,8 +912,8 @@ func (m *Model) All(where ...interface{}) (Result, error) {
 	if len(where) > 0 {
 		return m.Where(where[0], where[1:]...).All()
 	}
-	condition, conditionArgs := m.formatCondition(false)
-	return m.getAll(fmt.Sprintf("SELECT %s FROM %s%s", m.fields, m.tables, condition), conditionArgs...)
+	query, args := m.getSelectSql(false)
+	return m.getAll(query, args...)
 }
 
 // One retrieves one record from table and returns the result as map type.
@@ -767,8 +925,8 @@ func (m *Model) One(where ...interface{}) (Record, error) {
 	if len(where) > 0 {
 		return m.Where(where[0], where[1:]...).One()
 	}
-	condition, conditionArgs := m.formatCondition(true)
-	all, err := m.getAll(fmt.Sprintf("SELECT %s FROM %s%s", m.fields, m.tables, condition), conditionArgs...)
+	query, args := m.getSelectSql(true)
+	all, err := m.getAll(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -811,7 +969,7 @@ func (m *Model) Value(fieldsAndWhere ...interface{}) (Value, error) {
 // The optional parameter <where> is the same as the parameter of Model.Where function,
 // see Model.Where.
 //
-// Note that it returns sql.ErrNoRows if there's no record retrieved with the given conditions
+// Note that it returns ErrNoRows if there's no record retrieved with the given conditions
 // from table.
 //
 // Eg:
@@ -826,7 +984,7 @@ func (m *Model) Struct(pointer interface{}, where ...interface{}) error {
 		return err
 	}
 	if len(one) == 0 {
-		return sql.ErrNoRows
+		return ErrNoRows
 	}
 	return one.Struct(pointer)
 }
@@ -838,7 +996,7 @@ func (m *Model) Struct(pointer interface{}, where ...interface{}) error {
 // The optional parameter <where> is the same as the parameter of Model.Where function,
 // see Model.Where.
 //
-// Note that it returns sql.ErrNoRows if there's no record retrieved with the given conditions
+// Note that it returns ErrNoRows if there's no record retrieved with the given conditions
 // from table.
 //
 // Eg:
@@ -853,7 +1011,7 @@ func (m *Model) Structs(pointer interface{}, where ...interface{}) error {
 		return err
 	}
 	if len(all) == 0 {
-		return sql.ErrNoRows
+		return ErrNoRows
 	}
 	return all.Structs(pointer)
 }
@@ -865,7 +1023,7 @@ func (m *Model) Structs(pointer interface{}, where ...interface{}) error {
 // The optional parameter <where> is the same as the parameter of Model.Where function,
 // see Model.Where.
 //
-// Note that it returns sql.ErrNoRows if there's no record retrieved with the given conditions
+// Note that it returns ErrNoRows if there's no record retrieved with the given conditions
 // from table.
 //
 // Eg:
@@ -999,6 +1157,19 @@ func (m *Model) Chunk(limit int, callback func(result Result, err error) bool) {
 	}
 }
 
+// withColumnOrder wraps <data> with the explicit column order of the model for inserting.
+// It returns <data> without any change if no column order set.
+func (m *Model) withColumnOrder(data interface{}) interface{} {
+	if len(m.columns) == 0 {
+		return data
+	}
+	return &orderedData{
+		data:    data,
+		columns: m.columns,
+		append:  m.option&OPTION_APPENDCOLUMNS > 0,
+	}
+}
+
 // filterDataForInsertOrUpdate does filter feature with data for inserting/updating operations.
 // Note that, it does not filter list item, which is also type of map, for "omit empty" feature.
 func (m *Model) filterDataForInsertOrUpdate(data interface{}) interface{} {
@@ -1020,10 +1191,15 @@ func (m *Model) doFilterDataMapForInsertOrUpdate(data Map, allowOmitEmpty bool)
 		data = m.db.filterFields(m.schema, m.tables, data)
 	}
 	// Remove key-value pairs of which the value is empty.
+	// It uses a copy of the data to avoid changing the origin data map.
 	if allowOmitEmpty && m.option&OPTION_OMITEMPTY > 0 {
-		m := gmap.NewStrAnyMapFrom(data)
-		m.FilterEmpty()
-		data = m.Map()
+		newData := make(Map, len(data))
+		for k, v := range data {
+			if !isEmptyValue(v) {
+				newData[k] = v
+			}
+		}
+		data = newData
 	}
 
 	if len(m.fields) > 0 && m.fields != "*" {
@@ -1045,11 +1221,16 @@ func (m *Model) doFilterDataMapForInsertOrUpdate(data Map, allowOmitEmpty bool)
 
 // getLink returns the underlying database link object with configured <linkType> attribute.
 // The parameter <master> specifies whether using the master node if master-slave configured.
-func (m *Model) getLink(master bool) dbLink {
+// It returns the error if the link cannot be retrieved, eg: ErrMasterUnavailable of the open breaker.
+func (m *Model) getLink(master bool) (dbLink, error) {
 	if m.tx != nil {
-		return m.tx.tx
+		return m.tx.link, nil
 	}
 	linkType := m.linkType
+	// The locking query should be executed on master node.
+	if m.lock != "" {
+		linkType = gLINK_TYPE_MASTER
+	}
 	if linkType == 0 {
 		if master {
 			linkType = gLINK_TYPE_MASTER
@@ -1057,22 +1238,32 @@ func (m *Model) getLink(master bool) dbLink {
 			linkType = gLINK_TYPE_SLAVE
 		}
 	}
-	switch linkType {
-	case gLINK_TYPE_MASTER:
-		link, _ := m.db.getMaster(m.schema)
-		return link
-	case gLINK_TYPE_SLAVE:
-		link, _ := m.db.getSlave(m.schema)
-		return link
+	var (
+		link *sql.DB
+		err  error
+	)
+	if linkType == gLINK_TYPE_MASTER {
+		link, err = m.db.getMaster(m.schema)
+	} else if m.maxStaleness > 0 {
+		link, err = m.db.getSlaveWithStaleness(m.maxStaleness, m.schema)
+	} else {
+		link, err = m.db.getSlave(m.schema)
+	}
+	// Note that it should not return the nil *sql.DB, which is a non-nil dbLink.
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return link, nil
 }
 
 // getAll does the query from database.
 func (m *Model) getAll(query string, args ...interface{}) (result Result, err error) {
+	if m.lockErr != nil {
+		return nil, m.lockErr
+	}
 	cacheKey := ""
-	// Retrieve from cache.
-	if m.cacheEnabled {
+	// Retrieve from cache, which is skipped by the locking query as it must read from database.
+	if m.cacheEnabled && m.lock == "" {
 		cacheKey = m.cacheName
 		if len(cacheKey) == 0 {
 			cacheKey = query + "/" + gconv.String(args)
@@ -1081,18 +1272,31 @@ func (m *Model) getAll(query string, args ...interface{}) (result Result, err er
 			return v.(Result), nil
 		}
 	}
-	result, err = m.db.doGetAll(m.getLink(false), query, args...)
+	link, err := m.getLink(false)
+	if err != nil {
+		return nil, err
+	}
+	tables := m.db.getQueryTables(query)
+	versions := m.db.getTableVersions(tables)
+	result, err = m.db.doGetAll(link, query, args...)
 	// Cache the result.
 	if len(cacheKey) > 0 && err == nil {
 		if m.cacheDuration < 0 {
 			m.db.getCache().Remove(cacheKey)
 		} else {
-			m.db.getCache().Set(cacheKey, result, m.cacheDuration)
+			m.db.setQueryCache(cacheKey, result, m.cacheDuration, tables, versions)
 		}
 	}
 	return result, err
 }
 
+// getSelectSql returns the "SELECT ..." statement of the model and its arguments.
+// The parameter <limit> specifies whether it retrieves only one record if no limit set.
+func (m *Model) getSelectSql(limit bool) (string, []interface{}) {
+	condition, conditionArgs := m.formatCondition(limit)
+	return fmt.Sprintf("SELECT %s FROM %s%s%s", m.fields, m.tables, condition, m.lock), conditionArgs
+}
+
 // getPrimaryKey retrieves and returns the primary key name of the model table.
 // It parses m.tables to retrieve the primary table name, supporting m.tables like:
 // "user", "user u", "user as u, user_detail as ud".
@@ -1117,6 +1321,17 @@ func (m *Model) checkAndRemoveCache() {
 	}
 }
 
+// formatFullTableCondition formats the condition for Update/Delete operations. It adds an always
+// true WHERE statement to the condition if OPTION_ALLOWFULLTABLE option is set and there's no
+// WHERE condition, which marks the full table operation explicitly.
+func (m *Model) formatFullTableCondition() (condition string, conditionArgs []interface{}) {
+	condition, conditionArgs = m.formatCondition(false)
+	if m.option&OPTION_ALLOWFULLTABLE > 0 && !gstr.HasPrefix(condition, " WHERE ") {
+		condition = " WHERE 1=1" + condition
+	}
+	return
+}
+
 // formatCondition formats where arguments of the model and returns a new condition sql and its arguments.
 // Note that this function does not change any attribute value of the <m>.
 //
@@ -1170,16 +1385,21 @@ func (m *Model) formatCondition(limit bool) (condition string, conditionArgs []i
 	if m.orderBy != "" {
 		condition += " ORDER BY " + m.orderBy
 	}
-	if m.limit != 0 {
-		if m.start >= 0 {
-			condition += fmt.Sprintf(" LIMIT %d,%d", m.start, m.limit)
-		} else {
-			condition += fmt.Sprintf(" LIMIT %d", m.limit)
+	// The limit statement is built by the driver as it differs between databases.
+	count := m.limit
+	if count == 0 && limit {
+		count = 1
+	}
+	if count != 0 {
+		start := m.start
+		if start < 0 {
+			start = 0
+			if m.offset >= 0 {
+				start = m.offset
+			}
 		}
-	} else if limit {
-		condition += " LIMIT 1"
-	}
-	if m.offset >= 0 {
+		condition += m.db.getLimit(start, count)
+	} else if m.offset >= 0 {
 		condition += fmt.Sprintf(" OFFSET %d", m.offset)
 	}
 	return
@@ -13,11 +13,13 @@ package gdb
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/gogf/gf/internal/intlog"
 	"github.com/gogf/gf/text/gstr"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gogf/gf/text/gregex"
 )
@@ -36,11 +38,11 @@ func (db *dbMssql) Open(config *ConfigNode) (*sql.DB, error) {
 			config.User, config.Pass, config.Host, config.Port, config.Name,
 		)
 	}
-	intlog.Printf("Open: %s", source)
+	intlog.Printf("Open: %s", maskDSN(source))
 	if db, err := sql.Open("sqlserver", source); err == nil {
 		return db, nil
 	} else {
-		return nil, err
+		return nil, formatOpenError(err, source, config.Pass)
 	}
 }
 
@@ -55,9 +57,65 @@ func (db *dbMssql) handleSqlBeforeExec(query string) string {
 		return fmt.Sprintf("@p%d", index)
 	})
 	str, _ = gregex.ReplaceString("\"", "", str)
+	// The "OFFSET ... FETCH ..." statement requires "ORDER BY" statement in T-SQL.
+	// The "ORDER BY" clause in sub query does not count.
+	if gregex.IsMatchString(`(?i)\sOFFSET\s+\d+\s+ROWS\s+FETCH\s+NEXT\s+\d+\s+ROWS\s+ONLY\s*$`, str) &&
+		getOrderByIndex(str) < 0 {
+		str, _ = gregex.ReplaceString(`(?i)(\sOFFSET\s+\d+\s+ROWS\s+FETCH)`, ` ORDER BY (SELECT NULL)$1`, str)
+	}
 	return db.parseSql(str)
 }
 
+// getLimit returns the limit statement using "OFFSET ... FETCH ..." syntax of SQL Server.
+func (db *dbMssql) getLimit(start int, limit int) string {
+	return fmt.Sprintf(" OFFSET %d ROWS FETCH NEXT %d ROWS ONLY", start, limit)
+}
+
+// getLockSql returns error as SQL Server uses table hints for locking,
+// eg: "WITH (UPDLOCK, ROWLOCK)", which should be specified in the table name.
+func (db *dbMssql) getLockSql(shared bool) (string, error) {
+	return "", errors.New("locking clause is not supported by mssql, use table hints like WITH (UPDLOCK, ROWLOCK) instead")
+}
+
+// getSavepointSql returns the savepoint statements of SQL Server, which does not support releasing.
+func (db *dbMssql) getSavepointSql(name string) (save string, rollback string, release string) {
+	return "SAVE TRANSACTION " + name, "ROLLBACK TRANSACTION " + name, ""
+}
+
+// getTxOptions returns nil for the default options, as the mssql driver does not support read-only
+// transaction, of which only the Exec statements are rejected with ErrReadOnlyTx.
+func (db *dbMssql) getTxOptions(readOnly bool) *sql.TxOptions {
+	return nil
+}
+
+// getExplainSql returns error as SQL Server uses the session option "SET SHOWPLAN_ALL ON"
+// for the execution plan, which cannot be used on the connection pool.
+func (db *dbMssql) getExplainSql(query string) (string, error) {
+	return "", errors.New("EXPLAIN is not supported by mssql, use SET SHOWPLAN_ALL on one connection instead")
+}
+
+// getReplicaLag returns error as the replication lag of mssql is not supported.
+func (db *dbMssql) getReplicaLag(link dbLink) (time.Duration, error) {
+	return 0, errors.New("replica lag is not supported by mssql")
+}
+
+// classifyError returns the kind and the error number of driver error <err>. The error 547 is
+// also raised for check constraint, so it's classified by the message.
+func (db *dbMssql) classifyError(err error) (kind error, code string) {
+	code = getDriverErrorCode(err, "Number")
+	switch code {
+	case "2601", "2627":
+		kind = ErrUniqueViolation
+	case "547":
+		if strings.Contains(err.Error(), "FOREIGN KEY") {
+			kind = ErrForeignKeyViolation
+		}
+	case "515":
+		kind = ErrNotNullViolation
+	}
+	return
+}
+
 func (db *dbMssql) parseSql(sql string) string {
 	// SELECT * FROM USER WHERE ID=1 LIMIT 1
 	if m, _ := gregex.MatchString(`^SELECT(.+)LIMIT 1$`, sql); len(m) > 1 {
@@ -151,37 +209,58 @@ func (db *dbMssql) parseSql(sql string) string {
 	return sql
 }
 
+// getTableExistsSql returns the statement retrieving the base table of current database.
+func (db *dbMssql) getTableExistsSql() string {
+	return "SELECT TABLE_NAME FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_TYPE='BASE TABLE' AND TABLE_NAME=?"
+}
+
+// getVersionSql returns the statement retrieving the product version of the server, eg: "15.0.2000.5".
+func (db *dbMssql) getVersionSql() string {
+	return "SELECT CAST(SERVERPROPERTY('ProductVersion') AS VARCHAR(128))"
+}
+
+// getMaxPlaceholders returns the max place holder count in one statement, as mssql supports
+// at most 2100 parameters in one request, of which one is reserved for the statement itself.
+func (db *dbMssql) getMaxPlaceholders() int {
+	return 2099
+}
+
 // TODO
 func (db *dbMssql) Tables(schema ...string) (tables []string, err error) {
 	return
 }
 
+// TableFields retrieves and returns the fields of given table of current database.
+// Also see dbBase.TableFields.
 func (db *dbMssql) TableFields(table string, schema ...string) (fields map[string]*TableField, err error) {
 	table = gstr.Trim(table)
 	if gstr.Contains(table, " ") {
 		panic("function TableFields supports only single table operations")
 	}
-	checkSchema := db.schema.Val()
-	if len(schema) > 0 && schema[0] != "" {
-		checkSchema = schema[0]
-	}
-	v := db.cache.GetOrSetFunc(
-		fmt.Sprintf(`mssql_table_fields_%s_%s`, table, checkSchema), func() interface{} {
+	table, checkSchema := db.getTableSchema(table, schema...)
+	v := db.getOrSetTableFields(
+		db.getTableFieldsCacheKey(table, checkSchema), func() interface{} {
 			var result Result
 			var link *sql.DB
 			link, err = db.getSlave(checkSchema)
 			if err != nil {
 				return nil
 			}
-			result, err = db.doGetAll(link, fmt.Sprintf(`
+			result, err = db.doGetAll(link, `
 			SELECT c.name as FIELD, CASE t.name 
 				WHEN 'numeric' THEN t.name + '(' + convert(varchar(20),c.xprec) + ',' + convert(varchar(20),c.xscale) + ')' 
 				WHEN 'char' THEN t.name + '(' + convert(varchar(20),c.length)+ ')'
 				WHEN 'varchar' THEN t.name + '(' + convert(varchar(20),c.length)+ ')'
-				ELSE t.name + '(' + convert(varchar(20),c.length)+ ')' END as TYPE
+				ELSE t.name + '(' + convert(varchar(20),c.length)+ ')' END as TYPE,
+				c.isnullable as NULLABLE,
+				CASE WHEN EXISTS(
+					SELECT 1 FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc, INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu
+					WHERE tc.CONSTRAINT_NAME = kcu.CONSTRAINT_NAME AND tc.CONSTRAINT_TYPE = 'PRIMARY KEY'
+					AND kcu.TABLE_NAME = ? AND kcu.COLUMN_NAME = c.name
+				) THEN 'PRI' ELSE '' END as COLUMN_KEY
 			FROM systypes t,syscolumns c WHERE t.xtype=c.xtype 
-			AND c.id = (SELECT id FROM sysobjects WHERE name='%s') 
-			ORDER BY c.colid`, strings.ToUpper(table)))
+			AND c.id = (SELECT id FROM sysobjects WHERE name=?) 
+			ORDER BY c.colid`, table, table)
 			if err != nil {
 				return nil
 			}
@@ -191,10 +270,12 @@ func (db *dbMssql) TableFields(table string, schema ...string) (fields map[strin
 					Index: i,
 					Name:  strings.ToLower(m["FIELD"].String()),
 					Type:  strings.ToLower(m["TYPE"].String()),
+					Null:  m["NULLABLE"].Bool(),
+					Key:   m["COLUMN_KEY"].String(),
 				}
 			}
 			return fields
-		}, 0)
+		})
 	if err == nil {
 		fields = v.(map[string]*TableField)
 	}
@@ -29,11 +29,11 @@ func (db *dbMysql) Open(config *ConfigNode) (*sql.DB, error) {
 			config.User, config.Pass, config.Host, config.Port, config.Name, config.Charset,
 		)
 	}
-	intlog.Printf("Open: %s", source)
+	intlog.Printf("Open: %s", maskDSN(source))
 	if db, err := sql.Open("gf-mysql", source); err == nil {
 		return db, nil
 	} else {
-		return nil, err
+		return nil, formatOpenError(err, source, config.Pass)
 	}
 }
 
@@ -6,20 +6,25 @@
 //
 // Note:
 // 1. It needs manually import: _ "github.com/mattn/go-oci8"
-// 2. It does not support Save/Replace features.
+// 2. The Save/Replace features use MERGE statement on the unique index of which all the columns
+//    are in the data, or else they are the same as Insert.
 // 3. It does not support LastInsertId.
 
 package gdb
 
 import (
+	"bytes"
 	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/gogf/gf/internal/intlog"
 	"github.com/gogf/gf/text/gstr"
 	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gogf/gf/text/gregex"
 )
@@ -33,6 +38,10 @@ const (
 	tableAlias2 = "GFORM2"
 )
 
+// oracleErrorCodeReg is the regular expression object for the error code in error message,
+// eg: "ORA-00001: unique constraint violated".
+var oracleErrorCodeReg = regexp.MustCompile(`ORA-(\d{5})`)
+
 func (db *dbOracle) Open(config *ConfigNode) (*sql.DB, error) {
 	var source string
 	if config.LinkInfo != "" {
@@ -40,11 +49,11 @@ func (db *dbOracle) Open(config *ConfigNode) (*sql.DB, error) {
 	} else {
 		source = fmt.Sprintf("%s/%s@%s", config.User, config.Pass, config.Name)
 	}
-	intlog.Printf("Open: %s", source)
+	intlog.Printf("Open: %s", maskDSN(source))
 	if db, err := sql.Open("oci8", source); err == nil {
 		return db, nil
 	} else {
-		return nil, err
+		return nil, formatOpenError(err, source, config.Pass)
 	}
 }
 
@@ -52,14 +61,94 @@ func (db *dbOracle) getChars() (charLeft string, charRight string) {
 	return "\"", "\""
 }
 
+// handleSqlBeforeExec converts the '?' holders to the positional binds ":1", ":2"... of Oracle,
+// and removes the double quotes of the identifiers quoted by the package, so that the identifiers
+// are case-insensitive like the unquoted ones. The quoted strings and comments are not changed.
 func (db *dbOracle) handleSqlBeforeExec(query string) string {
-	index := 0
-	str, _ := gregex.ReplaceStringFunc("\\?", query, func(s string) string {
-		index++
+	query = replacePlaceholders(query, func(index int) string {
 		return fmt.Sprintf(":%d", index)
 	})
-	str, _ = gregex.ReplaceString("\"", "", str)
-	return db.parseSql(str)
+	return db.parseSql(removeIdentifierQuotes(query))
+}
+
+// removeIdentifierQuotes removes the double quotes of the identifiers in <query>,
+// eg: `SELECT "id" FROM "user"` to `SELECT id FROM user`.
+// The quoted strings and comments are not changed.
+func removeIdentifierQuotes(query string) string {
+	if strings.IndexByte(query, '"') == -1 {
+		return query
+	}
+	buffer := bytes.NewBuffer(nil)
+	for i := 0; i < len(query); {
+		end := i + 1
+		switch c := query[i]; {
+		case c == '"':
+			i = end
+			continue
+
+		case c == '\'':
+			end = indexQuoteEnd(query, i)
+
+		case strings.HasPrefix(query[i:], "--"):
+			end = indexFrom(query, "\n", i)
+
+		case strings.HasPrefix(query[i:], "/*"):
+			end = indexFrom(query, "*/", i+2) + 2
+		}
+		if end > len(query) {
+			end = len(query)
+		}
+		buffer.WriteString(query[i:end])
+		i = end
+	}
+	return buffer.String()
+}
+
+// getLimit returns the limit statement in "LIMIT start, limit" syntax, which is converted
+// to ROWNUM statement by parseSql.
+func (db *dbOracle) getLimit(start int, limit int) string {
+	return fmt.Sprintf(" LIMIT %d,%d", start, limit)
+}
+
+// getLockSql returns the locking clause of Oracle, which does not support shared lock for records.
+func (db *dbOracle) getLockSql(shared bool) (string, error) {
+	if shared {
+		return "", errors.New("shared lock is not supported by oracle")
+	}
+	return " FOR UPDATE", nil
+}
+
+// getSavepointSql returns the savepoint statements of Oracle, which does not support releasing.
+func (db *dbOracle) getSavepointSql(name string) (save string, rollback string, release string) {
+	return "SAVEPOINT " + name, "ROLLBACK TO SAVEPOINT " + name, ""
+}
+
+// getExplainSql returns error as Oracle writes the execution plan of "EXPLAIN PLAN FOR" statement
+// into the plan table of the session, which cannot be used on the connection pool.
+func (db *dbOracle) getExplainSql(query string) (string, error) {
+	return "", errors.New("EXPLAIN is not supported by oracle, use EXPLAIN PLAN FOR on one connection instead")
+}
+
+// getReplicaLag returns error as the replication lag of oracle is not supported.
+func (db *dbOracle) getReplicaLag(link dbLink) (time.Duration, error) {
+	return 0, errors.New("replica lag is not supported by oracle")
+}
+
+// classifyError returns the kind and the error code of driver error <err>, which is parsed from
+// the error message like "ORA-00001: unique constraint violated".
+func (db *dbOracle) classifyError(err error) (kind error, code string) {
+	if match := oracleErrorCodeReg.FindStringSubmatch(err.Error()); match != nil {
+		code = match[1]
+	}
+	switch code {
+	case "00001":
+		kind = ErrUniqueViolation
+	case "02291", "02292":
+		kind = ErrForeignKeyViolation
+	case "01400", "01407":
+		kind = ErrNotNullViolation
+	}
+	return
 }
 
 func (db *dbOracle) parseSql(sql string) string {
@@ -109,36 +198,70 @@ func (db *dbOracle) parseSql(sql string) string {
 			}
 		}
 
-		//也可以使用between,据说这种写法的性能会比between好点,里层SQL中的ROWNUM_ >= limit可以缩小查询后的数据集规模
-		sql = fmt.Sprintf("SELECT * FROM (SELECT GFORM.*, ROWNUM ROWNUM_ FROM (%s %s) GFORM WHERE ROWNUM <= %d) WHERE ROWNUM_ >= %d", queryExpr[1], queryExpr[2], limit, first)
+		// The "LIMIT first, limit" retrieves the rows numbered from first+1 to first+limit.
+		// The ROWNUM condition of the inner query reduces the rows of the outer query.
+		sql = fmt.Sprintf(
+			"SELECT * FROM (SELECT GFORM.*, ROWNUM ROWNUM_ FROM (%s %s) GFORM WHERE ROWNUM <= %d) WHERE ROWNUM_ > %d",
+			queryExpr[1], queryExpr[2], first+limit, first,
+		)
 	}
 	return sql
 }
 
-// TODO
+// getTableExistsSql returns the statement retrieving the table of current user,
+// whose name is stored in upper case in default.
+func (db *dbOracle) getTableExistsSql() string {
+	return "SELECT TABLE_NAME FROM USER_TABLES WHERE TABLE_NAME=UPPER(?)"
+}
+
+// getVersionSql returns the statement retrieving the version of the database product, eg: "19.0.0.0.0".
+func (db *dbOracle) getVersionSql() string {
+	return "SELECT VERSION FROM PRODUCT_COMPONENT_VERSION WHERE PRODUCT LIKE 'Oracle%' AND ROWNUM=1"
+}
+
+// Tables retrieves and returns the tables of current user from USER_TABLES,
+// or the tables of given schema from ALL_TABLES. The table names are in lower case.
 func (db *dbOracle) Tables(schema ...string) (tables []string, err error) {
+	var result Result
+	if len(schema) > 0 && schema[0] != "" {
+		result, err = db.GetAll(
+			`SELECT TABLE_NAME FROM ALL_TABLES WHERE OWNER=? ORDER BY TABLE_NAME`, strings.ToUpper(schema[0]),
+		)
+	} else {
+		result, err = db.GetAll(`SELECT TABLE_NAME FROM USER_TABLES ORDER BY TABLE_NAME`)
+	}
+	if err != nil {
+		return
+	}
+	for _, m := range result {
+		tables = append(tables, strings.ToLower(m["TABLE_NAME"].String()))
+	}
 	return
 }
 
+// TableFields retrieves and returns the fields of given table of current user from USER_TAB_COLUMNS.
+// Also see dbBase.TableFields.
 func (db *dbOracle) TableFields(table string, schema ...string) (fields map[string]*TableField, err error) {
 	table = gstr.Trim(table)
 	if gstr.Contains(table, " ") {
 		panic("function TableFields supports only single table operations")
 	}
-	checkSchema := db.schema.Val()
-	if len(schema) > 0 && schema[0] != "" {
-		checkSchema = schema[0]
-	}
-	v := db.cache.GetOrSetFunc(
-		fmt.Sprintf(`oracle_table_fields_%s_%s`, table, checkSchema),
+	table, checkSchema := db.getTableSchema(table, schema...)
+	v := db.getOrSetTableFields(
+		db.getTableFieldsCacheKey(table, checkSchema),
 		func() interface{} {
 			result := (Result)(nil)
-			result, err = db.GetAll(fmt.Sprintf(`
-			SELECT COLUMN_NAME AS FIELD, CASE DATA_TYPE 
-			    WHEN 'NUMBER' THEN DATA_TYPE||'('||DATA_PRECISION||','||DATA_SCALE||')' 
-				WHEN 'FLOAT' THEN DATA_TYPE||'('||DATA_PRECISION||','||DATA_SCALE||')' 
-				ELSE DATA_TYPE||'('||DATA_LENGTH||')' END AS TYPE  
-			FROM USER_TAB_COLUMNS WHERE TABLE_NAME = '%s' ORDER BY COLUMN_ID`, strings.ToUpper(table)))
+			result, err = db.GetAll(`
+			SELECT c.COLUMN_NAME AS FIELD, CASE c.DATA_TYPE 
+			    WHEN 'NUMBER' THEN c.DATA_TYPE||'('||c.DATA_PRECISION||','||c.DATA_SCALE||')' 
+				WHEN 'FLOAT' THEN c.DATA_TYPE||'('||c.DATA_PRECISION||','||c.DATA_SCALE||')' 
+				ELSE c.DATA_TYPE||'('||c.DATA_LENGTH||')' END AS TYPE, c.NULLABLE AS NULLABLE,
+				CASE WHEN EXISTS(
+					SELECT 1 FROM USER_CONSTRAINTS uc, USER_CONS_COLUMNS ucc
+					WHERE uc.CONSTRAINT_NAME = ucc.CONSTRAINT_NAME AND uc.CONSTRAINT_TYPE = 'P'
+					AND ucc.TABLE_NAME = c.TABLE_NAME AND ucc.COLUMN_NAME = c.COLUMN_NAME
+				) THEN 'PRI' END AS COLUMN_KEY
+			FROM USER_TAB_COLUMNS c WHERE c.TABLE_NAME = ? ORDER BY c.COLUMN_ID`, strings.ToUpper(table))
 			if err != nil {
 				return nil
 			}
@@ -148,33 +271,40 @@ func (db *dbOracle) TableFields(table string, schema ...string) (fields map[stri
 					Index: i,
 					Name:  strings.ToLower(m["FIELD"].String()),
 					Type:  strings.ToLower(m["TYPE"].String()),
+					Null:  m["NULLABLE"].String() == "Y",
+					Key:   m["COLUMN_KEY"].String(),
 				}
 			}
 			return fields
-		}, 0)
+		})
 	if err == nil {
 		fields = v.(map[string]*TableField)
 	}
 	return
 }
 
+// getTableUniqueIndex retrieves and returns the unique indexes of given table, including the one
+// of primary key. The key of the result is the index name, and the value is the column names
+// in upper case mapping to their char lengths.
 func (db *dbOracle) getTableUniqueIndex(table string) (fields map[string]map[string]string, err error) {
-	table = strings.ToUpper(table)
+	table = strings.ToUpper(removeIdentifierQuotes(table))
 	v := db.cache.GetOrSetFunc("table_unique_index_"+table, func() interface{} {
 		res := (Result)(nil)
-		res, err = db.GetAll(fmt.Sprintf(`
+		res, err = db.GetAll(`
 		SELECT INDEX_NAME,COLUMN_NAME,CHAR_LENGTH FROM USER_IND_COLUMNS 
-		WHERE TABLE_NAME = '%s' 
-		AND INDEX_NAME IN(SELECT INDEX_NAME FROM USER_INDEXES WHERE TABLE_NAME='%s' AND UNIQUENESS='UNIQUE') 
-		ORDER BY INDEX_NAME,COLUMN_POSITION`, table, table))
+		WHERE TABLE_NAME = ? 
+		AND INDEX_NAME IN(SELECT INDEX_NAME FROM USER_INDEXES WHERE TABLE_NAME = ? AND UNIQUENESS='UNIQUE') 
+		ORDER BY INDEX_NAME,COLUMN_POSITION`, table, table)
 		if err != nil {
 			return nil
 		}
 		fields := make(map[string]map[string]string)
 		for _, v := range res {
-			mm := make(map[string]string)
-			mm[v["COLUMN_NAME"].String()] = v["CHAR_LENGTH"].String()
-			fields[v["INDEX_NAME"].String()] = mm
+			name := v["INDEX_NAME"].String()
+			if _, ok := fields[name]; !ok {
+				fields[name] = make(map[string]string)
+			}
+			fields[name][v["COLUMN_NAME"].String()] = v["CHAR_LENGTH"].String()
 		}
 		return fields
 	}, 0)
@@ -184,11 +314,29 @@ func (db *dbOracle) getTableUniqueIndex(table string) (fields map[string]map[str
 	return
 }
 
+// containsAllColumns checks and returns whether <data> contains all the columns of <index>,
+// which are in upper case. The keys of <data> are compared case-insensitively.
+func containsAllColumns(data Map, index map[string]string) bool {
+	keys := make(map[string]struct{}, len(data))
+	for k := range data {
+		keys[strings.ToUpper(k)] = struct{}{}
+	}
+	for column := range index {
+		if _, ok := keys[column]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
 func (db *dbOracle) doInsert(link dbLink, table string, data interface{}, option int, batch ...int) (result sql.Result, err error) {
 	var fields []string
 	var values []string
 	var params []interface{}
 	var dataMap Map
+	table = db.db.handleTableName(table)
+	defer db.clearTableCache(link, table)
+	data, ordered := getOrderedData(data)
 	rv := reflect.ValueOf(data)
 	kind := rv.Kind()
 	if kind == reflect.Ptr {
@@ -199,14 +347,21 @@ func (db *dbOracle) doInsert(link dbLink, table string, data interface{}, option
 	case reflect.Slice:
 		fallthrough
 	case reflect.Array:
-		return db.db.doBatchInsert(link, table, data, option, batch...)
+		return db.db.doBatchInsert(link, table, ordered.withData(data), option, batch...)
 	case reflect.Map:
 		fallthrough
 	case reflect.Struct:
-		dataMap = varToMapDeep(data)
+		if dataMap, err = varToMapDeep(data); err != nil {
+			return nil, err
+		}
 	default:
 		return result, errors.New(fmt.Sprint("unsupported data type:", kind))
 	}
+	dataMap = db.filterColumnData(table, dataMap)
+	if len(dataMap) == 0 {
+		return nil, errors.New("data cannot be empty")
+	}
+	dataMap = db.convertData(table, dataMap)
 
 	indexs := make([]string, 0)
 	indexMap := make(map[string]string)
@@ -217,15 +372,24 @@ func (db *dbOracle) doInsert(link dbLink, table string, data interface{}, option
 			return nil, err
 		}
 
-		if len(index) > 0 {
-			for _, v := range index {
-				for k, _ := range v {
-					indexs = append(indexs, k)
-				}
-				indexMap = v
-				indexExists = true
-				break
+		// It uses the unique index of which all the columns are in the data,
+		// and the index name is used for choosing among them in stable order.
+		names := make([]string, 0, len(index))
+		for name := range index {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !containsAllColumns(dataMap, index[name]) {
+				continue
+			}
+			for k := range index[name] {
+				indexs = append(indexs, k)
 			}
+			sort.Strings(indexs)
+			indexMap = index[name]
+			indexExists = true
+			break
 		}
 
 	}
@@ -234,17 +398,25 @@ func (db *dbOracle) doInsert(link dbLink, table string, data interface{}, option
 	onStr := make([]string, 0)
 	updateStr := make([]string, 0)
 
-	charL, charR := db.db.getChars()
-	for k, v := range dataMap {
+	columns, err := getOrderedColumns(dataMap, ordered)
+	if err != nil {
+		return nil, err
+	}
+	charL, charR := db.db.getQuoteChars()
+	for _, k := range columns {
+		v := dataMap[k]
 		k = strings.ToUpper(k)
 
 		// 操作类型为REPLACE/SAVE时且存在唯一索引才使用merge，否则使用insert
 		if (option == gINSERT_OPTION_REPLACE || option == gINSERT_OPTION_SAVE) && indexExists {
 			fields = append(fields, tableAlias1+"."+charL+k+charR)
 			values = append(values, tableAlias2+"."+charL+k+charR)
-			params = append(params, v)
-
-			subSqlStr = append(subSqlStr, fmt.Sprintf("%s?%s %s", charL, charR, k))
+			if raw, ok := v.(Raw); ok {
+				subSqlStr = append(subSqlStr, fmt.Sprintf("%s %s", raw, k))
+			} else {
+				params = append(params, v)
+				subSqlStr = append(subSqlStr, fmt.Sprintf("? %s", k))
+			}
 
 			//merge中的on子句中由唯一索引组成,update子句中不含唯一索引
 			if _, ok := indexMap[k]; ok {
@@ -254,8 +426,12 @@ func (db *dbOracle) doInsert(link dbLink, table string, data interface{}, option
 			}
 		} else {
 			fields = append(fields, charL+k+charR)
-			values = append(values, "?")
-			params = append(params, v)
+			if raw, ok := v.(Raw); ok {
+				values = append(values, string(raw))
+			} else {
+				values = append(values, "?")
+				params = append(params, v)
+			}
 		}
 	}
 
@@ -270,10 +446,15 @@ func (db *dbOracle) doInsert(link dbLink, table string, data interface{}, option
 		case gINSERT_OPTION_REPLACE:
 			fallthrough
 		case gINSERT_OPTION_SAVE:
+			// The "WHEN MATCHED" clause is omitted if all the columns are of the unique index.
+			matchedStr := ""
+			if len(updateStr) > 0 {
+				matchedStr = " WHEN MATCHED THEN UPDATE SET " + strings.Join(updateStr, ",")
+			}
 			tmp := fmt.Sprintf(
-				"MERGE INTO %s %s USING(SELECT %s FROM DUAL) %s ON(%s) WHEN MATCHED THEN UPDATE SET %s WHEN NOT MATCHED THEN INSERT (%s) VALUES(%s)",
+				"MERGE INTO %s %s USING(SELECT %s FROM DUAL) %s ON(%s)%s WHEN NOT MATCHED THEN INSERT (%s) VALUES(%s)",
 				table, tableAlias1, strings.Join(subSqlStr, ","), tableAlias2,
-				strings.Join(onStr, "AND"), strings.Join(updateStr, ","), strings.Join(fields, ","), strings.Join(values, ","),
+				strings.Join(onStr, "AND "), matchedStr, strings.Join(fields, ","), strings.Join(values, ","),
 			)
 			return db.db.doExec(link, tmp, params...)
 		case gINSERT_OPTION_IGNORE:
@@ -299,64 +480,27 @@ func (db *dbOracle) doBatchInsert(link dbLink, table string, list interface{}, o
 	var keys []string
 	var values []string
 	var params []interface{}
-	listMap := (List)(nil)
-	switch v := list.(type) {
-	case Result:
-		listMap = v.List()
-	case Record:
-		listMap = List{v.Map()}
-	case List:
-		listMap = v
-	case Map:
-		listMap = List{v}
-	default:
-		rv := reflect.ValueOf(list)
-		kind := rv.Kind()
-		if kind == reflect.Ptr {
-			rv = rv.Elem()
-			kind = rv.Kind()
-		}
-		switch kind {
-		// 如果是slice，那么转换为List类型
-		case reflect.Slice:
-			fallthrough
-		case reflect.Array:
-			listMap = make(List, rv.Len())
-			for i := 0; i < rv.Len(); i++ {
-				listMap[i] = varToMapDeep(rv.Index(i).Interface())
-			}
-		case reflect.Map:
-			fallthrough
-		case reflect.Struct:
-			listMap = List{Map(varToMapDeep(list))}
-		default:
-			return result, errors.New(fmt.Sprint("unsupported list type:", kind))
-		}
-	}
-	// 判断长度
-	if len(listMap) < 1 {
-		return result, errors.New("empty data list")
-	}
+	table = db.db.handleTableName(table)
+	defer db.clearTableCache(link, table)
 	if link == nil {
 		if link, err = db.db.Master(); err != nil {
 			return
 		}
 	}
-	// 首先获取字段名称及记录长度
-	holders := []string(nil)
-	for k, _ := range listMap[0] {
-		keys = append(keys, k)
-		holders = append(holders, "?")
-	}
 	batchResult := new(batchSqlResult)
-	charL, charR := db.db.getChars()
-	keyStr := charL + strings.Join(keys, charL+","+charR) + charR
-	valueHolderStr := strings.Join(holders, ",")
 
-	// 当操作类型非insert时调用单笔的insert功能
+	// 当操作类型非insert时调用单笔的insert功能, which filters and converts each record itself.
 	if option != gINSERT_OPTION_DEFAULT {
+		list, ordered := getOrderedData(list)
+		listMap, err := varToList(list)
+		if err != nil {
+			return nil, err
+		}
+		if len(listMap) < 1 {
+			return nil, errors.New("data list cannot be empty")
+		}
 		for _, v := range listMap {
-			r, err := db.doInsert(link, table, v, option, 1)
+			r, err := db.doInsert(link, table, ordered.withData(v), option, 1)
 			if err != nil {
 				return r, err
 			}
@@ -366,25 +510,36 @@ func (db *dbOracle) doBatchInsert(link dbLink, table string, list interface{}, o
 			} else {
 				batchResult.lastResult = r
 				batchResult.rowsAffected += n
+				// The MERGE statement affects 1 row no matter whether the record is inserted or updated.
+				if option == gINSERT_OPTION_SAVE {
+					batchResult.savedRows += n
+				}
 			}
 		}
 		return batchResult, nil
 	}
 
-	// 构造批量写入数据格式(注意map的遍历是无序的)
-	batchNum := gDEFAULT_BATCH_NUM
-	if len(batch) > 0 {
-		batchNum = batch[0]
+	listMap, ordered, err := db.getBatchData(table, list)
+	if err != nil {
+		return nil, err
+	}
+	// 首先获取字段名称及记录长度
+	if keys, err = getOrderedColumns(listMap[0], ordered); err != nil {
+		return nil, err
 	}
+	charL, charR := db.db.getQuoteChars()
+	keyStr := charL + strings.Join(keys, charR+","+charL) + charR
+
+	// 构造批量写入数据格式(注意map的遍历是无序的)
+	batchNum := db.getBatchNum(len(keys), batch)
 
 	intoStr := make([]string, 0) //组装into语句
 	for i := 0; i < len(listMap); i++ {
-		for _, k := range keys {
-			params = append(params, listMap[i][k])
-		}
+		valueHolderStr, rowParams := getRowHolder(listMap[i], keys)
+		params = append(params, rowParams...)
 		values = append(values, valueHolderStr)
 
-		intoStr = append(intoStr, fmt.Sprintf(" INTO %s(%s) VALUES(%s) ", table, keyStr, valueHolderStr))
+		intoStr = append(intoStr, fmt.Sprintf(" INTO %s(%s) VALUES%s ", table, keyStr, valueHolderStr))
 		if len(intoStr) == batchNum {
 			r, err := db.db.doExec(link, fmt.Sprintf("INSERT ALL   %s SELECT * FROM DUAL", strings.Join(intoStr, " ")), params...)
 			if err != nil {
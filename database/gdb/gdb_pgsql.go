@@ -6,17 +6,23 @@
 //
 // Note:
 // 1. It needs manually import: _ "github.com/lib/pq"
-// 2. It does not support Save/Replace features.
+// 2. It does not support Replace feature, and Save uses "ON CONFLICT ... DO UPDATE" clause.
 // 3. It does not support LastInsertId.
 
 package gdb
 
 import (
+	"bytes"
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/gogf/gf/internal/intlog"
 	"github.com/gogf/gf/text/gstr"
+	"github.com/gogf/gf/util/gconv"
+	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gogf/gf/text/gregex"
 )
@@ -35,11 +41,11 @@ func (db *dbPgsql) Open(config *ConfigNode) (*sql.DB, error) {
 			config.User, config.Pass, config.Host, config.Port, config.Name,
 		)
 	}
-	intlog.Printf("Open: %s", source)
+	intlog.Printf("Open: %s", maskDSN(source))
 	if db, err := sql.Open("postgres", source); err == nil {
 		return db, nil
 	} else {
-		return nil, err
+		return nil, formatOpenError(err, source, config.Pass)
 	}
 }
 
@@ -47,44 +53,394 @@ func (db *dbPgsql) getChars() (charLeft string, charRight string) {
 	return "\"", "\""
 }
 
+// getCollate returns the COLLATE clause using quoted collation name, eg: COLLATE "C".
+func (db *dbPgsql) getCollate(collation string) string {
+	return ` COLLATE "` + collation + `"`
+}
+
+// convertValue converts the BIT/VARBIT values of PostgreSQL, which are returned as binary digit
+// strings like "101", to integers. The values of array types, of which the type names start with "_",
+// eg: "_int4", "_text", are converted to []interface{}, and the elements are converted using
+// the element type. Other types are converted by dbBase.convertValue.
+func (db *dbPgsql) convertValue(fieldValue []byte, fieldType string) interface{} {
+	t := strings.ToLower(fieldType)
+	switch t {
+	case "bit", "varbit":
+		if v, err := strconv.ParseInt(string(fieldValue), 2, 64); err == nil {
+			return v
+		}
+	}
+	if len(t) > 1 && t[0] == '_' {
+		array, err := parsePgArray(string(fieldValue), func(s string) interface{} {
+			return db.convertValue([]byte(s), t[1:])
+		})
+		if err != nil {
+			return string(fieldValue)
+		}
+		return array
+	}
+	return db.dbBase.convertValue(fieldValue, fieldType)
+}
+
+// convertArrayData formats the slice values of the array type columns of <table> in <data> to the
+// text representation of pgsql array, eg: []string{"a", "b"} to {"a","b"}, as the driver does not
+// support slice parameters. It returns a new map if any value is formatted, or else <data>.
+func (db *dbPgsql) convertArrayData(table string, data Map) Map {
+	candidate := false
+	for _, v := range data {
+		if isPgArrayValue(v) {
+			candidate = true
+			break
+		}
+	}
+	// It retrieves the table fields only if necessary.
+	if !candidate || gstr.ContainsAny(gstr.Trim(table), " ,") {
+		return data
+	}
+	fields, err := db.TableFields(table)
+	if err != nil {
+		return data
+	}
+	var newData Map
+	for k, v := range data {
+		field, ok := fields[k]
+		if !ok || !isPgArrayValue(v) || !strings.HasPrefix(field.Type, "_") {
+			continue
+		}
+		if newData == nil {
+			newData = make(Map, len(data))
+			for key, value := range data {
+				newData[key] = value
+			}
+		}
+		newData[k] = formatPgArray(reflect.ValueOf(v))
+	}
+	if newData == nil {
+		return data
+	}
+	return newData
+}
+
 func (db *dbPgsql) handleSqlBeforeExec(sql string) string {
-	index := 0
-	sql, _ = gregex.ReplaceStringFunc("\\?", sql, func(s string) string {
-		index++
+	// It converts the '?' holders to "$1", "$2"..., so the statements with '?' holders are portable
+	// between mysql and pgsql. The '?' in the string literals and quoted identifiers is not converted.
+	sql = replacePlaceholders(sql, func(index int) string {
 		return fmt.Sprintf("$%d", index)
 	})
-	sql, _ = gregex.ReplaceString(` LIMIT (\d+),\s*(\d+)`, ` LIMIT $1 OFFSET $2`, sql)
+	// The "LIMIT offset, count" syntax of mysql.
+	sql, _ = gregex.ReplaceString(` LIMIT (\d+),\s*(\d+)`, ` LIMIT $2 OFFSET $1`, sql)
 	return sql
 }
 
+// getLockSql returns the locking clause of pgsql, which uses "FOR SHARE" for shared lock.
+func (db *dbPgsql) getLockSql(shared bool) (string, error) {
+	if shared {
+		return " FOR SHARE", nil
+	}
+	return " FOR UPDATE", nil
+}
+
+// getSaveSql returns the "ON CONFLICT ... DO UPDATE" clause for saving.
+// Also see dbBase.getOnConflictSaveSql.
+func (db *dbPgsql) getSaveSql(table string, columns []string, conflict []string) (string, error) {
+	return db.getOnConflictSaveSql(table, columns, conflict)
+}
+
+// getInsertOperation returns the insert operation of pgsql, which has no "INSERT IGNORE" syntax,
+// so it uses plain "INSERT" for ignoring along with the clause of getIgnoreSql.
+func (db *dbPgsql) getInsertOperation(option int) string {
+	if option == gINSERT_OPTION_IGNORE {
+		return "INSERT"
+	}
+	return db.dbBase.getInsertOperation(option)
+}
+
+// getIgnoreSql returns the "ON CONFLICT DO NOTHING" clause for ignoring, which ignores the
+// conflicts of any unique index, or of the unique index of <conflict> columns if given.
+func (db *dbPgsql) getIgnoreSql(conflict []string) string {
+	if len(conflict) == 0 {
+		return "ON CONFLICT DO NOTHING"
+	}
+	targets := make([]string, len(conflict))
+	for i, k := range conflict {
+		targets[i] = db.quoteWord(k)
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", strings.Join(targets, ","))
+}
+
+// getSaveStatus interprets the affected rows number of single record saving. The upsert of
+// pgsql affects one row no matter whether the record is inserted or updated.
+func (db *dbPgsql) getSaveStatus(affected int64) int {
+	if affected > 0 {
+		return SAVE_STATUS_SAVED
+	}
+	return SAVE_STATUS_UNCHANGED
+}
+
+// getSaveReturningSql returns the "RETURNING" clause telling whether each record is inserted,
+// as the "xmax" system column of the inserted row version is 0.
+func (db *dbPgsql) getSaveReturningSql() string {
+	return " RETURNING (xmax = 0) AS inserted"
+}
+
+// doBatchSaveAndGetIds batch saves <list> using "ON CONFLICT ... DO UPDATE" statement and returns
+// the primary key values using "RETURNING" clause, which works for both inserted and updated records.
+// As the returned records are not guaranteed in the order of the values, the primary key values are
+// mapped back to the records by the returned values of the conflict <keys>.
+func (db *dbPgsql) doBatchSaveAndGetIds(link dbLink, table string, list interface{}, primary string, keys []string) ([]int64, error) {
+	table = db.handleTableName(table)
+	defer db.clearTableCache(link, table)
+	listMap, ordered, err := db.getBatchData(table, list)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		keys = []string{primary}
+	}
+	if err = checkListKeys(listMap, keys); err != nil {
+		return nil, err
+	}
+	columns, err := getOrderedColumns(listMap[0], ordered)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		fields   = make([]string, len(columns))
+		conflict = make([]string, len(keys))
+		updates  = make([]string, 0, len(columns))
+		keySet   = make(map[string]struct{}, len(keys))
+	)
+	for i, k := range keys {
+		conflict[i] = db.quoteWord(k)
+		keySet[k] = struct{}{}
+	}
+	for i, k := range columns {
+		fields[i] = db.quoteWord(k)
+		if _, ok := keySet[k]; !ok {
+			updates = append(updates, fmt.Sprintf("%s=EXCLUDED.%s", fields[i], fields[i]))
+		}
+	}
+	// It needs updating at least one column for returning the conflicted record.
+	if len(updates) == 0 {
+		updates = append(updates, fmt.Sprintf("%s=EXCLUDED.%s", conflict[0], conflict[0]))
+	}
+	ids := make([]int64, len(listMap))
+	batchNum := db.getBatchNum(len(columns), nil)
+	for start := 0; start < len(listMap); start += batchNum {
+		end := start + batchNum
+		if end > len(listMap) {
+			end = len(listMap)
+		}
+		values := make([]string, 0, end-start)
+		params := make([]interface{}, 0, (end-start)*len(columns))
+		for _, item := range listMap[start:end] {
+			holder, itemParams := getRowHolder(item, columns)
+			values = append(values, holder)
+			params = append(params, itemParams...)
+		}
+		result, err := db.doGetAll(link, fmt.Sprintf(
+			"INSERT INTO %s(%s) VALUES%s ON CONFLICT (%s) DO UPDATE SET %s RETURNING %s AS %s,%s",
+			table, strings.Join(fields, ","), strings.Join(values, ","),
+			strings.Join(conflict, ","), strings.Join(updates, ","),
+			db.quoteWord(primary), db.quoteWord(gSAVE_ID_ALIAS), strings.Join(conflict, ","),
+		), params...)
+		if err != nil {
+			return nil, err
+		}
+		idMap := make(map[string]int64, len(result))
+		for _, record := range result {
+			idMap[getListKeyValue(record.Map(), keys)] = record[gSAVE_ID_ALIAS].Int64()
+		}
+		for i := start; i < end; i++ {
+			id, ok := idMap[getListKeyValue(listMap[i], keys)]
+			if !ok {
+				return nil, errors.New(fmt.Sprintf(`saved record not found by keys: %s`, strings.Join(keys, ",")))
+			}
+			ids[i] = id
+		}
+	}
+	return ids, nil
+}
+
+// doBatchInsertAndGetIds batch inserts <list> and returns the primary key values using
+// "RETURNING" clause, which are in the order of the inserted records.
+func (db *dbPgsql) doBatchInsertAndGetIds(link dbLink, table string, list interface{}, primary string, batch ...int) ([]int64, error) {
+	list, ids, err := getPrimaryIds(list, primary)
+	if err != nil {
+		return nil, err
+	}
+	if ids != nil {
+		_, err = db.doBatchInsert(link, table, list, gINSERT_OPTION_DEFAULT, batch...)
+		return ids, err
+	}
+	table = db.handleTableName(table)
+	defer db.clearTableCache(link, table)
+	listMap, ordered, err := db.getBatchData(table, list)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := getOrderedColumns(listMap[0], ordered)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]string, len(columns))
+	for i, k := range columns {
+		fields[i] = db.quoteWord(k)
+	}
+	batchNum := db.getBatchNum(len(columns), batch)
+	ids = make([]int64, 0, len(listMap))
+	for start := 0; start < len(listMap); start += batchNum {
+		end := start + batchNum
+		if end > len(listMap) {
+			end = len(listMap)
+		}
+		values := make([]string, 0, end-start)
+		params := make([]interface{}, 0, (end-start)*len(columns))
+		for _, item := range listMap[start:end] {
+			holder, itemParams := getRowHolder(item, columns)
+			values = append(values, holder)
+			params = append(params, itemParams...)
+		}
+		result, err := db.doGetAll(link, fmt.Sprintf(
+			"INSERT INTO %s(%s) VALUES%s RETURNING %s",
+			table, strings.Join(fields, ","), strings.Join(values, ","), db.quoteWord(primary),
+		), params...)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range result {
+			ids = append(ids, record[primary].Int64())
+		}
+	}
+	return ids, nil
+}
+
+// getReplicaLag returns the replication lag of the standby node of <link>, which is the time since
+// the last replayed transaction, or 0 if all the received WAL is replayed. It requires pgsql 10+.
+func (db *dbPgsql) getReplicaLag(link dbLink) (time.Duration, error) {
+	result, err := db.doGetAll(link, `SELECT CASE
+		WHEN NOT pg_is_in_recovery() THEN NULL
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+	END AS lag`)
+	if err != nil {
+		return 0, err
+	}
+	if len(result) == 0 || result[0]["lag"].IsNil() {
+		return 0, errors.New("the node is not a standby node")
+	}
+	return time.Duration(result[0]["lag"].Float64() * float64(time.Second)), nil
+}
+
+// classifyError returns the kind and the SQLSTATE code of driver error <err>, which is retrieved
+// by method SQLState of pgx driver or attribute "Code" of pq driver.
+func (db *dbPgsql) classifyError(err error) (kind error, code string) {
+	if e, ok := err.(interface{ SQLState() string }); ok {
+		code = e.SQLState()
+	} else {
+		code = getDriverErrorCode(err, "Code")
+	}
+	switch code {
+	case "23505":
+		kind = ErrUniqueViolation
+	case "23503":
+		kind = ErrForeignKeyViolation
+	case "23502":
+		kind = ErrNotNullViolation
+	}
+	return
+}
+
+// getTableExistsSql returns the statement retrieving the table from pg_catalog, which has the
+// place holders for the namespace and the table name. The namespace is current_schema() if empty.
+// Also see dbPgsql.TableExists.
+func (db *dbPgsql) getTableExistsSql() string {
+	return "SELECT tablename FROM pg_catalog.pg_tables WHERE schemaname=COALESCE(NULLIF(?, ''), current_schema()) AND tablename=?"
+}
+
+// getTableNamespace returns the table name with prefix, the schema of the connection and the
+// namespace of <table> for the metadata queries. As the schema of the connection specifies the
+// database for pgsql, the schema qualifying the table name or resolved by the table resolver,
+// eg: "tenant_001.user", is used as the namespace of pg_catalog instead of the connection schema,
+// which is the given <schema> or else the configured one.
+func (db *dbPgsql) getTableNamespace(table string, schema ...string) (name string, linkSchema string, namespace string) {
+	name, namespace = db.getTableName(table)
+	linkSchema = db.schema.Val()
+	if len(schema) > 0 && schema[0] != "" {
+		linkSchema = schema[0]
+	}
+	return
+}
+
+// getTableNamespaceCacheKey returns the cache key of the fields of <table> in <namespace>
+// of <linkSchema>. Also see getTableFieldsCacheKey.
+func (db *dbPgsql) getTableNamespaceCacheKey(table string, linkSchema string, namespace string) string {
+	if namespace != "" {
+		linkSchema += "." + namespace
+	}
+	return db.getTableFieldsCacheKey(table, linkSchema)
+}
+
+// TableExists checks and returns whether <table> exists in the namespace of the schema.
+// Also see dbBase.TableExists and getTableNamespace.
+func (db *dbPgsql) TableExists(table string, schema ...string) (bool, error) {
+	table = gstr.Trim(table)
+	if gstr.ContainsAny(table, " ,") {
+		panic("function TableExists supports only single table operations")
+	}
+	table, linkSchema, namespace := db.getTableNamespace(table, schema...)
+	link, err := db.getSlave(linkSchema)
+	if err != nil {
+		return false, err
+	}
+	result, err := db.doGetAll(link, db.getTableExistsSql(), namespace, strings.ToLower(table))
+	if err != nil {
+		return false, err
+	}
+	return len(result) > 0, nil
+}
+
+// ClearTableFieldsCache removes the cached fields of <table>. Also see dbBase.ClearTableFieldsCache.
+func (db *dbPgsql) ClearTableFieldsCache(table string, schema ...string) {
+	table, linkSchema, namespace := db.getTableNamespace(table, schema...)
+	db.cache.Remove(db.getTableNamespaceCacheKey(table, linkSchema, namespace))
+}
+
 // TODO
 func (db *dbPgsql) Tables(schema ...string) (tables []string, err error) {
 	return
 }
 
+// TableFields retrieves and returns the fields of given table in the namespace of the schema
+// from pg_catalog. Also see dbBase.TableFields and getTableNamespace.
 func (db *dbPgsql) TableFields(table string, schema ...string) (fields map[string]*TableField, err error) {
 	table = gstr.Trim(table)
 	if gstr.Contains(table, " ") {
 		panic("function TableFields supports only single table operations")
 	}
-	table, _ = gregex.ReplaceString("\"", "", table)
-	checkSchema := db.schema.Val()
-	if len(schema) > 0 && schema[0] != "" {
-		checkSchema = schema[0]
-	}
-	v := db.cache.GetOrSetFunc(
-		fmt.Sprintf(`pgsql_table_fields_%s_%s`, table, checkSchema), func() interface{} {
+	table, linkSchema, namespace := db.getTableNamespace(table, schema...)
+	v := db.getOrSetTableFields(
+		db.getTableNamespaceCacheKey(table, linkSchema, namespace), func() interface{} {
 			var result Result
 			var link *sql.DB
-			link, err = db.getSlave(checkSchema)
+			link, err = db.getSlave(linkSchema)
 			if err != nil {
 				return nil
 			}
-			result, err = db.doGetAll(link, fmt.Sprintf(`
-			SELECT a.attname AS field, t.typname AS type FROM pg_class c, pg_attribute a 
-	        LEFT OUTER JOIN pg_description b ON a.attrelid=b.objoid AND a.attnum = b.objsubid,pg_type t
-	        WHERE c.relname = '%s' and a.attnum > 0 and a.attrelid = c.oid and a.atttypid = t.oid 
-			ORDER BY a.attnum`, strings.ToLower(table)))
+			result, err = db.doGetAll(link, `
+			SELECT a.attname AS field, t.typname AS type, a.attnotnull AS notnull,
+				pg_get_expr(d.adbin, d.adrelid) AS dflt, b.description AS comment,
+				CASE WHEN EXISTS(
+					SELECT 1 FROM pg_index i WHERE i.indrelid = c.oid AND i.indisprimary AND a.attnum = ANY(i.indkey)
+				) THEN 'PRI' ELSE '' END AS column_key
+			FROM pg_class c
+			JOIN pg_attribute a ON a.attrelid = c.oid
+			JOIN pg_type t ON a.atttypid = t.oid
+			LEFT JOIN pg_attrdef d ON d.adrelid = c.oid AND d.adnum = a.attnum
+			LEFT JOIN pg_description b ON b.objoid = c.oid AND b.objsubid = a.attnum
+			WHERE c.relname = ? AND c.relnamespace = (SELECT oid FROM pg_namespace WHERE nspname = COALESCE(NULLIF(?, ''), current_schema()))
+				AND a.attnum > 0 AND NOT a.attisdropped
+			ORDER BY a.attnum`, strings.ToLower(table), namespace)
 			if err != nil {
 				return nil
 			}
@@ -92,15 +448,144 @@ func (db *dbPgsql) TableFields(table string, schema ...string) (fields map[strin
 			fields = make(map[string]*TableField)
 			for i, m := range result {
 				fields[m["field"].String()] = &TableField{
-					Index: i,
-					Name:  m["field"].String(),
-					Type:  m["type"].String(),
+					Index:   i,
+					Name:    m["field"].String(),
+					Type:    m["type"].String(),
+					Null:    !m["notnull"].Bool(),
+					Key:     m["column_key"].String(),
+					Default: m["dflt"].Val(),
+					Comment: m["comment"].String(),
 				}
 			}
 			return fields
-		}, 0)
+		})
 	if err == nil {
 		fields = v.(map[string]*TableField)
 	}
 	return
 }
+
+// isPgArrayValue checks and returns whether <value> should be formatted as pgsql array,
+// which is type of slice/array but not []byte.
+func isPgArrayValue(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	if _, ok := value.([]byte); ok {
+		return false
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Slice, reflect.Array:
+		return true
+	}
+	return false
+}
+
+// formatPgArray formats the slice/array <rv> to the text representation of pgsql array,
+// eg: {"a","b\"c",NULL}, of which the elements are all quoted except NULL.
+func formatPgArray(rv reflect.Value) string {
+	buffer := bytes.NewBufferString("{")
+	for i := 0; i < rv.Len(); i++ {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		item := rv.Index(i).Interface()
+		switch {
+		case isNilValue(item):
+			buffer.WriteString("NULL")
+		case isPgArrayValue(item):
+			buffer.WriteString(formatPgArray(reflect.ValueOf(item)))
+		default:
+			s := ""
+			if t, ok := item.(time.Time); ok {
+				s = t.Format(time.RFC3339Nano)
+			} else {
+				s = gconv.String(item)
+			}
+			buffer.WriteByte('"')
+			buffer.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s))
+			buffer.WriteByte('"')
+		}
+	}
+	buffer.WriteByte('}')
+	return buffer.String()
+}
+
+// parsePgArray parses the text representation of pgsql array <s>, eg: {1,2,NULL}, {{"a","b"},{"c","d"}},
+// and returns its elements as []interface{}, of which the non-NULL elements are converted by <convert>
+// and the sub arrays of multi-dimensional array are also type of []interface{}.
+func parsePgArray(s string, convert func(string) interface{}) ([]interface{}, error) {
+	// The array with non-default lower bounds has the dimensions decoration, eg: [0:1]={1,2}.
+	if strings.HasPrefix(s, "[") {
+		if index := strings.Index(s, "="); index > 0 {
+			s = s[index+1:]
+		}
+	}
+	array, end, err := parsePgArrayFrom(s, 0, convert)
+	if err != nil {
+		return nil, err
+	}
+	if end != len(s) {
+		return nil, errors.New(fmt.Sprintf(`invalid array: %s`, s))
+	}
+	return array, nil
+}
+
+// parsePgArrayFrom parses the pgsql array starting at index <start> of <s>,
+// and returns its elements and the index next to the array.
+func parsePgArrayFrom(s string, start int, convert func(string) interface{}) ([]interface{}, int, error) {
+	if start >= len(s) || s[start] != '{' {
+		return nil, start, errors.New(fmt.Sprintf(`invalid array: %s`, s))
+	}
+	array := make([]interface{}, 0)
+	i := start + 1
+	if i < len(s) && s[i] == '}' {
+		return array, i + 1, nil
+	}
+	for i < len(s) {
+		switch s[i] {
+		case '{':
+			sub, end, err := parsePgArrayFrom(s, i, convert)
+			if err != nil {
+				return nil, i, err
+			}
+			array = append(array, sub)
+			i = end
+
+		case '"':
+			buffer := bytes.NewBuffer(nil)
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				buffer.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, i, errors.New(fmt.Sprintf(`invalid array: %s`, s))
+			}
+			array = append(array, convert(buffer.String()))
+			i++
+
+		default:
+			end := i
+			for end < len(s) && s[end] != ',' && s[end] != '}' {
+				end++
+			}
+			if v := strings.TrimSpace(s[i:end]); strings.EqualFold(v, "NULL") {
+				array = append(array, nil)
+			} else {
+				array = append(array, convert(v))
+			}
+			i = end
+		}
+		if i < len(s) && s[i] == ',' {
+			i++
+			continue
+		}
+		if i < len(s) && s[i] == '}' {
+			return array, i + 1, nil
+		}
+		break
+	}
+	return nil, i, errors.New(fmt.Sprintf(`invalid array: %s`, s))
+}
@@ -0,0 +1,157 @@
+// Copyright 2020 gf Author(https://github.com/gogf/gf). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/gogf/gf.
+
+package gdb
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+// replicaLags holds the last-known replication lags of the slave nodes, which are sampled
+// periodically by the monitor. See DB.SetReplicaLagMonitor.
+type replicaLags struct {
+	mu   sync.RWMutex
+	lags map[string]replicaLag // Key is the string of the slave node configuration.
+	stop chan struct{}         // Closed for stopping the monitor, nil if the monitor is not running.
+}
+
+// replicaLag is the replication lag of a slave node sampled at the time.
+type replicaLag struct {
+	lag     time.Duration // Replication lag.
+	sampled time.Time     // Sampling time.
+}
+
+// newReplicaLags creates and returns a replicaLags without monitor running.
+func newReplicaLags() *replicaLags {
+	return &replicaLags{
+		lags: make(map[string]replicaLag),
+	}
+}
+
+// set records the replication <lag> of <node> sampled now.
+func (r *replicaLags) set(node string, lag time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lags[node] = replicaLag{lag: lag, sampled: time.Now()}
+}
+
+// remove removes the replication lag of <node>, which makes it unknown.
+func (r *replicaLags) remove(node string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.lags, node)
+}
+
+// within checks whether the data of <node> is not staler than <maxStaleness>. As the node may stop
+// replicating after sampling, the staleness is the sampled lag plus the time elapsed since sampling.
+// It returns false if the lag of <node> is unknown.
+func (r *replicaLags) within(node string, maxStaleness time.Duration) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	v, ok := r.lags[node]
+	if !ok {
+		return false
+	}
+	return v.lag+time.Since(v.sampled) <= maxStaleness
+}
+
+// monitor stops the running monitor, and then starts calling <sample> immediately and
+// every <interval> in a goroutine if <interval> > 0.
+func (r *replicaLags) monitor(interval time.Duration, sample func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+	if interval <= 0 {
+		return
+	}
+	stop := make(chan struct{})
+	r.stop = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			sample()
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// SetReplicaLagMonitor starts sampling the replication lags of all the slave nodes every <interval>
+// in a goroutine, which are used for routing the reads with the staleness hint, see Model.MaxStaleness.
+// The lag is sampled using "SHOW SLAVE STATUS" for mysql and pg_last_xact_replay_timestamp() for pgsql.
+// The <interval> <= 0 stops the monitor, and the monitor is also stopped by Close.
+//
+// The lag of the slave node failing sampling is unknown, eg: the replication is stopped, so that the
+// reads with the staleness hint are routed to the master node.
+func (bs *dbBase) SetReplicaLagMonitor(interval time.Duration) {
+	bs.replicaLags.monitor(interval, bs.sampleReplicaLags)
+}
+
+// sampleReplicaLags samples and records the replication lags of all the slave nodes.
+func (bs *dbBase) sampleReplicaLags() {
+	list, _ := getConfigGroup(bs.group)
+	for i := range list {
+		node := list[i]
+		if node.Role != "slave" {
+			continue
+		}
+		sqlDb, err := bs.getSqlDbByNode(&node, bs.schema.Val())
+		if err != nil {
+			bs.replicaLags.remove(node.String())
+			continue
+		}
+		lag, err := bs.db.getReplicaLag(sqlDb)
+		if err != nil {
+			bs.replicaLags.remove(node.String())
+			continue
+		}
+		bs.replicaLags.set(node.String(), lag)
+	}
+}
+
+// getSlaveWithStaleness acts like function getSlave, but it returns the master node connection if
+// the replication lag of the chosen slave node exceeds <maxStaleness> or is unknown.
+// Also see SetReplicaLagMonitor.
+func (bs *dbBase) getSlaveWithStaleness(maxStaleness time.Duration, schema ...string) (*sql.DB, error) {
+	node, err := getConfigNodeByGroup(bs.group, false)
+	if err != nil {
+		return nil, err
+	}
+	if node.Role != "slave" || !bs.replicaLags.within(node.String(), maxStaleness) {
+		return bs.getSqlDb(true, schema...)
+	}
+	if node.Debug {
+		bs.db.SetDebug(node.Debug)
+	}
+	return bs.getSqlDbByNode(node, schema...)
+}
+
+// getReplicaLag returns the replication lag of the slave node of <link> using "SHOW SLAVE STATUS"
+// of mysql, which returns error if the replication is not configured or not running.
+func (bs *dbBase) getReplicaLag(link dbLink) (time.Duration, error) {
+	result, err := bs.db.doGetAll(link, "SHOW SLAVE STATUS")
+	if err != nil {
+		return 0, err
+	}
+	if len(result) == 0 {
+		return 0, errors.New("replication is not configured on the node")
+	}
+	seconds, ok := result[0]["Seconds_Behind_Master"]
+	if !ok || seconds.IsNil() {
+		return 0, errors.New("replication is not running on the node")
+	}
+	return time.Duration(seconds.Int64()) * time.Second, nil
+}
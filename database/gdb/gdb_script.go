@@ -0,0 +1,183 @@
+// Copyright 2020 gf Author(https://github.com/gogf/gf). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/gogf/gf.
+
+package gdb
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// scriptDelimiterReg is the regular expression object for the DELIMITER command of mysql client,
+	// eg: "DELIMITER //".
+	scriptDelimiterReg = regexp.MustCompile(`^(?i)DELIMITER[ \t]+(\S+)[^\n]*`)
+
+	// scriptDollarTagReg is the regular expression object for the tag of dollar-quoted string of pgsql,
+	// eg: "$$", "$body$".
+	scriptDollarTagReg = regexp.MustCompile(`^\$([a-zA-Z_][a-zA-Z0-9_]*)?\$`)
+)
+
+// connLink is the database link using one connection of the connection pool.
+type connLink struct {
+	conn *sql.Conn
+}
+
+// Query executes a query on the connection. See sql.Conn.QueryContext.
+func (l *connLink) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return l.conn.QueryContext(context.Background(), query, args...)
+}
+
+// Exec executes a query without returning any rows on the connection. See sql.Conn.ExecContext.
+func (l *connLink) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return l.conn.ExecContext(context.Background(), query, args...)
+}
+
+// Prepare creates a prepared statement on the connection. See sql.Conn.PrepareContext.
+func (l *connLink) Prepare(query string) (*sql.Stmt, error) {
+	return l.conn.PrepareContext(context.Background(), query)
+}
+
+// ExecMulti splits the SQL <script> into statements and executes them in order using one
+// connection of the master node, which is commonly used for migrations.
+//
+// The statements are separated by ";", and the semicolons in quoted strings or identifiers,
+// dollar-quoted strings of pgsql and comments ("--" and "/* */") are not separators.
+// The separator can be changed by the "DELIMITER" command of mysql client, eg: "DELIMITER //",
+// which is commonly used for creating triggers and procedures.
+//
+// It stops at the first failed statement and returns the error with the index of the statement,
+// which starts from 1.
+func (bs *dbBase) ExecMulti(script string) error {
+	master, err := bs.db.Master()
+	if err != nil {
+		return err
+	}
+	conn, err := master.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return doExecMulti(bs.db, &connLink{conn}, script)
+}
+
+// doExecMulti executes the statements of SQL <script> in order using <link>.
+// Also see ExecMulti.
+func doExecMulti(db DB, link dbLink, script string) error {
+	for i, statement := range splitSqlScript(script) {
+		if _, err := db.doExec(link, statement); err != nil {
+			return errors.New(fmt.Sprintf("statement %d failed: %s", i+1, err.Error()))
+		}
+	}
+	return nil
+}
+
+// splitSqlScript splits the SQL <script> into statements, which are trimmed and without
+// the delimiter. The statements containing only comments are ignored.
+func splitSqlScript(script string) []string {
+	var (
+		statements = make([]string, 0)
+		delimiter  = ";"
+		buffer     = bytes.NewBuffer(nil)
+		hasCode    = false // Whether the buffer contains anything other than spaces and comments.
+	)
+	flush := func() {
+		if hasCode {
+			statements = append(statements, strings.TrimSpace(buffer.String()))
+		}
+		buffer.Reset()
+		hasCode = false
+	}
+	for i := 0; i < len(script); {
+		end := i + 1
+		switch c := script[i]; {
+		case !hasCode && scriptDelimiterReg.MatchString(script[i:]):
+			// The DELIMITER command is handled by client, which is not sent to the server.
+			match := scriptDelimiterReg.FindStringSubmatch(script[i:])
+			delimiter = match[1]
+			buffer.Reset()
+			i += len(match[0])
+			continue
+
+		case strings.HasPrefix(script[i:], delimiter):
+			flush()
+			i += len(delimiter)
+			continue
+
+		case strings.HasPrefix(script[i:], "--"):
+			end = indexFrom(script, "\n", i)
+
+		case strings.HasPrefix(script[i:], "/*"):
+			// The conditional comments of mysql are executed, eg: "/*!40101 SET NAMES utf8 */".
+			if strings.HasPrefix(script[i:], "/*!") {
+				hasCode = true
+			}
+			end = indexFrom(script, "*/", i+2) + 2
+
+		case c == '\'' || c == '"' || c == '`':
+			end = indexQuoteEnd(script, i)
+			hasCode = true
+
+		case c == '$' && (i == 0 || !isIdentifierChar(script[i-1])) && scriptDollarTagReg.MatchString(script[i:]):
+			tag := scriptDollarTagReg.FindString(script[i:])
+			end = indexFrom(script, tag, i+len(tag)) + len(tag)
+			hasCode = true
+
+		case c != ' ' && c != '\t' && c != '\r' && c != '\n':
+			hasCode = true
+		}
+		if end > len(script) {
+			end = len(script)
+		}
+		buffer.WriteString(script[i:end])
+		i = end
+	}
+	flush()
+	return statements
+}
+
+// indexFrom returns the index of the first <substr> in <s> from index <start>,
+// or the length of <s> if <substr> is not found.
+func indexFrom(s string, substr string, start int) int {
+	if start > len(s) {
+		return len(s)
+	}
+	if index := strings.Index(s[start:], substr); index >= 0 {
+		return start + index
+	}
+	return len(s)
+}
+
+// indexQuoteEnd returns the index next to the closing quote of the quoted string starting
+// at index <start> in <s>. The quote char is escaped by backslash or by doubling it.
+func indexQuoteEnd(s string, start int) int {
+	quote := s[start]
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+			} else {
+				return i + 1
+			}
+		}
+	}
+	return len(s)
+}
+
+// isIdentifierChar checks and returns whether <c> can be part of an unquoted identifier.
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '$' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
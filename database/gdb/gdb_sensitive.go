@@ -0,0 +1,92 @@
+// Copyright 2020 gf Author(https://github.com/gogf/gf). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/gogf/gf.
+
+package gdb
+
+import (
+	"strings"
+)
+
+// gSENSITIVE_MASK is the replacement of the sensitive values in logs and errors.
+const gSENSITIVE_MASK = "***"
+
+// sensitiveArg is the argument marked as sensitive, see Sensitive.
+type sensitiveArg struct {
+	value interface{}
+}
+
+// Sensitive marks the argument <value> as sensitive, of which the value is passed to the driver
+// but replaced with "***" in the logged statements and errors, eg:
+// db.GetOne("SELECT * FROM user WHERE passport=? AND password=?", "john", gdb.Sensitive(password)).
+//
+// Note that the slice value for "IN(?)" is not expanded if it is marked as sensitive.
+func Sensitive(value interface{}) interface{} {
+	return sensitiveArg{value}
+}
+
+// SetSensitiveColumns sets the sensitive column names, which are case-insensitive, eg: "password".
+// The values of these columns in the data of Insert/Update operations are replaced with "***"
+// in the logged statements and errors. See Sensitive for the arguments of raw statements.
+func (bs *dbBase) SetSensitiveColumns(columns ...string) {
+	set := make(map[string]struct{}, len(columns))
+	for _, column := range columns {
+		set[strings.ToLower(column)] = struct{}{}
+	}
+	bs.sensitiveColumns.Set(set)
+}
+
+// markSensitiveData marks the values of the sensitive columns in <data> as sensitive.
+// It returns a new map if any value is marked, or else <data>.
+func (bs *dbBase) markSensitiveData(data Map) Map {
+	set, _ := bs.sensitiveColumns.Val().(map[string]struct{})
+	if len(set) == 0 {
+		return data
+	}
+	var newData Map
+	for k, v := range data {
+		if _, ok := set[strings.ToLower(k)]; !ok || isNilValue(v) {
+			continue
+		}
+		if _, ok := v.(Raw); ok {
+			continue
+		}
+		if newData == nil {
+			newData = make(Map, len(data))
+			for key, value := range data {
+				newData[key] = value
+			}
+		}
+		newData[k] = Sensitive(v)
+	}
+	if newData == nil {
+		return data
+	}
+	return newData
+}
+
+// unwrapSensitiveArgs returns the arguments with the sensitive values unwrapped for the driver,
+// and the arguments with the sensitive values masked for logging, which is <args> itself if
+// there's no sensitive value.
+func unwrapSensitiveArgs(args []interface{}) (driverArgs []interface{}, logArgs []interface{}) {
+	for i, arg := range args {
+		v, ok := arg.(sensitiveArg)
+		if !ok {
+			continue
+		}
+		if driverArgs == nil {
+			driverArgs = make([]interface{}, len(args))
+			logArgs = make([]interface{}, len(args))
+			copy(driverArgs, args)
+			copy(logArgs, args)
+		}
+		driverArgs[i] = v.value
+		logArgs[i] = gSENSITIVE_MASK
+	}
+	if driverArgs == nil {
+		return args, args
+	}
+	return driverArgs, logArgs
+}
@@ -0,0 +1,164 @@
+// Copyright 2020 gf Author(https://github.com/gogf/gf). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/gogf/gf.
+
+package gdb
+
+import (
+	"sync"
+
+	"github.com/gogf/gf/container/gtype"
+)
+
+// SqlCollector collects the statements executed by the DB object in memory,
+// which is commonly used for asserting the executed statements in tests. See DB.CatchSql.
+type SqlCollector struct {
+	mu   sync.RWMutex
+	sqls []*Sql
+	db   *dbBase
+}
+
+// CatchSql attaches and returns a new collector, which collects all the statements executed
+// by Query/Exec and the operations based on them, no matter whether the debug mode is enabled.
+// The collected statements are the same as the ones logged in debug mode, containing the arguments,
+// the execution error and time. Call Close of the returned collector to stop collecting.
+func (bs *dbBase) CatchSql() *SqlCollector {
+	c := &SqlCollector{
+		sqls: make([]*Sql, 0),
+		db:   bs,
+	}
+	bs.sqlCollectors.Add(c)
+	return c
+}
+
+// Retrieve returns the collected statements in execution order.
+func (c *SqlCollector) Retrieve() []*Sql {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	sqls := make([]*Sql, len(c.sqls))
+	copy(sqls, c.sqls)
+	return sqls
+}
+
+// Clear removes all the collected statements.
+func (c *SqlCollector) Clear() {
+	c.mu.Lock()
+	c.sqls = c.sqls[:0]
+	c.mu.Unlock()
+}
+
+// Close detaches the collector from the DB object, which stops collecting.
+// The collected statements can still be retrieved after closing.
+func (c *SqlCollector) Close() {
+	c.db.sqlCollectors.Remove(c)
+}
+
+// add adds the statement <s> to the collector.
+func (c *SqlCollector) add(s *Sql) {
+	c.mu.Lock()
+	c.sqls = append(c.sqls, s)
+	c.mu.Unlock()
+}
+
+// sqlHistory is the ring buffer of the recent executed statements.
+type sqlHistory struct {
+	mu   sync.RWMutex
+	size *gtype.Int // Capacity of the buffer, which is 0 if it's disabled.
+	sqls []*Sql     // Buffer of the statements.
+	next int        // Index in <sqls> for the next statement.
+	full bool       // Whether the buffer is full, which means <next> is the oldest one.
+}
+
+// newSqlHistory creates and returns a disabled sqlHistory.
+func newSqlHistory() *sqlHistory {
+	return &sqlHistory{
+		size: gtype.NewInt(),
+	}
+}
+
+// SetSqlHistory enables keeping the recent <size> executed statements in memory, which can be
+// retrieved by LastSql and RecentSqls for debugging, no matter whether the debug mode is enabled.
+// It's disabled in default, and <size> 0 disables it and clears the kept statements.
+func (bs *dbBase) SetSqlHistory(size int) {
+	h := bs.sqlHistory
+	if size < 0 {
+		size = 0
+	}
+	h.mu.Lock()
+	h.sqls = make([]*Sql, size)
+	h.next = 0
+	h.full = false
+	h.size.Set(size)
+	h.mu.Unlock()
+}
+
+// LastSql returns the last executed statement, or nil if there's none or the history is disabled.
+// Also see SetSqlHistory.
+func (bs *dbBase) LastSql() *Sql {
+	sqls := bs.RecentSqls(1)
+	if len(sqls) == 0 {
+		return nil
+	}
+	return sqls[0]
+}
+
+// RecentSqls returns the recent <n> executed statements in execution order, which contains
+// fewer statements if there're not enough kept ones. Also see SetSqlHistory.
+func (bs *dbBase) RecentSqls(n int) []*Sql {
+	h := bs.sqlHistory
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	count := h.next
+	if h.full {
+		count = len(h.sqls)
+	}
+	if n > count {
+		n = count
+	}
+	if n <= 0 {
+		return nil
+	}
+	sqls := make([]*Sql, n)
+	for i := 0; i < n; i++ {
+		sqls[i] = h.sqls[(h.next-n+i+len(h.sqls))%len(h.sqls)]
+	}
+	return sqls
+}
+
+// add adds the statement <s> to the buffer, which overwrites the oldest one if it's full.
+func (h *sqlHistory) add(s *Sql) {
+	h.mu.Lock()
+	if len(h.sqls) > 0 {
+		h.sqls[h.next] = s
+		h.next = (h.next + 1) % len(h.sqls)
+		if h.next == 0 {
+			h.full = true
+		}
+	}
+	h.mu.Unlock()
+}
+
+// isRecordingSql checks and returns whether the executed statements should be recorded,
+// which is true if the debug mode or the history is enabled, or there's any attached collector.
+func (bs *dbBase) isRecordingSql() bool {
+	return bs.db.getDebug() || bs.sqlCollectors.Size() > 0 || bs.sqlHistory.size.Val() > 0
+}
+
+// recordSql adds the executed statement <s> to the history and all the attached collectors,
+// and outputs it to logger if the debug mode is enabled.
+func (bs *dbBase) recordSql(s *Sql) {
+	if bs.sqlHistory.size.Val() > 0 {
+		bs.sqlHistory.add(s)
+	}
+	if bs.sqlCollectors.Size() > 0 {
+		bs.sqlCollectors.Iterator(func(v interface{}) bool {
+			v.(*SqlCollector).add(s)
+			return true
+		})
+	}
+	if bs.db.getDebug() {
+		bs.printSql(s)
+	}
+}
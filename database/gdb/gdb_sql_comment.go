@@ -0,0 +1,58 @@
+// Copyright 2020 gf Author(https://github.com/gogf/gf). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/gogf/gf.
+
+package gdb
+
+import (
+	"strings"
+
+	"github.com/gogf/gf/container/gtype"
+)
+
+// SetSqlComment sets the <comment> prepended to all the statements executed by Query/Exec and
+// the operations based on them, which is commonly used for tracing the slow statements back to
+// the code path in the process list and slow log of the database, eg: "service=orders".
+// The statement is like "/* service=orders */ SELECT ...". The empty <comment> removes it.
+// Use WithSqlComment for attributing the concurrent requests differently.
+//
+// The comment is always injected as "/* */" comment, of which the comment delimiters in <comment>
+// are removed, so it cannot change the statement.
+func (bs *dbBase) SetSqlComment(comment string) {
+	bs.sqlComment.Set(formatSqlComment(comment))
+}
+
+// WithSqlComment returns a new DB object prepending <comment> to its executed statements, eg:
+// db.WithSqlComment("service=orders,route=/checkout").Table("order").All().
+//
+// The returned DB object shares the connections, caches and configurations with the current one,
+// so it's cheap to create it for each request. Also see SetSqlComment.
+func (bs *dbBase) WithSqlComment(comment string) DB {
+	return bs.cloneDB(func(base *dbBase) {
+		base.sqlComment = gtype.NewString(formatSqlComment(comment))
+	})
+}
+
+// formatSqlComment returns the SQL comment of <comment>, like "/* service=orders */", or an empty
+// string if <comment> is empty. The comment delimiters "/*" and "*/" in <comment> are removed, and
+// the space after "/*" prevents it from being the conditional comment or optimizer hint of mysql.
+func formatSqlComment(comment string) string {
+	for strings.Contains(comment, "/*") || strings.Contains(comment, "*/") {
+		comment = strings.NewReplacer("/*", "", "*/", "").Replace(comment)
+	}
+	comment = strings.TrimSpace(comment)
+	if comment == "" {
+		return ""
+	}
+	return "/* " + comment + " */"
+}
+
+// addSqlComment prepends the comment set by SetSqlComment/WithSqlComment to <query>.
+func (bs *dbBase) addSqlComment(query string) string {
+	if comment := bs.sqlComment.Val(); comment != "" {
+		return comment + " " + query
+	}
+	return query
+}
@@ -6,12 +6,16 @@
 //
 // Note:
 // 1. It needs manually import: _ "github.com/mattn/go-sqlite3"
-// 2. It does not support Save/Replace features.
+// 2. The Save feature requires SQLite 3.24.0+ for the "ON CONFLICT ... DO UPDATE" clause.
 
 package gdb
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/gogf/gf/internal/intlog"
 	"github.com/gogf/gf/text/gstr"
 )
@@ -27,11 +31,11 @@ func (db *dbSqlite) Open(config *ConfigNode) (*sql.DB, error) {
 	} else {
 		source = config.Name
 	}
-	intlog.Printf("Open: %s", source)
+	intlog.Printf("Open: %s", maskDSN(source))
 	if db, err := sql.Open("sqlite3", source); err == nil {
 		return db, nil
 	} else {
-		return nil, err
+		return nil, formatOpenError(err, source, config.Pass)
 	}
 }
 
@@ -39,22 +43,156 @@ func (db *dbSqlite) getChars() (charLeft string, charRight string) {
 	return "`", "`"
 }
 
+// getTruncateSql returns the DELETE statement for truncating as sqlite does not support TRUNCATE.
+func (db *dbSqlite) getTruncateSql(table string) string {
+	return "DELETE FROM " + table
+}
+
+// getTableExistsSql returns the statement retrieving the table from sqlite_master.
+func (db *dbSqlite) getTableExistsSql() string {
+	return "SELECT name FROM sqlite_master WHERE type='table' AND name=?"
+}
+
+// getVersionSql returns the statement retrieving the version of the sqlite library.
+func (db *dbSqlite) getVersionSql() string {
+	return "SELECT sqlite_version()"
+}
+
+// getMaxPlaceholders returns the max place holder count in one statement, which is the default
+// SQLITE_MAX_VARIABLE_NUMBER 999 of sqlite before 3.32.0.
+func (db *dbSqlite) getMaxPlaceholders() int {
+	return 999
+}
+
 // TODO
 func (db *dbSqlite) Tables(schema ...string) (tables []string, err error) {
 	return
 }
 
-// TODO
+// TableFields retrieves and returns the fields of given table using "PRAGMA table_info".
+// Also see dbBase.TableFields.
 func (db *dbSqlite) TableFields(table string, schema ...string) (fields map[string]*TableField, err error) {
 	table = gstr.Trim(table)
 	if gstr.Contains(table, " ") {
 		panic("function TableFields supports only single table operations")
 	}
+	table, checkSchema := db.getTableSchema(table, schema...)
+	v := db.getOrSetTableFields(
+		db.getTableFieldsCacheKey(table, checkSchema), func() interface{} {
+			var result Result
+			var link *sql.DB
+			link, err = db.getSlave(checkSchema)
+			if err != nil {
+				return nil
+			}
+			result, err = db.doGetAll(link, fmt.Sprintf(`PRAGMA table_info(%s)`, db.quoteWord(table)))
+			if err != nil {
+				return nil
+			}
+			fields = make(map[string]*TableField)
+			for i, m := range result {
+				key := ""
+				if m["pk"].Int() > 0 {
+					key = "PRI"
+				}
+				fields[m["name"].String()] = &TableField{
+					Index:   i,
+					Name:    m["name"].String(),
+					Type:    m["type"].String(),
+					Null:    !m["notnull"].Bool(),
+					Key:     key,
+					Default: m["dflt_value"].Val(),
+				}
+			}
+			return fields
+		})
+	if err == nil {
+		fields = v.(map[string]*TableField)
+	}
+	return
+}
+
+// getInsertOperation returns the insert operation of sqlite, which uses "INSERT OR REPLACE"
+// and "INSERT OR IGNORE" for replacing and ignoring.
+func (db *dbSqlite) getInsertOperation(option int) string {
+	switch option {
+	case gINSERT_OPTION_REPLACE:
+		return "INSERT OR REPLACE"
+	case gINSERT_OPTION_IGNORE:
+		return "INSERT OR IGNORE"
+	default:
+		return "INSERT"
+	}
+}
+
+// getInsertIds returns the <count> auto-increment values generated by the inserting statement
+// of <result>, in which the LastInsertId of sqlite is the last generated value.
+func (db *dbSqlite) getInsertIds(result sql.Result, count int) ([]int64, error) {
+	last, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, count)
+	for i := range ids {
+		ids[i] = last - int64(count-1-i)
+	}
+	return ids, nil
+}
+
+// getLockSql returns error as sqlite locks the whole database file instead of records.
+func (db *dbSqlite) getLockSql(shared bool) (string, error) {
+	return "", errors.New("locking clause is not supported by sqlite, which locks the whole database file")
+}
+
+// getSaveSql returns the "ON CONFLICT ... DO UPDATE" clause for saving.
+// Also see dbBase.getOnConflictSaveSql.
+func (db *dbSqlite) getSaveSql(table string, columns []string, conflict []string) (string, error) {
+	return db.getOnConflictSaveSql(table, columns, conflict)
+}
+
+// getSaveStatus interprets the affected rows number of single record saving. The upsert of
+// sqlite affects one row no matter whether the record is inserted or updated.
+func (db *dbSqlite) getSaveStatus(affected int64) int {
+	if affected > 0 {
+		return SAVE_STATUS_SAVED
+	}
+	return SAVE_STATUS_UNCHANGED
+}
+
+// getSaveCounts interprets the affected rows number of saving records, which is 1 for each
+// inserted or updated record for the "ON CONFLICT ... DO UPDATE" clause of sqlite.
+func (db *dbSqlite) getSaveCounts(affected int64, count int) (inserted int64, updated int64, saved int64) {
+	return 0, 0, affected
+}
+
+// getExplainSql returns the "EXPLAIN QUERY PLAN" statement of <query>, as the "EXPLAIN" statement
+// of SQLite returns the virtual machine instructions.
+func (db *dbSqlite) getExplainSql(query string) (string, error) {
+	if err := checkExplainable(query); err != nil {
+		return "", err
+	}
+	return "EXPLAIN QUERY PLAN " + query, nil
+}
+
+// getReplicaLag returns error as sqlite has no replication.
+func (db *dbSqlite) getReplicaLag(link dbLink) (time.Duration, error) {
+	return 0, errors.New("replica lag is not supported by sqlite")
+}
+
+// classifyError returns the kind and the extended result code of driver error <err>.
+func (db *dbSqlite) classifyError(err error) (kind error, code string) {
+	code = getDriverErrorCode(err, "ExtendedCode")
+	switch code {
+	case "1555", "2067":
+		kind = ErrUniqueViolation
+	case "787":
+		kind = ErrForeignKeyViolation
+	case "1299":
+		kind = ErrNotNullViolation
+	}
 	return
 }
 
-// @todo 需要增加对Save方法的支持，可使用正则来实现替换，
-// @todo 将ON DUPLICATE KEY UPDATE触发器修改为两条SQL语句(INSERT OR IGNORE & UPDATE)
 func (db *dbSqlite) handleSqlBeforeExec(sql string) string {
 	return sql
 }
@@ -7,9 +7,13 @@
 package gdb
 
 import (
+	"bytes"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/gogf/gf/text/gstr"
 
@@ -23,28 +27,52 @@ import (
 
 // convertValue automatically checks and converts field value from database type
 // to golang variable type.
+//
+// The boolean values are converted to bool consistently across the drivers, eg: "t"/"f" of pgsql,
+// "1"/"0" of sqlite, "true"/"false". Note that the BOOLEAN of mysql is TINYINT(1) whose values are
+// returned as integer 1/0, which are also converted to bool by Value.Bool and the bool attributes.
+//
+// The datetime values keep the fractional seconds and the time zone, which are converted to
+// time.Time and *gtime.Time attributes with full precision.
 func (bs *dbBase) convertValue(fieldValue []byte, fieldType string) interface{} {
 	t, _ := gregex.ReplaceString(`\(.+\)`, "", fieldType)
 	t = strings.ToLower(t)
+	// The unsigned type can be like "bigint(20) unsigned" or "UNSIGNED BIGINT".
+	unsigned := false
+	if gstr.Contains(t, "unsigned") {
+		unsigned = true
+		t = strings.TrimSpace(gstr.Replace(t, "unsigned", ""))
+	}
 	switch t {
-	case "binary", "varbinary", "blob", "tinyblob", "mediumblob", "longblob":
+	// The binary values are returned as raw []byte without any string conversion,
+	// eg: BYTEA of pgsql, IMAGE of mssql and RAW of oracle.
+	case "binary", "varbinary", "blob", "tinyblob", "mediumblob", "longblob", "bytea", "image", "raw", "long raw":
 		return fieldValue
 
 	case "int", "tinyint", "small_int", "smallint", "medium_int", "mediumint":
-		if gstr.ContainsI(fieldType, "unsigned") {
-			gconv.Uint(string(fieldValue))
+		if unsigned {
+			return gconv.Uint(string(fieldValue))
 		}
 		return gconv.Int(string(fieldValue))
 
 	case "big_int", "bigint":
-		if gstr.ContainsI(fieldType, "unsigned") {
-			gconv.Uint64(string(fieldValue))
+		if unsigned {
+			// It keeps the string if it cannot be parsed exactly.
+			if v, err := strconv.ParseUint(string(fieldValue), 10, 64); err == nil {
+				return v
+			}
+			return string(fieldValue)
 		}
 		return gconv.Int64(string(fieldValue))
 
-	case "float", "double", "decimal":
+	case "float", "double":
 		return gconv.Float64(string(fieldValue))
 
+	case "decimal", "numeric", "money", "smallmoney":
+		// It keeps the exact decimal string avoiding precision loss of float,
+		// which can be converted using Value.Float64 if necessary.
+		return string(fieldValue)
+
 	case "bit":
 		s := string(fieldValue)
 		// mssql is true|false string.
@@ -56,8 +84,19 @@ func (bs *dbBase) convertValue(fieldValue []byte, fieldType string) interface{}
 		}
 		return gbinary.BeDecodeToInt64(fieldValue)
 
-	case "bool":
-		return gconv.Bool(fieldValue)
+	case "bool", "boolean":
+		return parseBool(string(fieldValue))
+
+	case "json", "jsonb":
+		// It decodes the JSON content for binding to map/slice attributes of struct,
+		// numbers are decoded as json.Number avoiding precision loss.
+		var v interface{}
+		decoder := json.NewDecoder(bytes.NewReader(fieldValue))
+		decoder.UseNumber()
+		if err := decoder.Decode(&v); err != nil {
+			return string(fieldValue)
+		}
+		return v
 
 	case "date":
 		t, _ := gtime.StrToTime(string(fieldValue))
@@ -65,7 +104,7 @@ func (bs *dbBase) convertValue(fieldValue []byte, fieldType string) interface{}
 
 	case "datetime", "timestamp":
 		t, _ := gtime.StrToTime(string(fieldValue))
-		return t.String()
+		return formatDatetime(t)
 
 	default:
 		// Auto detect field type, using key match.
@@ -77,12 +116,15 @@ func (bs *dbBase) convertValue(fieldValue []byte, fieldType string) interface{}
 			return gconv.Float64(string(fieldValue))
 
 		case strings.Contains(t, "bool"):
-			return gconv.Bool(string(fieldValue))
+			return parseBool(string(fieldValue))
 
 		case strings.Contains(t, "binary") || strings.Contains(t, "blob"):
 			return fieldValue
 
 		case strings.Contains(t, "int"):
+			if unsigned {
+				return gconv.Uint64(string(fieldValue))
+			}
 			return gconv.Int(string(fieldValue))
 
 		case strings.Contains(t, "time"):
@@ -91,7 +133,7 @@ func (bs *dbBase) convertValue(fieldValue []byte, fieldType string) interface{}
 			if err != nil {
 				return s
 			}
-			return t.String()
+			return formatDatetime(t)
 
 		case strings.Contains(t, "date"):
 			s := string(fieldValue)
@@ -107,6 +149,357 @@ func (bs *dbBase) convertValue(fieldValue []byte, fieldType string) interface{}
 	}
 }
 
+// RegisterMoneyColumn registers the DECIMAL <column> of <table> as money column with
+// given <scale>, which is the count of the decimal places.
+//
+// The value of money column is read as int64 in minimum unit, that is the decimal
+// multiplied by 10^scale, eg: "12.34" is read as 1234 with scale 2. The integer value
+// for the money column is also divided by 10^scale automatically in writing.
+func (bs *dbBase) RegisterMoneyColumn(table string, column string, scale int) {
+	bs.moneyColumns.Set(bs.getTableKey(table)+"."+column, scale)
+}
+
+// RegisterTypeConverter registers <converter> for the columns of database type <fieldType>,
+// eg: "set", "tsvector". The type name is case-insensitive and without length, which is the
+// database type name reported by the driver. The converter takes priority over the built-in
+// conversion of the type.
+func (bs *dbBase) RegisterTypeConverter(fieldType string, converter Converter) {
+	bs.typeConverters.Set(getConverterTypeKey(fieldType), &converter)
+}
+
+// RegisterColumnConverter registers <converter> for the <column> of <table>, eg: the tags column
+// storing comma-separated strings can be decoded to []string in reading and encoded back in writing.
+// The column converter takes priority over the type converter.
+func (bs *dbBase) RegisterColumnConverter(table string, column string, converter Converter) {
+	bs.columnConverters.Set(bs.getTableKey(table)+"."+column, &converter)
+}
+
+// getConverterTypeKey returns the key of the type converter registry for <fieldType>,
+// which is the lower case type name without length, eg: "varchar" for "VARCHAR(255)".
+func getConverterTypeKey(fieldType string) string {
+	t, _ := gregex.ReplaceString(`\(.+\)`, "", fieldType)
+	return strings.ToLower(strings.TrimSpace(t))
+}
+
+// getTableKey returns the table key for the money column registry and cache tags,
+// which is the table name with prefix but without security chars.
+func (bs *dbBase) getTableKey(table string) string {
+	charLeft, charRight := bs.db.getChars()
+	table = bs.db.handleTableName(table)
+	if charLeft != "" {
+		table = gstr.Replace(table, charLeft, "")
+	}
+	if charRight != "" {
+		table = gstr.Replace(table, charRight, "")
+	}
+	return table
+}
+
+// getQueryTables retrieves the tables after "FROM" and "JOIN" from <query>, including the
+// comma-separated tables with aliases, eg: "FROM a x, b AS y", and returns their table keys.
+// The tables of the sub queries are also retrieved, but not the sub queries themselves.
+func (bs *dbBase) getQueryTables(query string) []string {
+	matches := queryTableReg.FindAllStringSubmatchIndex(query, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	tables := make([]string, 0, len(matches))
+	for _, match := range matches {
+		isFrom := strings.EqualFold(query[match[2]:match[3]], "FROM")
+		for _, table := range parseQueryTables(query[match[1]:], isFrom) {
+			tables = append(tables, bs.getTableKey(table))
+		}
+	}
+	return tables
+}
+
+// parseQueryTables parses and returns the tables at the beginning of <s>, which follows the
+// "FROM" keyword if <isFrom>, or else the "JOIN" keyword of which only one table is parsed.
+func parseQueryTables(s string, isFrom bool) []string {
+	var (
+		tables []string
+		index  = 0
+	)
+	// nextWord skips the white spaces and returns the next word, which ends with white space,
+	// comma or parentheses.
+	nextWord := func() string {
+		for index < len(s) && isSpaceChar(s[index]) {
+			index++
+		}
+		start := index
+		for index < len(s) && !isSpaceChar(s[index]) && !strings.ContainsRune(",()", rune(s[index])) {
+			index++
+		}
+		return s[start:index]
+	}
+	for {
+		table := nextWord()
+		// It's sub query if the table is empty.
+		if table == "" {
+			break
+		}
+		tables = append(tables, table)
+		// The optional alias of the table.
+		next := index
+		word := nextWord()
+		if strings.EqualFold(word, "AS") {
+			nextWord()
+		} else if _, ok := queryTableEndKeywords[strings.ToUpper(word)]; ok {
+			index = next
+		}
+		for index < len(s) && isSpaceChar(s[index]) {
+			index++
+		}
+		if !isFrom || index >= len(s) || s[index] != ',' {
+			break
+		}
+		index++
+	}
+	return tables
+}
+
+// isSpaceChar checks and returns whether <c> is the white space char.
+func isSpaceChar(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// getMoneyScales retrieves the tables from <query> and returns the registered money columns
+// of these tables, of which the key is the column name and the value is the scale.
+func (bs *dbBase) getMoneyScales(query string) map[string]int {
+	if bs.moneyColumns.Size() == 0 {
+		return nil
+	}
+	tables := bs.getQueryTables(query)
+	if len(tables) == 0 {
+		return nil
+	}
+	scales := make(map[string]int)
+	for _, table := range tables {
+		prefix := table + "."
+		bs.moneyColumns.RLockFunc(func(m map[string]int) {
+			for key, scale := range m {
+				if gstr.HasPrefix(key, prefix) {
+					scales[key[len(prefix):]] = scale
+				}
+			}
+		})
+	}
+	return scales
+}
+
+// getConverters returns the registered converters having Decode function for the columns of the
+// result of <query>, of which the key is the column name. The column converters of the tables in
+// <query> take priority over the type converters.
+func (bs *dbBase) getConverters(query string, columnNames []string, columnTypes []string) map[string]*Converter {
+	if bs.typeConverters.Size() == 0 && bs.columnConverters.Size() == 0 {
+		return nil
+	}
+	converters := make(map[string]*Converter)
+	if bs.typeConverters.Size() > 0 {
+		for i, name := range columnNames {
+			if v := bs.typeConverters.Get(getConverterTypeKey(columnTypes[i])); v != nil {
+				if converter := v.(*Converter); converter.Decode != nil {
+					converters[name] = converter
+				}
+			}
+		}
+	}
+	if bs.columnConverters.Size() > 0 {
+		for _, table := range bs.getQueryTables(query) {
+			prefix := table + "."
+			bs.columnConverters.RLockFunc(func(m map[string]interface{}) {
+				for key, v := range m {
+					if converter := v.(*Converter); converter.Decode != nil && gstr.HasPrefix(key, prefix) {
+						converters[key[len(prefix):]] = converter
+					}
+				}
+			})
+		}
+	}
+	return converters
+}
+
+// filterColumnData removes the key-value pairs of <data> which are not the columns of <table>
+// if the filtering is enabled by SetFilterUnknownColumns. The keys are matched case-insensitively,
+// eg: the untagged struct attribute "Nickname" is renamed to the column "nickname". It returns a
+// new map if any key is removed or renamed, or else <data>. It does nothing if the columns of
+// <table> cannot be retrieved.
+func (bs *dbBase) filterColumnData(table string, data Map) Map {
+	if !bs.filterColumns.Val() || gstr.ContainsAny(gstr.Trim(table), " ,") {
+		return data
+	}
+	fields, err := bs.db.TableFields(table)
+	if err != nil || len(fields) == 0 {
+		return data
+	}
+	var (
+		newData Map
+		columns map[string]string
+	)
+	for k, v := range data {
+		if _, ok := fields[k]; ok {
+			continue
+		}
+		if newData == nil {
+			newData = make(Map, len(data))
+			for key, value := range data {
+				newData[key] = value
+			}
+			columns = make(map[string]string, len(fields))
+			for name := range fields {
+				columns[strings.ToLower(name)] = name
+			}
+		}
+		delete(newData, k)
+		// The value of the key exactly matching the column takes precedence.
+		if column, ok := columns[strings.ToLower(k)]; ok {
+			if _, ok := data[column]; !ok {
+				newData[column] = v
+			}
+		}
+	}
+	if newData == nil {
+		return data
+	}
+	return newData
+}
+
+// convertMoneyData converts the integer values of the registered money columns of <table> in
+// <data> to decimal strings. It returns a new map if any value is converted, or else <data>.
+func (bs *dbBase) convertMoneyData(table string, data Map) Map {
+	if bs.moneyColumns.Size() == 0 {
+		return data
+	}
+	var newData Map
+	tableKey := bs.getTableKey(table)
+	for k, v := range data {
+		scale, ok := bs.moneyColumns.Search(tableKey + "." + k)
+		if !ok {
+			continue
+		}
+		switch v.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		default:
+			continue
+		}
+		if newData == nil {
+			newData = make(Map, len(data))
+			for key, value := range data {
+				newData[key] = value
+			}
+		}
+		newData[k] = centsToDecimal(gconv.Int64(v), scale)
+	}
+	if newData == nil {
+		return data
+	}
+	return newData
+}
+
+// convertJsonData encodes the map/slice values of the JSON type columns of <table> in <data> to
+// JSON strings. It returns a new map if any value is encoded, or else <data>.
+//
+// Note that the struct value in <data> is not supported here, which is converted to map before,
+// use tag option "json" for its attribute instead, eg: `orm:"attrs,json"`.
+func (bs *dbBase) convertJsonData(table string, data Map) Map {
+	candidate := false
+	for _, v := range data {
+		if isJsonValue(v) {
+			candidate = true
+			break
+		}
+	}
+	// It retrieves the table fields only if necessary.
+	if !candidate || gstr.ContainsAny(gstr.Trim(table), " ,") {
+		return data
+	}
+	fields, err := bs.db.TableFields(table)
+	if err != nil {
+		return data
+	}
+	var newData Map
+	for k, v := range data {
+		field, ok := fields[k]
+		if !ok || !isJsonValue(v) {
+			continue
+		}
+		if t := strings.ToLower(field.Type); t != "json" && t != "jsonb" {
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			continue
+		}
+		if newData == nil {
+			newData = make(Map, len(data))
+			for key, value := range data {
+				newData[key] = value
+			}
+		}
+		newData[k] = string(b)
+	}
+	if newData == nil {
+		return data
+	}
+	return newData
+}
+
+// convertData converts the values of <data> for writing to <table>, using the registered converters,
+// money columns, JSON type columns, array type columns and sensitive columns in order. It returns a new map if any value
+// is converted, or else <data>.
+func (bs *dbBase) convertData(table string, data Map) Map {
+	data = bs.convertJsonData(table, bs.convertMoneyData(table, bs.convertColumnData(table, data)))
+	return bs.markSensitiveData(bs.db.convertArrayData(table, data))
+}
+
+// convertArrayData converts the values of the array type columns of <table> in <data> for writing.
+// It does nothing in default, as only pgsql supports array type.
+func (bs *dbBase) convertArrayData(table string, data Map) Map {
+	return data
+}
+
+// convertColumnData encodes the values of <data> using the Encode function of the registered
+// converters for the columns of <table>. It returns a new map if any value is encoded, or else <data>.
+func (bs *dbBase) convertColumnData(table string, data Map) Map {
+	if bs.typeConverters.Size() == 0 && bs.columnConverters.Size() == 0 {
+		return data
+	}
+	// It retrieves the table fields only if necessary.
+	var fields map[string]*TableField
+	if bs.typeConverters.Size() > 0 && !gstr.ContainsAny(gstr.Trim(table), " ,") {
+		fields, _ = bs.db.TableFields(table)
+	}
+	var newData Map
+	tableKey := bs.getTableKey(table)
+	for k, v := range data {
+		if v == nil {
+			continue
+		}
+		var converter *Converter
+		if c := bs.columnConverters.Get(tableKey + "." + k); c != nil {
+			converter = c.(*Converter)
+		} else if field, ok := fields[k]; ok {
+			if c := bs.typeConverters.Get(getConverterTypeKey(field.Type)); c != nil {
+				converter = c.(*Converter)
+			}
+		}
+		if converter == nil || converter.Encode == nil {
+			continue
+		}
+		if newData == nil {
+			newData = make(Map, len(data))
+			for key, value := range data {
+				newData[key] = value
+			}
+		}
+		newData[k] = converter.Encode(v)
+	}
+	if newData == nil {
+		return data
+	}
+	return newData
+}
+
 // filterFields removes all key-value pairs which are not the field of given table.
 func (bs *dbBase) filterFields(schema, table string, data map[string]interface{}) map[string]interface{} {
 	// It must use data copy here to avoid its changing the origin data map.
@@ -140,23 +533,95 @@ func (bs *dbBase) Tables(schema ...string) (tables []string, err error) {
 	return
 }
 
+// TableExists checks and returns whether <table> exists in the schema.
+// The table name is handled like TableFields, and the parameter <schema>
+// specifies the schema instead of the configured one.
+// It returns false and nil error if the table does not exist.
+func (bs *dbBase) TableExists(table string, schema ...string) (bool, error) {
+	table = gstr.Trim(table)
+	if gstr.ContainsAny(table, " ,") {
+		panic("function TableExists supports only single table operations")
+	}
+	table, checkSchema := bs.getTableSchema(table, schema...)
+	link, err := bs.db.getSlave(checkSchema)
+	if err != nil {
+		return false, err
+	}
+	result, err := bs.db.doGetAll(link, bs.db.getTableExistsSql(), table)
+	if err != nil {
+		return false, err
+	}
+	return len(result) > 0, nil
+}
+
+// getTableExistsSql returns the statement retrieving the table of current schema, which has
+// only one place holder for the table name. It returns no record if the table does not exist.
+func (bs *dbBase) getTableExistsSql() string {
+	return "SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA=DATABASE() AND TABLE_NAME=?"
+}
+
+// getVersionSql returns the statement retrieving the version of the database server.
+func (bs *dbBase) getVersionSql() string {
+	return "SELECT VERSION()"
+}
+
+// ClearTableFieldsCache removes the cached fields of <table>, which should be called after
+// the table structure is changed, eg: DDL migrations. The table name is handled like TableFields,
+// and the parameter <schema> specifies the schema instead of the configured one.
+func (bs *dbBase) ClearTableFieldsCache(table string, schema ...string) {
+	table, checkSchema := bs.getTableSchema(table, schema...)
+	bs.cache.Remove(bs.getTableFieldsCacheKey(table, checkSchema))
+}
+
+// getTableFieldsCacheKey returns the cache key of the fields of <table> in <schema>,
+// in which the <table> should be the table name with prefix.
+func (bs *dbBase) getTableFieldsCacheKey(table string, schema string) string {
+	return fmt.Sprintf(`%s%s_%s`, gTABLE_FIELDS_CACHE_KEY_PREFIX, schema, table)
+}
+
+// getOrSetTableFields retrieves and returns the cached table fields by <key>, or else sets the
+// cache with the result of <f> if it's not nil. The <f> is called only once for the same <key>
+// concurrently, which avoids many goroutines querying the metadata of the same table at once.
+//
+// Note that it does not use the cache lock as <f> also uses the cache for the connection objects.
+func (bs *dbBase) getOrSetTableFields(key string, f func() interface{}) interface{} {
+	if v := bs.cache.Get(key); v != nil {
+		return v
+	}
+	mu := bs.tableFieldsLocks.GetOrSetFuncLock(key, func() interface{} {
+		return new(sync.Mutex)
+	}).(*sync.Mutex)
+	mu.Lock()
+	defer mu.Unlock()
+	if v := bs.cache.Get(key); v != nil {
+		return v
+	}
+	v := f()
+	if v != nil {
+		bs.cache.Set(key, v, 0)
+	}
+	return v
+}
+
 // TableFields retrieves and returns the fields of given table.
 //
 // Note that it returns a map containing the field name and its corresponding fields.
 // As a map is unsorted, the TableField struct has a "Index" field marks its sequence in the fields.
 //
-// It's using cache feature to enhance the performance, which is never expired util the process restarts.
+// The table name is handled with the configured prefix, redirections and table resolver, and the
+// fields are retrieved from the schema qualifying the resolved table name if no <schema> is given,
+// eg: "tenant_001" of "tenant_001.user". The Key attribute of the primary key field is "PRI" for all drivers.
+//
+// It's using cache feature to enhance the performance, which is never expired util the process restarts
+// or ClearTableFieldsCache is called.
 func (bs *dbBase) TableFields(table string, schema ...string) (fields map[string]*TableField, err error) {
 	table = gstr.Trim(table)
 	if gstr.Contains(table, " ") {
 		panic("function TableFields supports only single table operations")
 	}
-	checkSchema := bs.schema.Val()
-	if len(schema) > 0 && schema[0] != "" {
-		checkSchema = schema[0]
-	}
-	v := bs.cache.GetOrSetFunc(
-		fmt.Sprintf(`mysql_table_fields_%s_%s`, table, checkSchema),
+	table, checkSchema := bs.getTableSchema(table, schema...)
+	v := bs.getOrSetTableFields(
+		bs.getTableFieldsCacheKey(table, checkSchema),
 		func() interface{} {
 			var result Result
 			var link *sql.DB
@@ -185,7 +650,7 @@ func (bs *dbBase) TableFields(table string, schema ...string) (fields map[string
 				}
 			}
 			return fields
-		}, 0)
+		})
 	if err == nil {
 		fields = v.(map[string]*TableField)
 	}
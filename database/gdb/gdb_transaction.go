@@ -8,39 +8,136 @@ package gdb
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"reflect"
+	"sync"
 
+	"github.com/gogf/gf/internal/empty"
 	"github.com/gogf/gf/text/gregex"
 )
 
+// ErrReadOnlyTx is returned by the write operations on read-only transaction. See DB.Begin.
+var ErrReadOnlyTx = errors.New("write operation on read-only transaction")
+
 // TX is the struct for transaction management.
 type TX struct {
-	db     DB
-	tx     *sql.Tx
-	master *sql.DB
+	db         DB
+	tx         *sql.Tx
+	link       dbLink // Link for the statements, which is *txLink, or *readOnlyTxLink for read-only transaction.
+	master     *sql.DB
+	savepoints int       // Count of the savepoints created by Run.
+	inflight   *inflight // In-flight operations of the DB object, which the transaction is one of.
+	finishOnce sync.Once // Removes the transaction from the in-flight operations once.
+}
+
+// txLink is the link of read-write transaction, which records the tables written in the transaction
+// for clearing their caches again after committing. See DB.GetAllCache.
+type txLink struct {
+	*sql.Tx
+	mu     sync.Mutex
+	tables map[string]struct{} // Tables written in the transaction.
+}
+
+// addTable records <table> written in the transaction.
+func (l *txLink) addTable(table string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tables == nil {
+		l.tables = make(map[string]struct{})
+	}
+	l.tables[table] = struct{}{}
+}
+
+// readOnlyTxLink is the link of read-only transaction, of which the Exec statements are
+// rejected with ErrReadOnlyTx by doExec. The other writing statements are rejected by the
+// database as the transaction is started in read-only access mode.
+type readOnlyTxLink struct {
+	*sql.Tx
 }
 
-// Commit commits the transaction.
+// IsReadOnly checks and returns whether the transaction is read-only. See DB.Begin.
+func (tx *TX) IsReadOnly() bool {
+	_, ok := tx.link.(*readOnlyTxLink)
+	return ok
+}
+
+// Commit commits the transaction, and clears the caches of the tables written in the transaction.
 func (tx *TX) Commit() error {
-	return tx.tx.Commit()
+	defer tx.finish()
+	if err := tx.tx.Commit(); err != nil {
+		return err
+	}
+	if l, ok := tx.link.(*txLink); ok {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for table := range l.tables {
+			tx.db.clearTableCache(nil, table)
+		}
+	}
+	return nil
 }
 
 // Rollback aborts the transaction.
 func (tx *TX) Rollback() error {
+	defer tx.finish()
 	return tx.tx.Rollback()
 }
 
+// finish removes the transaction from the in-flight operations of the DB object.
+func (tx *TX) finish() {
+	tx.finishOnce.Do(func() {
+		if tx.inflight != nil {
+			tx.inflight.release()
+		}
+	})
+}
+
+// Run wraps the operations in <f> with a savepoint of the transaction. If <f> returns error,
+// it rolls back the transaction to the savepoint and returns the error, which undoes only
+// the operations in <f> without aborting the transaction. It is commonly used in the closure
+// of Transaction for try/catch-style partial rollback.
+//
+// It also rolls back to the savepoint if <f> panics, and then panics again.
+func (tx *TX) Run(f func(tx *TX) error) (err error) {
+	tx.savepoints++
+	save, rollback, release := tx.db.getSavepointSql(fmt.Sprintf("%s%d", gSAVEPOINT_PREFIX, tx.savepoints))
+	// The savepoint statements are allowed on read-only transaction.
+	if _, err = tx.db.doExec(tx.tx, save); err != nil {
+		return err
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			tx.db.doExec(tx.tx, rollback)
+			panic(e)
+		}
+		if err != nil {
+			if _, e := tx.db.doExec(tx.tx, rollback); e != nil {
+				err = errors.New(fmt.Sprintf("%s, rollback to savepoint failed: %s", err.Error(), e.Error()))
+			}
+		} else if release != "" {
+			_, err = tx.db.doExec(tx.tx, release)
+		}
+	}()
+	return f(tx)
+}
+
 // Query does query operation on transaction.
 // See dbBase.Query.
 func (tx *TX) Query(query string, args ...interface{}) (rows *sql.Rows, err error) {
-	return tx.db.doQuery(tx.tx, query, args...)
+	return tx.db.doQuery(tx.link, query, args...)
 }
 
 // Exec does none query operation on transaction.
 // See dbBase.Exec.
 func (tx *TX) Exec(query string, args ...interface{}) (sql.Result, error) {
-	return tx.db.doExec(tx.tx, query, args...)
+	return tx.db.doExec(tx.link, query, args...)
+}
+
+// ExecMulti splits the SQL <script> into statements and executes them in order on transaction.
+// See dbBase.ExecMulti.
+func (tx *TX) ExecMulti(script string) error {
+	return doExecMulti(tx.db, tx.link, script)
 }
 
 // Prepare creates a prepared statement for later queries or executions.
@@ -49,7 +146,7 @@ func (tx *TX) Exec(query string, args ...interface{}) (sql.Result, error) {
 // The caller must call the statement's Close method
 // when the statement is no longer needed.
 func (tx *TX) Prepare(query string) (*sql.Stmt, error) {
-	return tx.db.doPrepare(tx.tx, query)
+	return tx.db.doPrepare(tx.link, query)
 }
 
 // GetAll queries and returns data records from database.
@@ -59,7 +156,7 @@ func (tx *TX) GetAll(query string, args ...interface{}) (Result, error) {
 		return nil, err
 	}
 	defer rows.Close()
-	return tx.db.rowsToResult(rows)
+	return tx.db.rowsToResult(rows, query)
 }
 
 // GetOne queries and returns one record from database.
@@ -74,6 +171,19 @@ func (tx *TX) GetOne(query string, args ...interface{}) (Record, error) {
 	return nil, nil
 }
 
+// GetOneOrErr queries and returns one record from database, which is the same as GetOne
+// but it returns ErrNoRows if there's no record retrieved.
+func (tx *TX) GetOneOrErr(query string, args ...interface{}) (Record, error) {
+	one, err := tx.GetOne(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(one) == 0 {
+		return nil, ErrNoRows
+	}
+	return one, nil
+}
+
 // GetStruct queries one record from database and converts it to given struct.
 // The parameter <pointer> should be a pointer to struct.
 func (tx *TX) GetStruct(obj interface{}, query string, args ...interface{}) error {
@@ -94,12 +204,27 @@ func (tx *TX) GetStructs(objPointerSlice interface{}, query string, args ...inte
 	return all.Structs(objPointerSlice)
 }
 
-// GetScan queries one or more records from database and converts them to given struct or
-// struct array.
+// GetMapStructs queries records from database and converts them to a map of structs of which
+// key is the value of column <key>. Also see dbBase.GetMapStructs.
+func (tx *TX) GetMapStructs(pointer interface{}, key string, query string, args ...interface{}) error {
+	all, err := tx.GetAll(query, args...)
+	if err != nil {
+		return err
+	}
+	return all.MapKeyStructs(pointer, key)
+}
+
+// GetScan queries one or more records from database and converts them to given struct,
+// struct array, map or map array.
 //
 // If parameter <pointer> is type of struct pointer, it calls GetStruct internally for
 // the conversion. If parameter <pointer> is type of slice, it calls GetStructs internally
 // for conversion.
+//
+// The parameter <pointer> can also be type of *map[string]interface{}/*Record for one record,
+// or *[]map[string]interface{}/*Result for all records.
+//
+// It returns ErrNoRows if there's no record retrieved, and <pointer> is not changed.
 func (tx *TX) GetScan(objPointer interface{}, query string, args ...interface{}) error {
 	t := reflect.TypeOf(objPointer)
 	k := t.Kind()
@@ -109,11 +234,30 @@ func (tx *TX) GetScan(objPointer interface{}, query string, args ...interface{})
 	k = t.Elem().Kind()
 	switch k {
 	case reflect.Array, reflect.Slice:
-		return tx.db.GetStructs(objPointer, query, args...)
+		if t.Elem().Elem().Kind() == reflect.Map {
+			all, err := tx.GetAll(query, args...)
+			if err != nil {
+				return err
+			}
+			if len(all) == 0 {
+				return ErrNoRows
+			}
+			return scanMaps(objPointer, all, all.List())
+		}
+		return tx.GetStructs(objPointer, query, args...)
+	case reflect.Map:
+		one, err := tx.GetOne(query, args...)
+		if err != nil {
+			return err
+		}
+		if len(one) == 0 {
+			return ErrNoRows
+		}
+		return scanMaps(objPointer, one, one.Map())
 	case reflect.Struct:
-		return tx.db.GetStruct(objPointer, query, args...)
+		return tx.GetStruct(objPointer, query, args...)
 	default:
-		return fmt.Errorf("element type should be type of struct/slice, unsupported: %v", k)
+		return fmt.Errorf("element type should be type of struct/slice/map, unsupported: %v", k)
 	}
 	return nil
 }
@@ -132,6 +276,19 @@ func (tx *TX) GetValue(query string, args ...interface{}) (Value, error) {
 	return nil, nil
 }
 
+// GetScalar queries one field of one record from database and assigns it to the variable <pointer>.
+// It returns ErrNoRows if there's no record retrieved. Also see dbBase.GetScalar.
+func (tx *TX) GetScalar(pointer interface{}, query string, args ...interface{}) error {
+	one, err := tx.GetOneOrErr(query, args...)
+	if err != nil {
+		return err
+	}
+	for _, v := range one {
+		return scanValue(pointer, v.Val())
+	}
+	return nil
+}
+
 // GetCount queries and returns the count from database.
 func (tx *TX) GetCount(query string, args ...interface{}) (int, error) {
 	if !gregex.IsMatchString(`(?i)SELECT\s+COUNT\(.+\)\s+FROM`, query) {
@@ -144,6 +301,120 @@ func (tx *TX) GetCount(query string, args ...interface{}) (int, error) {
 	return value.Int(), nil
 }
 
+// Explain queries and returns the execution plan of <query> on transaction.
+// Also see dbBase.Explain.
+func (tx *TX) Explain(query string, args ...interface{}) (Result, error) {
+	explainSql, err := tx.db.getExplainSql(query)
+	if err != nil {
+		return nil, err
+	}
+	return tx.GetAll(explainSql, args...)
+}
+
+// CountTable queries and returns the count of records of <table> matching <condition>
+// on transaction. Also see dbBase.CountTable.
+func (tx *TX) CountTable(table string, condition interface{}, args ...interface{}) (int, error) {
+	value, err := doAggregate(tx.db, tx.link, table, "COUNT(1)", condition, args...)
+	if err != nil {
+		return 0, err
+	}
+	return value.Int(), nil
+}
+
+// CountDistinct queries and returns the count of distinct non-NULL values of <column> of
+// the records of <table> matching <condition> on transaction. Also see dbBase.CountDistinct.
+func (tx *TX) CountDistinct(table string, column string, condition interface{}, args ...interface{}) (int, error) {
+	expression := fmt.Sprintf("COUNT(DISTINCT %s)", tx.db.quoteWord(column))
+	value, err := doAggregate(tx.db, tx.link, table, expression, condition, args...)
+	if err != nil {
+		return 0, err
+	}
+	return value.Int(), nil
+}
+
+// Sum queries and returns the sum of <column> of the records of <table> matching <condition>
+// on transaction. Also see dbBase.Sum.
+func (tx *TX) Sum(table string, column string, condition interface{}, args ...interface{}) (Value, error) {
+	return tx.aggregate("SUM", table, column, condition, args...)
+}
+
+// Avg queries and returns the average of <column> of the records of <table> matching <condition>
+// on transaction. Also see dbBase.Avg.
+func (tx *TX) Avg(table string, column string, condition interface{}, args ...interface{}) (Value, error) {
+	return tx.aggregate("AVG", table, column, condition, args...)
+}
+
+// Min queries and returns the minimum of <column> of the records of <table> matching <condition>
+// on transaction. Also see dbBase.Min.
+func (tx *TX) Min(table string, column string, condition interface{}, args ...interface{}) (Value, error) {
+	return tx.aggregate("MIN", table, column, condition, args...)
+}
+
+// Max queries and returns the maximum of <column> of the records of <table> matching <condition>
+// on transaction. Also see dbBase.Max.
+func (tx *TX) Max(table string, column string, condition interface{}, args ...interface{}) (Value, error) {
+	return tx.aggregate("MAX", table, column, condition, args...)
+}
+
+// aggregate queries and returns the value of aggregate <function> over the quoted <column>
+// of the records of <table> matching <condition> on transaction.
+func (tx *TX) aggregate(function string, table string, column string, condition interface{}, args ...interface{}) (Value, error) {
+	expression := fmt.Sprintf("%s(%s)", function, tx.db.quoteWord(column))
+	return doAggregate(tx.db, tx.link, table, expression, condition, args...)
+}
+
+// GetArray queries and returns the values of the first column of all records on transaction.
+// Also see dbBase.GetArray.
+func (tx *TX) GetArray(query string, args ...interface{}) ([]Value, error) {
+	return doGetArray(tx.db, tx.link, query, args...)
+}
+
+// GetInts queries and returns the values of the first column of all records as []int
+// on transaction. Also see dbBase.GetInts.
+func (tx *TX) GetInts(query string, args ...interface{}) ([]int, error) {
+	return doGetInts(tx.db, tx.link, query, args...)
+}
+
+// GetStrings queries and returns the values of the first column of all records as []string
+// on transaction. Also see dbBase.GetStrings.
+func (tx *TX) GetStrings(query string, args ...interface{}) ([]string, error) {
+	return doGetStrings(tx.db, tx.link, query, args...)
+}
+
+// Find queries one record from <table> by its primary key on transaction and converts it
+// to given struct. Also see dbBase.Find.
+func (tx *TX) Find(pointer interface{}, table string, primary interface{}) error {
+	query, args, err := getFindQuery(tx.db, table, primary)
+	if err != nil {
+		return err
+	}
+	return tx.GetStruct(pointer, query, args...)
+}
+
+// Chunk queries and processes the records page by page on transaction.
+// Also see dbBase.Chunk.
+func (tx *TX) Chunk(size int, callback func(result Result) error, query string, args ...interface{}) error {
+	return doChunk(tx.db, tx.link, size, "", callback, query, args...)
+}
+
+// ChunkByKey queries and processes the records page by page using keyset pagination on column <key>
+// on transaction. Also see dbBase.ChunkByKey.
+func (tx *TX) ChunkByKey(key string, size int, callback func(result Result) error, query string, args ...interface{}) error {
+	return doChunk(tx.db, tx.link, size, key, callback, query, args...)
+}
+
+// Paginate queries and returns one page of records along with the total count on transaction.
+// Also see dbBase.Paginate.
+func (tx *TX) Paginate(query string, page int, size int, args ...interface{}) (result Result, total int, err error) {
+	return doPaginate(tx.db, tx.link, query, page, size, args...)
+}
+
+// GetPageWithAggregates queries and returns one page of records along with the total count and
+// the aggregate values on transaction. Also see dbBase.GetPageWithAggregates.
+func (tx *TX) GetPageWithAggregates(page int, size int, from string, where interface{}, args []interface{}, aggregates map[string]string) (result Result, total int, values Record, err error) {
+	return doGetPageWithAggregates(tx.db, tx.link, page, size, from, where, args, aggregates)
+}
+
 // Insert does "INSERT INTO ..." statement for the table.
 // If there's already one unique record of the data in the table, it returns error.
 //
@@ -154,11 +425,12 @@ func (tx *TX) GetCount(query string, args ...interface{}) (int, error) {
 //
 // The parameter <batch> specifies the batch operation count when given data is slice.
 func (tx *TX) Insert(table string, data interface{}, batch ...int) (sql.Result, error) {
-	return tx.db.doInsert(tx.tx, table, data, gINSERT_OPTION_DEFAULT, batch...)
+	return tx.db.doInsert(tx.link, table, data, gINSERT_OPTION_DEFAULT, batch...)
 }
 
 // InsertIgnore does "INSERT IGNORE INTO ..." statement for the table.
 // If there's already one unique record of the data in the table, it ignores the inserting.
+// It uses "INSERT OR IGNORE" for sqlite and "INSERT ... ON CONFLICT DO NOTHING" for pgsql.
 //
 // The parameter <data> can be type of map/gmap/struct/*struct/[]map/[]struct, etc.
 // Eg:
@@ -167,7 +439,7 @@ func (tx *TX) Insert(table string, data interface{}, batch ...int) (sql.Result,
 //
 // The parameter <batch> specifies the batch operation count when given data is slice.
 func (tx *TX) InsertIgnore(table string, data interface{}, batch ...int) (sql.Result, error) {
-	return tx.db.doInsert(tx.tx, table, data, gINSERT_OPTION_IGNORE, batch...)
+	return tx.db.doInsert(tx.link, table, data, gINSERT_OPTION_IGNORE, batch...)
 }
 
 // Replace does "REPLACE INTO ..." statement for the table.
@@ -183,7 +455,7 @@ func (tx *TX) InsertIgnore(table string, data interface{}, batch ...int) (sql.Re
 // If given data is type of slice, it then does batch replacing, and the optional parameter
 // <batch> specifies the batch operation count.
 func (tx *TX) Replace(table string, data interface{}, batch ...int) (sql.Result, error) {
-	return tx.db.doInsert(tx.tx, table, data, gINSERT_OPTION_REPLACE, batch...)
+	return tx.db.doInsert(tx.link, table, data, gINSERT_OPTION_REPLACE, batch...)
 }
 
 // Save does "INSERT INTO ... ON DUPLICATE KEY UPDATE..." statement for the table.
@@ -198,31 +470,61 @@ func (tx *TX) Replace(table string, data interface{}, batch ...int) (sql.Result,
 // If given data is type of slice, it then does batch saving, and the optional parameter
 // <batch> specifies the batch operation count.
 func (tx *TX) Save(table string, data interface{}, batch ...int) (sql.Result, error) {
-	return tx.db.doInsert(tx.tx, table, data, gINSERT_OPTION_SAVE, batch...)
+	return tx.db.doInsert(tx.link, table, data, gINSERT_OPTION_SAVE, batch...)
+}
+
+// InsertOnConflict does "INSERT ... ON CONFLICT (conflict) DO UPDATE SET ..." statement for the
+// table on transaction. See dbBase.InsertOnConflict.
+func (tx *TX) InsertOnConflict(table string, data interface{}, conflict []string, batch ...int) (sql.Result, error) {
+	return tx.db.doInsert(tx.link, table, withConflict(data, conflict), gINSERT_OPTION_SAVE, batch...)
+}
+
+// SaveAndGetStatus saves single record and returns its saving status on transaction.
+// See dbBase.SaveAndGetStatus.
+func (tx *TX) SaveAndGetStatus(table string, data interface{}) (int, error) {
+	return doSaveAndGetStatus(tx.db, tx.link, table, data)
+}
+
+// InsertSelect does "INSERT INTO table(columns) SELECT ..." statement in the transaction.
+// See dbBase.InsertSelect.
+func (tx *TX) InsertSelect(table string, columns []string, selectQuery string, args ...interface{}) (sql.Result, error) {
+	return tx.db.doInsertSelect(tx.link, table, columns, selectQuery, args...)
 }
 
 // BatchInsert batch inserts data.
 // The parameter <list> must be type of slice of map or struct.
 func (tx *TX) BatchInsert(table string, list interface{}, batch ...int) (sql.Result, error) {
-	return tx.db.doBatchInsert(tx.tx, table, list, gINSERT_OPTION_DEFAULT, batch...)
+	return tx.db.doBatchInsert(tx.link, table, list, gINSERT_OPTION_DEFAULT, batch...)
 }
 
 // BatchInsert batch inserts data with ignore option.
 // The parameter <list> must be type of slice of map or struct.
 func (tx *TX) BatchInsertIgnore(table string, list interface{}, batch ...int) (sql.Result, error) {
-	return tx.db.doBatchInsert(tx.tx, table, list, gINSERT_OPTION_IGNORE, batch...)
+	return tx.db.doBatchInsert(tx.link, table, list, gINSERT_OPTION_IGNORE, batch...)
 }
 
 // BatchReplace batch replaces data.
 // The parameter <list> must be type of slice of map or struct.
 func (tx *TX) BatchReplace(table string, list interface{}, batch ...int) (sql.Result, error) {
-	return tx.db.doBatchInsert(tx.tx, table, list, gINSERT_OPTION_REPLACE, batch...)
+	return tx.db.doBatchInsert(tx.link, table, list, gINSERT_OPTION_REPLACE, batch...)
 }
 
 // BatchSave batch replaces data.
 // The parameter <list> must be type of slice of map or struct.
 func (tx *TX) BatchSave(table string, list interface{}, batch ...int) (sql.Result, error) {
-	return tx.db.doBatchInsert(tx.tx, table, list, gINSERT_OPTION_SAVE, batch...)
+	return tx.db.doBatchInsert(tx.link, table, list, gINSERT_OPTION_SAVE, batch...)
+}
+
+// BatchInsertAndGetIds batch inserts <list> and returns the primary key values of all the records
+// in the order of <list>. Also see dbBase.BatchInsertAndGetIds.
+func (tx *TX) BatchInsertAndGetIds(table string, list interface{}, primary string, batch ...int) ([]int64, error) {
+	return tx.db.doBatchInsertAndGetIds(tx.link, table, list, primary, batch...)
+}
+
+// BatchSaveAndGetIds batch saves <list> and returns the primary key values of all the records
+// in the order of <list>. Also see dbBase.BatchSaveAndGetIds.
+func (tx *TX) BatchSaveAndGetIds(table string, list interface{}, primary string, keys ...string) ([]int64, error) {
+	return tx.db.doBatchSaveAndGetIds(tx.link, table, list, primary, keys)
 }
 
 // Update does "UPDATE ... " statement for the table.
@@ -244,7 +546,19 @@ func (tx *TX) Update(table string, data interface{}, condition interface{}, args
 	if newWhere != "" {
 		newWhere = " WHERE " + newWhere
 	}
-	return tx.db.doUpdate(tx.tx, table, data, newWhere, newArgs...)
+	return tx.db.doUpdate(tx.link, table, data, newWhere, newArgs...)
+}
+
+// UpdateAndCount does "UPDATE ... " statement for the table like Update,
+// but returns the number of the affected rows.
+func (tx *TX) UpdateAndCount(table string, data interface{}, condition interface{}, args ...interface{}) (int64, error) {
+	return getRowsAffected(tx.Update(table, data, condition, args...))
+}
+
+// BatchUpdate updates <column> of multiple records with different values in batch.
+// See dbBase.BatchUpdate.
+func (tx *TX) BatchUpdate(table string, column string, key string, data interface{}, batch ...int) (sql.Result, error) {
+	return tx.db.doBatchUpdate(tx.link, table, column, key, data, batch...)
 }
 
 // Delete does "DELETE FROM ... " statement for the table.
@@ -263,5 +577,25 @@ func (tx *TX) Delete(table string, condition interface{}, args ...interface{}) (
 	if newWhere != "" {
 		newWhere = " WHERE " + newWhere
 	}
-	return tx.db.doDelete(tx.tx, table, newWhere, newArgs...)
+	return tx.db.doDelete(tx.link, table, newWhere, newArgs...)
+}
+
+// DeleteAndCount does "DELETE FROM ... " statement for the table like Delete,
+// but returns the number of the affected rows.
+func (tx *TX) DeleteAndCount(table string, condition interface{}, args ...interface{}) (int64, error) {
+	return getRowsAffected(tx.Delete(table, condition, args...))
+}
+
+// ReplaceSet deletes the records of <table> matching <condition> and then batch inserts <list>
+// in the transaction. It only deletes the matched records if <list> is empty.
+// Also see dbBase.ReplaceSet.
+func (tx *TX) ReplaceSet(table string, condition interface{}, list interface{}) error {
+	if _, err := tx.Delete(table, condition); err != nil {
+		return err
+	}
+	if empty.IsEmpty(list) {
+		return nil
+	}
+	_, err := tx.BatchInsert(table, list)
+	return err
 }
@@ -7,7 +7,6 @@
 package gdb
 
 import (
-	"database/sql"
 	"github.com/gogf/gf/container/gmap"
 	"github.com/gogf/gf/util/gconv"
 
@@ -44,7 +43,7 @@ func (r Record) GMap() *gmap.StrAnyMap {
 // Note that the parameter <pointer> should be type of *struct/**struct.
 func (r Record) Struct(pointer interface{}) error {
 	if r == nil {
-		return sql.ErrNoRows
+		return ErrNoRows
 	}
 	return mapToStruct(r.Map(), pointer)
 }
@@ -7,7 +7,6 @@
 package gdb
 
 import (
-	"database/sql"
 	"github.com/gogf/gf/encoding/gparser"
 )
 
@@ -35,7 +34,7 @@ func (r Record) ToMap() Map {
 // Deprecated.
 func (r Record) ToStruct(pointer interface{}) error {
 	if r == nil {
-		return sql.ErrNoRows
+		return ErrNoRows
 	}
 	return mapToStruct(r.Map(), pointer)
 }
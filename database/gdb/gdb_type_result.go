@@ -7,7 +7,6 @@
 package gdb
 
 import (
-	"database/sql"
 	"fmt"
 	"reflect"
 
@@ -106,7 +105,7 @@ func (r Result) RecordKeyUint(key string) map[uint]Record {
 func (r Result) Structs(pointer interface{}) (err error) {
 	l := len(r)
 	if l == 0 {
-		return sql.ErrNoRows
+		return ErrNoRows
 	}
 	t := reflect.TypeOf(pointer)
 	if t.Kind() != reflect.Ptr {
@@ -133,6 +132,50 @@ func (r Result) Structs(pointer interface{}) (err error) {
 	return nil
 }
 
+// MapKeyStructs converts <r> to a map of structs of which key is the value of column <key>,
+// eg: map[int]*User keyed by "id". The parameter <pointer> should be type of *map[K]struct or
+// *map[K]*struct, and the key value is converted to type K. The latter record overwrites
+// the former one if they have the same key value.
+func (r Result) MapKeyStructs(pointer interface{}, key string) (err error) {
+	if len(r) == 0 {
+		return ErrNoRows
+	}
+	t := reflect.TypeOf(pointer)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Map {
+		return fmt.Errorf("pointer should be type of pointer to map, but got: %T", pointer)
+	}
+	var (
+		mapType  = t.Elem()
+		itemType = mapType.Elem()
+		m        = reflect.MakeMapWithSize(mapType, len(r))
+	)
+	for _, item := range r {
+		v, ok := item[key]
+		if !ok {
+			return fmt.Errorf(`key column "%s" is not found in the result`, key)
+		}
+		k := reflect.New(mapType.Key())
+		if err = scanValue(k.Interface(), v.Val()); err != nil {
+			return err
+		}
+		if itemType.Kind() == reflect.Ptr {
+			e := reflect.New(itemType.Elem()).Elem()
+			if err = item.Struct(e); err != nil {
+				return err
+			}
+			m.SetMapIndex(k.Elem(), e.Addr())
+		} else {
+			e := reflect.New(itemType).Elem()
+			if err = item.Struct(e); err != nil {
+				return err
+			}
+			m.SetMapIndex(k.Elem(), e)
+		}
+	}
+	reflect.ValueOf(pointer).Elem().Set(m)
+	return nil
+}
+
 // IsEmpty checks and returns whether <r> is empty.
 func (r Result) IsEmpty() bool {
 	return len(r) == 0
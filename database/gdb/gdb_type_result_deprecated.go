@@ -7,7 +7,6 @@
 package gdb
 
 import (
-	"database/sql"
 	"fmt"
 	"reflect"
 
@@ -105,7 +104,7 @@ func (r Result) ToUintRecord(key string) map[uint]Record {
 func (r Result) ToStructs(pointer interface{}) (err error) {
 	l := len(r)
 	if l == 0 {
-		return sql.ErrNoRows
+		return ErrNoRows
 	}
 	t := reflect.TypeOf(pointer)
 	if t.Kind() != reflect.Ptr {
@@ -7,8 +7,12 @@
 package gdb_test
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"github.com/gogf/gf/container/garray"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -19,6 +23,8 @@ import (
 	"github.com/gogf/gf/frame/g"
 	"github.com/gogf/gf/os/gtime"
 	"github.com/gogf/gf/test/gtest"
+	"github.com/gogf/gf/text/gstr"
+	"github.com/gogf/gf/util/gconv"
 )
 
 func Test_DB_Ping(t *testing.T) {
@@ -28,6 +34,231 @@ func Test_DB_Ping(t *testing.T) {
 		gtest.Assert(err1, nil)
 		gtest.Assert(err2, nil)
 	})
+	gtest.Case(t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		gtest.Assert(db.PingMasterContext(ctx), nil)
+		gtest.Assert(db.PingSlaveContext(ctx), nil)
+
+		ctx, cancel = context.WithCancel(context.Background())
+		cancel()
+		gtest.Assert(db.PingMasterContext(ctx), context.Canceled)
+		gtest.Assert(db.PingSlaveContext(ctx), context.Canceled)
+	})
+	gtest.Case(t, func() {
+		db.SetPingTimeout(time.Second)
+		defer db.SetPingTimeout(0)
+		gtest.Assert(db.PingMaster(), nil)
+		gtest.Assert(db.PingSlave(), nil)
+	})
+}
+
+func Test_DB_MasterChecked(t *testing.T) {
+	gtest.Case(t, func() {
+		master, err := db.MasterChecked()
+		gtest.Assert(err, nil)
+		gtest.AssertNE(master, nil)
+		slave, err := db.SlaveChecked()
+		gtest.Assert(err, nil)
+		gtest.AssertNE(slave, nil)
+	})
+	gtest.Case(t, func() {
+		gdb.AddConfigNode("test_unreachable", gdb.ConfigNode{
+			Host: "127.0.0.1",
+			Port: "1",
+			User: "root",
+			Name: "test",
+			Type: "mysql",
+		})
+		unreachable, err := gdb.New("test_unreachable")
+		gtest.Assert(err, nil)
+		unreachable.SetPingTimeout(time.Second)
+		master, err := unreachable.Master()
+		gtest.Assert(err, nil)
+		gtest.AssertNE(master, nil)
+		master, err = unreachable.MasterChecked()
+		gtest.AssertNE(err, nil)
+		gtest.Assert(master, nil)
+		slave, err := unreachable.SlaveChecked()
+		gtest.AssertNE(err, nil)
+		gtest.Assert(slave, nil)
+	})
+}
+
+func Test_DB_Version(t *testing.T) {
+	gtest.Case(t, func() {
+		version, err := db.Version()
+		gtest.Assert(err, nil)
+		gtest.AssertNE(version, "")
+
+		value, err := db.GetValue("SELECT VERSION()")
+		gtest.Assert(err, nil)
+		gtest.Assert(version, value.String())
+
+		// It's cached.
+		collector := db.CatchSql()
+		defer collector.Close()
+		cached, err := db.Version()
+		gtest.Assert(err, nil)
+		gtest.Assert(cached, version)
+		gtest.Assert(len(collector.Retrieve()), 0)
+	})
+}
+
+func Test_DB_ExecOnAll(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		err := db.ExecOnAll(fmt.Sprintf("UPDATE %s SET nickname=? WHERE id=?", table), "T1", 1)
+		gtest.Assert(err, nil)
+		value, err := db.Table(table).Fields("nickname").Where("id", 1).Value()
+		gtest.Assert(err, nil)
+		gtest.Assert(value.String(), "T1")
+	})
+	gtest.Case(t, func() {
+		err := db.ExecOnAll("UPDATE not_exist_table SET nickname='T'")
+		gtest.AssertNE(err, nil)
+		nodeErrors, ok := err.(gdb.NodeErrors)
+		gtest.Assert(ok, true)
+		gtest.Assert(len(nodeErrors), 1)
+		gtest.Assert(gstr.Contains(nodeErrors[0].Node, configNode.Host), true)
+		gtest.Assert(gstr.Contains(nodeErrors[0].Node, configNode.Pass), false)
+		gtest.Assert(gstr.Contains(err.Error(), "not_exist_table"), true)
+	})
+}
+
+func Test_DB_WarmUp(t *testing.T) {
+	gtest.Case(t, func() {
+		err := db.WarmUp(5)
+		gtest.Assert(err, nil)
+		master, err := db.Master()
+		gtest.Assert(err, nil)
+		gtest.Assert(master.Stats().Idle >= 5, true)
+	})
+	// Limited by the max open connection count.
+	gtest.Case(t, func() {
+		err := db.WarmUp(100)
+		gtest.Assert(err, nil)
+		master, err := db.Master()
+		gtest.Assert(err, nil)
+		gtest.Assert(master.Stats().OpenConnections, configNode.MaxOpenConnCount)
+	})
+	gtest.Case(t, func() {
+		gdb.AddConfigNode("test_unreachable", gdb.ConfigNode{
+			Host: "127.0.0.1",
+			Port: "1",
+			User: "root",
+			Name: "test",
+			Type: "mysql",
+		})
+		unreachable, err := gdb.New("test_unreachable")
+		gtest.Assert(err, nil)
+		unreachable.SetPingTimeout(time.Second)
+		gtest.AssertNE(unreachable.WarmUp(2), nil)
+	})
+}
+
+func Test_DB_ClusterHealth(t *testing.T) {
+	gtest.Case(t, func() {
+		healths := db.ClusterHealth()
+		gtest.Assert(len(healths), 1)
+		gtest.Assert(healths[0].Ok, true)
+		gtest.Assert(healths[0].Error, nil)
+		gtest.Assert(healths[0].Role, "master")
+		gtest.Assert(gstr.Contains(healths[0].Node, configNode.Host), true)
+		gtest.Assert(gstr.Contains(healths[0].Node, configNode.Pass), false)
+	})
+	// The unreachable slave node does not fail the others.
+	gtest.Case(t, func() {
+		slave := gdb.ConfigNode{
+			Host: "127.0.0.1",
+			Port: "1",
+			User: "root",
+			Name: "test",
+			Type: "mysql",
+			Role: "slave",
+		}
+		gdb.SetConfigGroup("test_health", gdb.ConfigGroup{configNode, slave})
+		clusterDb, err := gdb.New("test_health")
+		gtest.Assert(err, nil)
+		clusterDb.SetPingTimeout(time.Second)
+		healths := clusterDb.ClusterHealth()
+		gtest.Assert(len(healths), 2)
+		gtest.Assert(healths[0].Ok, true)
+		gtest.Assert(healths[0].Role, "master")
+		gtest.Assert(healths[1].Ok, false)
+		gtest.AssertNE(healths[1].Error, nil)
+		gtest.Assert(healths[1].Role, "slave")
+		gtest.Assert(healths[1].Latency <= 2*time.Second, true)
+	})
+}
+
+func Test_DB_Close(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	// Waiting for the in-flight transaction.
+	gtest.Case(t, func() {
+		closingDb, err := gdb.New()
+		gtest.Assert(err, nil)
+		closingDb.SetSchema(SCHEMA1)
+		tx, err := closingDb.Begin()
+		gtest.Assert(err, nil)
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			// The statements of the in-flight transaction are allowed in closing.
+			_, err := tx.Update(table, g.Map{"nickname": "closing"}, "id=?", 1)
+			gtest.Assert(err, nil)
+			gtest.Assert(tx.Commit(), nil)
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		start := time.Now()
+		gtest.Assert(closingDb.Close(ctx), nil)
+		gtest.Assert(time.Since(start) >= 200*time.Millisecond, true)
+
+		_, err = closingDb.GetAll(fmt.Sprintf("SELECT * FROM %s", table))
+		gtest.Assert(err, gdb.ErrClosed)
+		_, err = closingDb.Begin()
+		gtest.Assert(err, gdb.ErrClosed)
+
+		value, err := db.GetValue(fmt.Sprintf("SELECT nickname FROM %s WHERE id=?", table), 1)
+		gtest.Assert(err, nil)
+		gtest.Assert(value.String(), "closing")
+	})
+	// Timeout.
+	gtest.Case(t, func() {
+		closingDb, err := gdb.New()
+		gtest.Assert(err, nil)
+		tx, err := closingDb.Begin()
+		gtest.Assert(err, nil)
+		defer tx.Rollback()
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		gtest.Assert(closingDb.Close(ctx), context.DeadlineExceeded)
+	})
+	// Waiting for the rows returned by Query being closed.
+	gtest.Case(t, func() {
+		closingDb, err := gdb.New()
+		gtest.Assert(err, nil)
+		closingDb.SetSchema(SCHEMA1)
+		rows, err := closingDb.Query(fmt.Sprintf("SELECT * FROM %s", table))
+		gtest.Assert(err, nil)
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			count := 0
+			for rows.Next() {
+				count++
+			}
+			gtest.Assert(rows.Err(), nil)
+			gtest.Assert(count, SIZE)
+			gtest.Assert(rows.Close(), nil)
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		start := time.Now()
+		gtest.Assert(closingDb.Close(ctx), nil)
+		gtest.Assert(time.Since(start) >= 200*time.Millisecond, true)
+	})
 }
 
 func Test_DB_Query(t *testing.T) {
@@ -58,6 +289,189 @@ func Test_DB_Exec(t *testing.T) {
 
 }
 
+func Test_DB_SqlHistory(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		gtest.Assert(db.LastSql(), nil)
+		db.SetSqlHistory(3)
+		defer db.SetSqlHistory(0)
+		for i := 1; i <= 5; i++ {
+			_, err := db.GetOne(fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), i)
+			gtest.Assert(err, nil)
+		}
+		last := db.LastSql()
+		gtest.AssertNE(last, nil)
+		gtest.Assert(last.Args, g.Slice{5})
+		gtest.Assert(last.Format, fmt.Sprintf("SELECT * FROM %s WHERE id=5", table))
+
+		sqls := db.RecentSqls(10)
+		gtest.Assert(len(sqls), 3)
+		gtest.Assert(sqls[0].Args, g.Slice{3})
+		gtest.Assert(sqls[2].Args, g.Slice{5})
+
+		sqls = db.RecentSqls(2)
+		gtest.Assert(len(sqls), 2)
+		gtest.Assert(sqls[0].Args, g.Slice{4})
+
+		_, err := db.Exec(fmt.Sprintf("DELETE FROM %s WHERE id=?", table), -1)
+		gtest.Assert(err, nil)
+		gtest.Assert(db.LastSql().Args, g.Slice{-1})
+	})
+	gtest.Case(t, func() {
+		db.SetSqlHistory(0)
+		_, err := db.GetOne(fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), 1)
+		gtest.Assert(err, nil)
+		gtest.Assert(db.LastSql(), nil)
+		gtest.Assert(len(db.RecentSqls(1)), 0)
+	})
+}
+
+func Test_DB_SqlComment(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		collector := db.CatchSql()
+		defer collector.Close()
+		commentDb := db.WithSqlComment("service=orders,route=/checkout")
+		// The comment is visible in the process list.
+		value, err := commentDb.GetValue("SELECT INFO FROM information_schema.PROCESSLIST WHERE ID=CONNECTION_ID()")
+		gtest.Assert(err, nil)
+		gtest.Assert(gstr.HasPrefix(value.String(), "/* service=orders,route=/checkout */ SELECT"), true)
+
+		_, err = commentDb.Table(table).Data(g.Map{"nickname": "comment"}).Where("id", 1).Update()
+		gtest.Assert(err, nil)
+		_, err = db.GetAll(fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), 1)
+		gtest.Assert(err, nil)
+
+		sqls := collector.Retrieve()
+		gtest.Assert(len(sqls) >= 3, true)
+		last := sqls[len(sqls)-1]
+		gtest.Assert(last.Sql, fmt.Sprintf("SELECT * FROM %s WHERE id=?", table))
+		gtest.Assert(gstr.HasPrefix(sqls[len(sqls)-2].Sql, "/* service=orders,route=/checkout */ UPDATE"), true)
+	})
+	gtest.Case(t, func() {
+		db.SetSqlComment("service=orders")
+		defer db.SetSqlComment("")
+		value, err := db.GetValue("SELECT INFO FROM information_schema.PROCESSLIST WHERE ID=CONNECTION_ID()")
+		gtest.Assert(err, nil)
+		gtest.Assert(gstr.HasPrefix(value.String(), "/* service=orders */ SELECT"), true)
+	})
+}
+
+func Test_DB_CatchSql(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		collector := db.CatchSql()
+		defer collector.Close()
+		_, err := db.Exec(fmt.Sprintf("UPDATE %s SET nickname=? WHERE id=?", table), "name_100", 1)
+		gtest.Assert(err, nil)
+		_, err = db.GetAll(fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), 1)
+		gtest.Assert(err, nil)
+		_, err = db.Exec("ERROR")
+		gtest.AssertNE(err, nil)
+
+		sqls := collector.Retrieve()
+		gtest.Assert(len(sqls), 3)
+		gtest.Assert(sqls[0].Sql, fmt.Sprintf("UPDATE %s SET nickname=? WHERE id=?", table))
+		gtest.Assert(sqls[0].Args, g.Slice{"name_100", 1})
+		gtest.Assert(sqls[0].Error, nil)
+		gtest.Assert(sqls[1].Format, fmt.Sprintf("SELECT * FROM %s WHERE id=1", table))
+		gtest.AssertNE(sqls[2].Error, nil)
+
+		collector.Clear()
+		gtest.Assert(len(collector.Retrieve()), 0)
+		collector.Close()
+		_, err = db.GetAll(fmt.Sprintf("SELECT * FROM %s", table))
+		gtest.Assert(err, nil)
+		gtest.Assert(len(collector.Retrieve()), 0)
+	})
+	gtest.Case(t, func() {
+		collector := db.CatchSql()
+		defer collector.Close()
+		wg := sync.WaitGroup{}
+		for i := 1; i <= SIZE; i++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				db.GetOne(fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), id)
+			}(i)
+		}
+		wg.Wait()
+		gtest.Assert(len(collector.Retrieve()), SIZE)
+	})
+}
+
+func Test_DB_SetSensitiveColumns(t *testing.T) {
+	table := createTable()
+	defer dropTable(table)
+	db.SetSensitiveColumns("password")
+	defer db.SetSensitiveColumns()
+	gtest.Case(t, func() {
+		// The table fields might be retrieved for the data converting, which are cached here.
+		_, err := db.TableFields(table)
+		gtest.Assert(err, nil)
+
+		collector := db.CatchSql()
+		defer collector.Close()
+		_, err = db.Insert(table, g.Map{"id": 1, "passport": "user_1", "password": "pass_1"})
+		gtest.Assert(err, nil)
+		_, err = db.Update(table, g.Map{"password": "pass_2"}, "id=?", 1)
+		gtest.Assert(err, nil)
+		one, err := db.GetOne(fmt.Sprintf("SELECT * FROM %s WHERE passport=? AND password=?", table), "user_1", gdb.Sensitive("pass_2"))
+		gtest.Assert(err, nil)
+		gtest.Assert(one["password"].String(), "pass_2")
+
+		sqls := collector.Retrieve()
+		gtest.Assert(len(sqls), 3)
+		for _, s := range sqls {
+			gtest.Assert(gstr.Contains(s.Format, "pass_"), false)
+			gtest.Assert(gstr.Contains(s.Format, "'***'"), true)
+		}
+		gtest.Assert(gstr.Contains(sqls[0].Format, "'user_1'"), true)
+	})
+	gtest.Case(t, func() {
+		// The error message does not contain the sensitive value either.
+		_, err := db.Insert(table, g.Map{"id": 1, "passport": "user_1", "password": "pass_1"})
+		gtest.AssertNE(err, nil)
+		gtest.Assert(gstr.Contains(err.Error(), "pass_1"), false)
+	})
+}
+
+func Test_DB_ExecMulti(t *testing.T) {
+	table := createTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		// The session variable is available as all statements are executed on one connection.
+		err := db.ExecMulti(fmt.Sprintf(`
+SET @gf_nickname='name;1';
+INSERT INTO %s(id,passport,nickname) VALUES(1,'user_1',@gf_nickname);
+-- INSERT INTO %s(id,passport) VALUES(100,'user_100');
+INSERT INTO %s(id,passport) VALUES(2,'user_2');
+`, table, table, table))
+		gtest.Assert(err, nil)
+
+		result, err := db.GetAll(fmt.Sprintf("SELECT * FROM %s ORDER BY id", table))
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), 2)
+		gtest.Assert(result[0]["nickname"].String(), "name;1")
+		gtest.Assert(result[1]["passport"].String(), "user_2")
+	})
+	gtest.Case(t, func() {
+		err := db.ExecMulti(fmt.Sprintf(
+			"INSERT INTO %s(id,passport) VALUES(3,'user_3');ERROR;INSERT INTO %s(id,passport) VALUES(4,'user_4');",
+			table, table,
+		))
+		gtest.AssertNE(err, nil)
+		gtest.Assert(gstr.Contains(err.Error(), "statement 2"), true)
+
+		ids, err := db.GetInts(fmt.Sprintf("SELECT id FROM %s WHERE id>2 ORDER BY id", table))
+		gtest.Assert(err, nil)
+		gtest.Assert(ids, []int{3})
+	})
+}
+
 func Test_DB_Prepare(t *testing.T) {
 	gtest.Case(t, func() {
 		st, err := db.Prepare("SELECT 100")
@@ -73,6 +487,14 @@ func Test_DB_Prepare(t *testing.T) {
 		err = rows.Close()
 		gtest.Assert(err, nil)
 	})
+	// The error contains the failed statement.
+	gtest.Case(t, func() {
+		_, err := db.Prepare("SELECT * FROM table_not_exists WHERE id=?")
+		gtest.AssertNE(err, nil)
+		gtest.Assert(err.(*gdb.Error).Sql(), "SELECT * FROM table_not_exists WHERE id=?")
+		gtest.AssertNE(err.(*gdb.Error).Unwrap(), nil)
+		gtest.Assert(err.(*gdb.Error).Code(), "1146")
+	})
 }
 
 func Test_DB_Insert(t *testing.T) {
@@ -279,6 +701,41 @@ func Test_DB_BatchInsert(t *testing.T) {
 
 }
 
+func Test_DB_BatchInsertTx(t *testing.T) {
+	table := createTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		list := g.List{}
+		for i := 1; i <= SIZE; i++ {
+			list = append(list, g.Map{
+				"id":          i,
+				"passport":    fmt.Sprintf(`user_%d`, i),
+				"password":    fmt.Sprintf(`pass_%d`, i),
+				"nickname":    fmt.Sprintf(`name_%d`, i),
+				"create_time": gtime.Now().String(),
+			})
+		}
+		// The last chunk conflicts with the first record.
+		list = append(list, list[0])
+		_, err := db.BatchInsertTx(table, list, 2)
+		gtest.AssertNE(err, nil)
+		gtest.Assert(gdb.IsUniqueViolation(err), true)
+
+		count, err := db.Table(table).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 0)
+
+		r, err := db.BatchInsertTx(table, list[:SIZE], 2)
+		gtest.Assert(err, nil)
+		n, _ := r.RowsAffected()
+		gtest.Assert(n, SIZE)
+
+		count, err = db.Table(table).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, SIZE)
+	})
+}
+
 func Test_DB_BatchInsert_Struct(t *testing.T) {
 	// batch insert struct
 	gtest.Case(t, func() {
@@ -304,6 +761,35 @@ func Test_DB_BatchInsert_Struct(t *testing.T) {
 		n, _ := result.RowsAffected()
 		gtest.Assert(n, 1)
 	})
+	// batch insert struct slice with tag renamed columns
+	gtest.Case(t, func() {
+		table := createTable()
+		defer dropTable(table)
+
+		type User struct {
+			Id       int    `gdb:"id"`
+			Passport string `gdb:"passport"`
+			Password string `gdb:"password"`
+			Name     string `gdb:"nickname"`
+		}
+		users := []User{
+			{Id: 1, Passport: "t1", Password: "p1", Name: "T1"},
+			{Id: 2, Passport: "t2", Password: "p2", Name: "T2"},
+		}
+		result, err := db.BatchInsert(table, users)
+		gtest.Assert(err, nil)
+		n, _ := result.RowsAffected()
+		gtest.Assert(n, 2)
+
+		one, err := db.Table(table).Where("id", 2).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["nickname"].String(), "T2")
+
+		var user *User
+		err = db.Table(table).Where("id", 1).Struct(&user)
+		gtest.Assert(err, nil)
+		gtest.Assert(user.Name, "T1")
+	})
 }
 
 func Test_DB_Save(t *testing.T) {
@@ -331,6 +817,103 @@ func Test_DB_Save(t *testing.T) {
 	})
 }
 
+func Test_DB_InsertOnConflict(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		_, err := db.InsertOnConflict(table, g.List{
+			{"id": 1, "passport": "t1", "nickname": "T1"},
+			{"id": SIZE + 1, "passport": "t11", "nickname": "T11"},
+		}, []string{"id"})
+		gtest.Assert(err, nil)
+
+		one, err := db.Table(table).Where("id", 1).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["passport"].String(), "t1")
+		gtest.Assert(one["nickname"].String(), "T1")
+		gtest.Assert(one["password"].String(), "pass_1")
+
+		n, err := db.Table(table).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(n, SIZE+1)
+	})
+}
+
+func Test_DB_InsertSelect(t *testing.T) {
+	table1 := createInitTable()
+	table2 := createTable()
+	defer dropTable(table1)
+	defer dropTable(table2)
+	gtest.Case(t, func() {
+		r, err := db.InsertSelect(
+			table2,
+			[]string{"id", "passport", "nickname"},
+			fmt.Sprintf("SELECT id, passport, nickname FROM %s WHERE id<=?", table1),
+			3,
+		)
+		gtest.Assert(err, nil)
+		n, _ := r.RowsAffected()
+		gtest.Assert(n, 3)
+
+		one, err := db.Table(table2).Where("id", 3).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["passport"].String(), "user_3")
+		gtest.Assert(one["nickname"].String(), "name_3")
+		gtest.Assert(one["password"].IsNil(), true)
+	})
+	gtest.Case(t, func() {
+		err := db.Transaction(func(tx *gdb.TX) error {
+			r, err := tx.InsertSelect(table2, nil, fmt.Sprintf("SELECT * FROM %s WHERE id>?", table1), SIZE-2)
+			gtest.Assert(err, nil)
+			n, _ := r.RowsAffected()
+			gtest.Assert(n, 2)
+			return err
+		})
+		gtest.Assert(err, nil)
+
+		count, err := db.Table(table2).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 5)
+	})
+	gtest.Case(t, func() {
+		_, err := db.InsertSelect(table2, []string{"id"}, " ")
+		gtest.AssertNE(err, nil)
+	})
+}
+
+func Test_DB_SaveAndGetStatus(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		status, err := db.SaveAndGetStatus(table, g.Map{
+			"id":       SIZE + 1,
+			"passport": "t11",
+			"password": "p11",
+			"nickname": "T11",
+		})
+		gtest.Assert(err, nil)
+		gtest.Assert(status, gdb.SAVE_STATUS_INSERTED)
+
+		status, err = db.SaveAndGetStatus(table, g.Map{
+			"id":       SIZE + 1,
+			"nickname": "T11_NEW",
+		})
+		gtest.Assert(err, nil)
+		gtest.Assert(status, gdb.SAVE_STATUS_UPDATED)
+
+		status, err = db.SaveAndGetStatus(table, g.Map{
+			"id":       SIZE + 1,
+			"nickname": "T11_NEW",
+		})
+		gtest.Assert(err, nil)
+		gtest.Assert(status, gdb.SAVE_STATUS_UNCHANGED)
+	})
+	gtest.Case(t, func() {
+		_, err := db.SaveAndGetStatus(table, g.List{g.Map{"id": 1}})
+		gtest.AssertNE(err, nil)
+	})
+}
+
 func Test_DB_Replace(t *testing.T) {
 	table := createInitTable()
 	defer dropTable(table)
@@ -356,6 +939,29 @@ func Test_DB_Replace(t *testing.T) {
 	})
 }
 
+func Test_DB_Insert_UniqueViolation(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		_, err := db.Insert(table, g.Map{
+			"id":       1,
+			"passport": "user_1",
+		})
+		gtest.AssertNE(err, nil)
+		gtest.Assert(gdb.IsUniqueViolation(err), true)
+		gtest.Assert(gdb.IsNotNullViolation(err), false)
+		gtest.Assert(err.(*gdb.Error).Code(), "1062")
+		gtest.Assert(gstr.Contains(err.Error(), "user_1"), true)
+		gtest.AssertNE(err.(*gdb.Error).Unwrap(), nil)
+
+		_, err = db.Insert(table, g.Map{
+			"id":       SIZE + 1,
+			"passport": "user_11",
+		})
+		gtest.Assert(err, nil)
+	})
+}
+
 func Test_DB_Update(t *testing.T) {
 	table := createInitTable()
 	defer dropTable(table)
@@ -373,9 +979,167 @@ func Test_DB_Update(t *testing.T) {
 		gtest.Assert(one["password"].String(), "987654321")
 		gtest.Assert(one["nickname"].String(), "name_3")
 	})
-}
-
-func Test_DB_GetAll(t *testing.T) {
+	gtest.Case(t, func() {
+		result, err := db.Update(table, g.Map{"password": "123", "nickname": nil}, "id=4")
+		gtest.Assert(err, nil)
+		n, _ := result.RowsAffected()
+		gtest.Assert(n, 1)
+
+		one, err := db.Table(table).Where("id", 4).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["password"].String(), "123")
+		gtest.Assert(one["nickname"].IsNil(), true)
+		gtest.Assert(one["passport"].String(), "user_4")
+	})
+}
+
+func Test_DB_Raw(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+
+	gtest.Case(t, func() {
+		result, err := db.Update(table, g.Map{"nickname": gdb.Raw("CONCAT(passport, '_', id)")}, "id<=?", 2)
+		gtest.Assert(err, nil)
+		n, _ := result.RowsAffected()
+		gtest.Assert(n, 2)
+
+		one, err := db.Table(table).Where("id", 2).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["nickname"].String(), "user_2_2")
+	})
+	gtest.Case(t, func() {
+		_, err := db.Insert(table, g.Map{
+			"id":          SIZE + 1,
+			"passport":    "raw",
+			"nickname":    gdb.Raw("UPPER('raw')"),
+			"create_time": gdb.Raw("NOW()"),
+		})
+		gtest.Assert(err, nil)
+		_, err = db.BatchInsert(table, g.List{
+			{"id": SIZE + 2, "passport": "raw_2", "nickname": gdb.Raw("CONCAT('raw', '_', 2)")},
+			{"id": SIZE + 3, "passport": "raw_3", "nickname": "raw_3"},
+		})
+		gtest.Assert(err, nil)
+
+		result, err := db.Table(table).Where("id>?", SIZE).Order("id").All()
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), 3)
+		gtest.Assert(result[0]["nickname"].String(), "RAW")
+		gtest.Assert(result[0]["create_time"].IsEmpty(), false)
+		gtest.Assert(result[1]["nickname"].String(), "raw_2")
+		gtest.Assert(result[2]["nickname"].String(), "raw_3")
+	})
+}
+
+func Test_DB_DryRun(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+
+	gtest.Case(t, func() {
+		s, err := db.UpdateSql(table, g.Map{"nickname": "T"}, "id=?", 1)
+		gtest.Assert(err, nil)
+		gtest.Assert(s.Sql, fmt.Sprintf("UPDATE `%s` SET `nickname`=? WHERE id=?", table))
+		gtest.Assert(s.Args, g.Slice{"T", 1})
+		gtest.Assert(s.Format, fmt.Sprintf("UPDATE `%s` SET `nickname`='T' WHERE id=1", table))
+
+		s, err = db.DeleteSql(table, g.Map{"id": 2})
+		gtest.Assert(err, nil)
+		gtest.Assert(s.Sql, fmt.Sprintf("DELETE FROM `%s` WHERE `id`=?", table))
+		gtest.Assert(s.Args, g.Slice{2})
+
+		sqls, err := db.InsertSql(table, g.List{
+			{"id": SIZE + 1, "passport": "t1"},
+			{"id": SIZE + 2, "passport": "t2"},
+			{"id": SIZE + 3, "passport": "t3"},
+		}, 2)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(sqls), 2)
+		gtest.Assert(len(sqls[0].Args), 4)
+		gtest.Assert(len(sqls[1].Args), 2)
+		gtest.Assert(gstr.HasPrefix(sqls[0].Sql, fmt.Sprintf("INSERT INTO `%s`(", table)), true)
+
+		// The columns are sorted for the same statement.
+		for i := 0; i < 10; i++ {
+			sqls, err = db.InsertSql(table, g.Map{"password": "p", "id": SIZE + 1, "passport": "t1", "nickname": "n"})
+			gtest.Assert(err, nil)
+			gtest.Assert(sqls[0].Sql, fmt.Sprintf("INSERT INTO `%s`(`id`,`nickname`,`passport`,`password`) VALUES(?,?,?,?) ", table))
+			gtest.Assert(sqls[0].Args, g.Slice{SIZE + 1, "n", "t1", "p"})
+
+			s, err = db.UpdateSql(table, g.Map{"password": "p", "passport": "t1", "nickname": "n"}, "id", 1)
+			gtest.Assert(err, nil)
+			gtest.Assert(s.Sql, fmt.Sprintf("UPDATE `%s` SET `nickname`=?,`passport`=?,`password`=? WHERE `id`=?", table))
+		}
+
+		// Nothing is changed.
+		count, err := db.Table(table).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, SIZE)
+		one, err := db.Table(table).Where("id", 1).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["nickname"].String(), "name_1")
+	})
+}
+
+func Test_DB_BatchUpdate(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		result, err := db.BatchUpdate(table, "nickname", "id", g.MapIntStr{
+			1: "T1",
+			2: "T2",
+			3: "T3",
+		}, 2)
+		gtest.Assert(err, nil)
+		n, _ := result.RowsAffected()
+		gtest.Assert(n, 3)
+
+		all, err := db.Table(table).Where("id<=?", 4).Order("id").All()
+		gtest.Assert(err, nil)
+		gtest.Assert(all[0]["nickname"].String(), "T1")
+		gtest.Assert(all[1]["nickname"].String(), "T2")
+		gtest.Assert(all[2]["nickname"].String(), "T3")
+		gtest.Assert(all[3]["nickname"].String(), "name_4")
+	})
+	gtest.Case(t, func() {
+		_, err := db.BatchUpdate(table, "nickname", "id", g.Map{})
+		gtest.AssertNE(err, nil)
+	})
+}
+
+func Test_DB_ProtectFullTableOps(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	db.SetProtectFullTableOps(true)
+	defer db.SetProtectFullTableOps(false)
+	gtest.Case(t, func() {
+		_, err := db.Update(table, "nickname='T'", nil)
+		gtest.AssertNE(err, nil)
+		_, err = db.Delete(table, "")
+		gtest.AssertNE(err, nil)
+		_, err = db.Table(table).Data("nickname='T'").Update()
+		gtest.AssertNE(err, nil)
+		_, err = db.Table(table).Delete()
+		gtest.AssertNE(err, nil)
+	})
+	gtest.Case(t, func() {
+		result, err := db.Update(table, "nickname='T'", "1=1")
+		gtest.Assert(err, nil)
+		n, _ := result.RowsAffected()
+		gtest.Assert(n, SIZE)
+
+		result, err = db.Table(table).AllowFullTable().Data("nickname='N'").Update()
+		gtest.Assert(err, nil)
+		n, _ = result.RowsAffected()
+		gtest.Assert(n, SIZE)
+
+		result, err = db.Table(table).AllowFullTable().Delete()
+		gtest.Assert(err, nil)
+		n, _ = result.RowsAffected()
+		gtest.Assert(n, SIZE)
+	})
+}
+
+func Test_DB_GetAll(t *testing.T) {
 	table := createInitTable()
 	defer dropTable(table)
 
@@ -385,6 +1149,16 @@ func Test_DB_GetAll(t *testing.T) {
 		gtest.Assert(len(result), 1)
 		gtest.Assert(result[0]["id"].Int(), 1)
 	})
+	gtest.Case(t, func() {
+		result, err := db.GetAll(
+			fmt.Sprintf("SELECT * FROM %s WHERE id IN (?) AND passport IN (?) AND nickname<>'?' ORDER BY id", table),
+			g.Slice{1, 2, 3}, g.Slice{"user_2", "user_3", "user_4"},
+		)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), 2)
+		gtest.Assert(result[0]["id"].Int(), 2)
+		gtest.Assert(result[1]["id"].Int(), 3)
+	})
 	gtest.Case(t, func() {
 		result, err := db.GetAll(fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), g.Slice{1})
 		gtest.Assert(err, nil)
@@ -435,233 +1209,1367 @@ func Test_DB_GetOne(t *testing.T) {
 	})
 }
 
-func Test_DB_GetValue(t *testing.T) {
+func Test_DB_GetOneOrErr(t *testing.T) {
 	table := createInitTable()
 	defer dropTable(table)
 	gtest.Case(t, func() {
-		value, err := db.GetValue(fmt.Sprintf("SELECT id FROM %s WHERE passport=?", table), "user_3")
+		record, err := db.GetOneOrErr(fmt.Sprintf("SELECT * FROM %s WHERE passport=?", table), "user_1")
 		gtest.Assert(err, nil)
-		gtest.Assert(value.Int(), 3)
+		gtest.Assert(record["nickname"].String(), "name_1")
+
+		record, err = db.GetOneOrErr(fmt.Sprintf("SELECT * FROM %s WHERE passport=?", table), "none")
+		gtest.Assert(gdb.IsNoRows(err), true)
+		gtest.Assert(record, nil)
+
+		record, err = db.GetOne(fmt.Sprintf("SELECT * FROM %s WHERE passport=?", table), "none")
+		gtest.Assert(err, nil)
+		gtest.Assert(record, nil)
+	})
+	gtest.Case(t, func() {
+		tx, err := db.Begin()
+		gtest.Assert(err, nil)
+		defer tx.Rollback()
+		_, err = tx.GetOneOrErr(fmt.Sprintf("SELECT * FROM %s WHERE passport=?", table), "none")
+		gtest.Assert(err, gdb.ErrNoRows)
 	})
 }
 
-func Test_DB_GetCount(t *testing.T) {
+func Test_DB_GetValue(t *testing.T) {
 	table := createInitTable()
 	defer dropTable(table)
 	gtest.Case(t, func() {
-		count, err := db.GetCount(fmt.Sprintf("SELECT * FROM %s", table))
+		value, err := db.GetValue(fmt.Sprintf("SELECT id FROM %s WHERE passport=?", table), "user_3")
 		gtest.Assert(err, nil)
-		gtest.Assert(count, SIZE)
+		gtest.Assert(value.Int(), 3)
 	})
 }
 
-func Test_DB_GetStruct(t *testing.T) {
+func Test_DB_GetScalar(t *testing.T) {
 	table := createInitTable()
 	defer dropTable(table)
 	gtest.Case(t, func() {
-		type User struct {
-			Id         int
-			Passport   string
-			Password   string
-			NickName   string
-			CreateTime gtime.Time
-		}
-		user := new(User)
-		err := db.GetStruct(user, fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), 3)
+		var count int
+		err := db.GetScalar(&count, fmt.Sprintf("SELECT COUNT(*) FROM %s", table))
 		gtest.Assert(err, nil)
-		gtest.Assert(user.NickName, "name_3")
+		gtest.Assert(count, SIZE)
+
+		var nickname string
+		err = db.GetScalar(&nickname, fmt.Sprintf("SELECT nickname FROM %s WHERE id=?", table), 2)
+		gtest.Assert(err, nil)
+		gtest.Assert(nickname, "name_2")
+
+		var createTime time.Time
+		err = db.GetScalar(&createTime, fmt.Sprintf("SELECT create_time FROM %s WHERE id=?", table), 2)
+		gtest.Assert(err, nil)
+		gtest.Assert(createTime.IsZero(), false)
 	})
 	gtest.Case(t, func() {
-		type User struct {
-			Id         int
-			Passport   string
-			Password   string
-			NickName   string
-			CreateTime *gtime.Time
-		}
-		user := new(User)
-		err := db.GetStruct(user, fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), 3)
+		var nickname string
+		err := db.GetScalar(&nickname, fmt.Sprintf("SELECT nickname FROM %s WHERE id=?", table), -1)
+		gtest.Assert(err, gdb.ErrNoRows)
+
+		var max *int
+		err = db.GetScalar(&max, fmt.Sprintf("SELECT MAX(id) FROM %s WHERE id<0", table))
 		gtest.Assert(err, nil)
-		gtest.Assert(user.NickName, "name_3")
+		gtest.Assert(max == nil, true)
+
+		err = db.GetScalar(nickname, fmt.Sprintf("SELECT nickname FROM %s WHERE id=?", table), 1)
+		gtest.AssertNE(err, nil)
+	})
+	gtest.Case(t, func() {
+		tx, err := db.Begin()
+		gtest.Assert(err, nil)
+		defer tx.Rollback()
+		var id int64
+		err = tx.GetScalar(&id, fmt.Sprintf("SELECT id FROM %s WHERE passport=?", table), "user_3")
+		gtest.Assert(err, nil)
+		gtest.Assert(id, 3)
 	})
 }
 
-func Test_DB_GetStructs(t *testing.T) {
+func Test_DB_GetCount(t *testing.T) {
 	table := createInitTable()
 	defer dropTable(table)
 	gtest.Case(t, func() {
-		type User struct {
-			Id         int
-			Passport   string
-			Password   string
-			NickName   string
-			CreateTime gtime.Time
-		}
-		var users []User
-		err := db.GetStructs(&users, fmt.Sprintf("SELECT * FROM %s WHERE id>?", table), 1)
+		count, err := db.GetCount(fmt.Sprintf("SELECT * FROM %s", table))
 		gtest.Assert(err, nil)
-		gtest.Assert(len(users), SIZE-1)
-		gtest.Assert(users[0].Id, 2)
-		gtest.Assert(users[1].Id, 3)
-		gtest.Assert(users[2].Id, 4)
-		gtest.Assert(users[0].NickName, "name_2")
-		gtest.Assert(users[1].NickName, "name_3")
-		gtest.Assert(users[2].NickName, "name_4")
+		gtest.Assert(count, SIZE)
 	})
+}
 
+func Test_DB_Explain(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
 	gtest.Case(t, func() {
-		type User struct {
-			Id         int
-			Passport   string
-			Password   string
-			NickName   string
-			CreateTime *gtime.Time
-		}
-		var users []User
-		err := db.GetStructs(&users, fmt.Sprintf("SELECT * FROM %s WHERE id>?", table), 1)
+		result, err := db.Explain(fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), 1)
 		gtest.Assert(err, nil)
-		gtest.Assert(len(users), SIZE-1)
-		gtest.Assert(users[0].Id, 2)
-		gtest.Assert(users[1].Id, 3)
-		gtest.Assert(users[2].Id, 4)
-		gtest.Assert(users[0].NickName, "name_2")
-		gtest.Assert(users[1].NickName, "name_3")
-		gtest.Assert(users[2].NickName, "name_4")
+		gtest.Assert(len(result), 1)
+		gtest.Assert(result[0]["table"].String(), table)
+
+		// The DML statement is not executed.
+		result, err = db.Explain(fmt.Sprintf("DELETE FROM %s WHERE id>?", table), 0)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result) > 0, true)
+		count, err := db.CountTable(table, nil)
+		gtest.Assert(err, nil)
+		gtest.Assert(count, SIZE)
+
+		_, err = db.Explain(fmt.Sprintf("DROP TABLE %s", table))
+		gtest.AssertNE(err, nil)
+		exists, err := db.TableExists(table)
+		gtest.Assert(err, nil)
+		gtest.Assert(exists, true)
 	})
 }
 
-func Test_DB_GetScan(t *testing.T) {
+func Test_DB_CountTable(t *testing.T) {
 	table := createInitTable()
 	defer dropTable(table)
 	gtest.Case(t, func() {
-		type User struct {
-			Id         int
-			Passport   string
-			Password   string
-			NickName   string
-			CreateTime gtime.Time
-		}
-		user := new(User)
-		err := db.GetScan(user, fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), 3)
+		count, err := db.CountTable(table, nil)
 		gtest.Assert(err, nil)
-		gtest.Assert(user.NickName, "name_3")
-	})
-	gtest.Case(t, func() {
-		type User struct {
-			Id         int
-			Passport   string
-			Password   string
-			NickName   string
-			CreateTime *gtime.Time
-		}
-		user := new(User)
-		err := db.GetScan(user, fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), 3)
+		gtest.Assert(count, SIZE)
+
+		count, err = db.CountTable(table, "id>?", 3)
 		gtest.Assert(err, nil)
-		gtest.Assert(user.NickName, "name_3")
-	})
+		gtest.Assert(count, SIZE-3)
 
-	gtest.Case(t, func() {
-		type User struct {
-			Id         int
-			Passport   string
-			Password   string
-			NickName   string
-			CreateTime gtime.Time
-		}
-		var users []User
-		err := db.GetScan(&users, fmt.Sprintf("SELECT * FROM %s WHERE id>?", table), 1)
+		count, err = db.CountTable(table, g.Map{"passport": "user_1"})
 		gtest.Assert(err, nil)
-		gtest.Assert(len(users), SIZE-1)
-		gtest.Assert(users[0].Id, 2)
-		gtest.Assert(users[1].Id, 3)
-		gtest.Assert(users[2].Id, 4)
-		gtest.Assert(users[0].NickName, "name_2")
-		gtest.Assert(users[1].NickName, "name_3")
-		gtest.Assert(users[2].NickName, "name_4")
-	})
+		gtest.Assert(count, 1)
 
-	gtest.Case(t, func() {
-		type User struct {
-			Id         int
-			Passport   string
-			Password   string
-			NickName   string
-			CreateTime *gtime.Time
-		}
-		var users []User
-		err := db.GetScan(&users, fmt.Sprintf("SELECT * FROM %s WHERE id>?", table), 1)
+		count, err = db.CountTable(table, "id IN(?)", g.Slice{1, 2, 100})
 		gtest.Assert(err, nil)
-		gtest.Assert(len(users), SIZE-1)
-		gtest.Assert(users[0].Id, 2)
-		gtest.Assert(users[1].Id, 3)
-		gtest.Assert(users[2].Id, 4)
-		gtest.Assert(users[0].NickName, "name_2")
-		gtest.Assert(users[1].NickName, "name_3")
-		gtest.Assert(users[2].NickName, "name_4")
+		gtest.Assert(count, 2)
 	})
 }
 
-func Test_DB_Delete(t *testing.T) {
+func Test_DB_CountDistinct(t *testing.T) {
 	table := createInitTable()
 	defer dropTable(table)
 	gtest.Case(t, func() {
-		result, err := db.Delete(table, nil)
+		_, err := db.Update(table, g.Map{"nickname": "name_1"}, "id<=?", 3)
+		gtest.Assert(err, nil)
+		_, err = db.Update(table, g.Map{"nickname": nil}, "id>?", 8)
 		gtest.Assert(err, nil)
-		n, _ := result.RowsAffected()
-		gtest.Assert(n, SIZE)
-	})
-}
 
-func Test_DB_Time(t *testing.T) {
-	table := createTable()
-	defer dropTable(table)
+		count, err := db.CountDistinct(table, "nickname", nil)
+		gtest.Assert(err, nil)
+		// name_1, name_4 ... name_8, the NULL values are not counted.
+		gtest.Assert(count, 6)
+
+		count, err = db.CountDistinct(table, "nickname", "id<=?", 5)
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 3)
+
+		count, err = db.CountDistinct(table, "id", g.Map{"nickname": "name_1"})
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 3)
+	})
+}
+
+func Test_DB_Aggregate(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		value, err := db.Sum(table, "id", nil)
+		gtest.Assert(err, nil)
+		gtest.Assert(value.Int(), SIZE*(SIZE+1)/2)
+
+		value, err = db.Sum(table, "id", "id<=?", 3)
+		gtest.Assert(err, nil)
+		gtest.Assert(value.Int(), 6)
+
+		value, err = db.Avg(table, "id", "id IN(?)", g.Slice{1, 2})
+		gtest.Assert(err, nil)
+		gtest.Assert(value.Float64(), 1.5)
+
+		value, err = db.Min(table, "id", "id>?", 3)
+		gtest.Assert(err, nil)
+		gtest.Assert(value.Int(), 4)
+
+		value, err = db.Max(table, "passport", nil)
+		gtest.Assert(err, nil)
+		gtest.Assert(value.String(), "user_9")
+	})
+	// NULL result for no matched records.
+	gtest.Case(t, func() {
+		value, err := db.Sum(table, "id", "id>?", SIZE)
+		gtest.Assert(err, nil)
+		gtest.Assert(value.IsNil(), true)
+		gtest.Assert(value.Int(), 0)
+		gtest.Assert(value.Float64(), 0)
+
+		value, err = db.Max(table, "id", "id>?", SIZE)
+		gtest.Assert(err, nil)
+		gtest.Assert(value.Int(), 0)
+	})
+}
+
+func Test_DB_Chunk(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		ids := make([]int, 0)
+		pages := 0
+		err := db.Chunk(3, func(result gdb.Result) error {
+			pages++
+			for _, record := range result {
+				ids = append(ids, record["id"].Int())
+			}
+			return nil
+		}, fmt.Sprintf("SELECT * FROM %s WHERE id>? ORDER BY id", table), 1)
+		gtest.Assert(err, nil)
+		gtest.Assert(pages, 3)
+		gtest.Assert(ids, []int{2, 3, 4, 5, 6, 7, 8, 9, 10})
+	})
+	gtest.Case(t, func() {
+		ids := make([]int, 0)
+		pages := 0
+		err := db.ChunkByKey("id", 5, func(result gdb.Result) error {
+			pages++
+			for _, record := range result {
+				ids = append(ids, record["id"].Int())
+			}
+			return nil
+		}, fmt.Sprintf("SELECT id, passport FROM %s WHERE id<>?", table), 3)
+		gtest.Assert(err, nil)
+		gtest.Assert(pages, 2)
+		gtest.Assert(ids, []int{1, 2, 4, 5, 6, 7, 8, 9, 10})
+	})
+	// Stopping by callback error.
+	gtest.Case(t, func() {
+		pages := 0
+		err := db.ChunkByKey("id", 2, func(result gdb.Result) error {
+			pages++
+			if pages == 2 {
+				return fmt.Errorf("stop at page %d", pages)
+			}
+			return nil
+		}, fmt.Sprintf("SELECT * FROM %s", table))
+		gtest.Assert(err.Error(), "stop at page 2")
+		gtest.Assert(pages, 2)
+	})
+	gtest.Case(t, func() {
+		err := db.Chunk(0, func(result gdb.Result) error {
+			return nil
+		}, fmt.Sprintf("SELECT * FROM %s", table))
+		gtest.AssertNE(err, nil)
+		err = db.ChunkByKey("uid", 2, func(result gdb.Result) error {
+			return nil
+		}, fmt.Sprintf("SELECT * FROM %s", table))
+		gtest.AssertNE(err, nil)
+	})
+	gtest.Case(t, func() {
+		tx, err := db.Begin()
+		gtest.Assert(err, nil)
+		defer tx.Rollback()
+		count := 0
+		err = tx.ChunkByKey("id", 4, func(result gdb.Result) error {
+			count += len(result)
+			return nil
+		}, fmt.Sprintf("SELECT * FROM %s", table))
+		gtest.Assert(err, nil)
+		gtest.Assert(count, SIZE)
+	})
+}
+
+func Test_DB_Paginate(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		result, total, err := db.Paginate(fmt.Sprintf("SELECT * FROM %s ORDER BY id", table), 2, 3)
+		gtest.Assert(err, nil)
+		gtest.Assert(total, SIZE)
+		gtest.Assert(len(result), 3)
+		gtest.Assert(result[0]["id"].Int(), 4)
+		gtest.Assert(result[2]["id"].Int(), 6)
+	})
+	gtest.Case(t, func() {
+		result, total, err := db.Paginate(fmt.Sprintf("SELECT * FROM %s WHERE id>? ORDER BY id", table), 1, 3, 8)
+		gtest.Assert(err, nil)
+		gtest.Assert(total, 2)
+		gtest.Assert(len(result), 2)
+		gtest.Assert(result[0]["id"].Int(), 9)
+	})
+	gtest.Case(t, func() {
+		result, total, err := db.Paginate(fmt.Sprintf("SELECT * FROM %s WHERE id>?", table), 1, 3, SIZE)
+		gtest.Assert(err, nil)
+		gtest.Assert(total, 0)
+		gtest.Assert(len(result), 0)
+	})
+	gtest.Case(t, func() {
+		_, _, err := db.Paginate(fmt.Sprintf("SELECT * FROM %s", table), 1, 0)
+		gtest.AssertNE(err, nil)
+	})
+}
+
+func Test_DB_GetArray(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		array, err := db.GetArray(fmt.Sprintf("SELECT nickname, id FROM %s WHERE id<=? ORDER BY id", table), 2)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(array), 2)
+		gtest.Assert(array[0].String(), "name_1")
+		gtest.Assert(array[1].String(), "name_2")
+
+		ints, err := db.GetInts(fmt.Sprintf("SELECT id, nickname FROM %s WHERE id>? ORDER BY id DESC", table), 7)
+		gtest.Assert(err, nil)
+		gtest.Assert(ints, []int{10, 9, 8})
+
+		strs, err := db.GetStrings(fmt.Sprintf("SELECT passport FROM %s WHERE id IN(?) ORDER BY id", table), g.Slice{1, 3})
+		gtest.Assert(err, nil)
+		gtest.Assert(strs, []string{"user_1", "user_3"})
+	})
+	gtest.Case(t, func() {
+		ints, err := db.GetInts(fmt.Sprintf("SELECT id FROM %s WHERE id>?", table), SIZE)
+		gtest.Assert(err, nil)
+		gtest.AssertNE(ints, nil)
+		gtest.Assert(len(ints), 0)
+
+		strs, err := db.GetStrings(fmt.Sprintf("SELECT passport FROM %s WHERE id>?", table), SIZE)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(strs), 0)
+	})
+}
+
+func Test_DB_Union(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		query, args, err := db.Union(
+			db.Table(table).Fields("id,nickname").Where("id<?", 3),
+			&gdb.Sql{Sql: fmt.Sprintf("SELECT id,nickname FROM %s WHERE id>? AND id IN(?)", table), Args: []interface{}{8, g.Slice{9, 10}}},
+			fmt.Sprintf("SELECT id,nickname FROM %s WHERE id=5", table),
+		)
+		gtest.Assert(err, nil)
+		gtest.Assert(args, g.Slice{3, 8, 9, 10})
+		result, err := db.GetAll(query+" ORDER BY id", args...)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), 5)
+		gtest.Assert(result[0]["id"].Int(), 1)
+		gtest.Assert(result[4]["id"].Int(), 10)
+		gtest.Assert(result[2]["nickname"].String(), "name_5")
+	})
+	gtest.Case(t, func() {
+		sub := db.Table(table).Fields("passport").Where("id<=?", 2)
+		query, args, err := db.Union(sub, sub)
+		gtest.Assert(err, nil)
+		result, err := db.GetAll(query, args...)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), 2)
+
+		query, args, err = db.UnionAll(sub, sub)
+		gtest.Assert(err, nil)
+		gtest.Assert(args, g.Slice{2, 2})
+		result, err = db.GetAll(query, args...)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), 4)
+	})
+	gtest.Case(t, func() {
+		_, _, err := db.Union(db.Table(table), 1)
+		gtest.AssertNE(err, nil)
+	})
+}
+
+func Test_DB_GetAllCache(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		key := "test_get_all_cache_" + table
+		defer db.ClearCache(key)
+		query := fmt.Sprintf("SELECT * FROM %s WHERE id=?", table)
+		one, err := db.GetOneCache(time.Minute, key, query, 1)
+		gtest.Assert(err, nil)
+		gtest.Assert(one["nickname"].String(), "name_1")
+
+		// Raw statement does not clear the cache.
+		_, err = db.Exec(fmt.Sprintf("UPDATE %s SET nickname='cache' WHERE id=1", table))
+		gtest.Assert(err, nil)
+
+		one, err = db.GetOneCache(time.Minute, key, query, 1)
+		gtest.Assert(err, nil)
+		gtest.Assert(one["nickname"].String(), "name_1")
+
+		db.ClearCache(key)
+		one, err = db.GetOneCache(time.Minute, key, query, 1)
+		gtest.Assert(err, nil)
+		gtest.Assert(one["nickname"].String(), "cache")
+	})
+	gtest.Case(t, func() {
+		query := fmt.Sprintf("SELECT * FROM %s WHERE id>? ORDER BY id", table)
+		result, err := db.GetAllCache(time.Minute, "", query, 8)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), 2)
+
+		_, err = db.Exec(fmt.Sprintf("DELETE FROM %s WHERE id=10", table))
+		gtest.Assert(err, nil)
+
+		// Cached with the hash of query and arguments.
+		result, err = db.GetAllCache(time.Minute, "", query, 8)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), 2)
+
+		result, err = db.GetAllCache(time.Minute, "", query, 7)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), 2)
+		gtest.Assert(result[0]["id"].Int(), 8)
+	})
+	// Cleared automatically on writes to the table.
+	gtest.Case(t, func() {
+		query := fmt.Sprintf("SELECT * FROM %s WHERE id<=? ORDER BY id", table)
+		result, err := db.GetAllCache(time.Minute, "", query, 3)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), 3)
+
+		_, err = db.Delete(table, "id=?", 3)
+		gtest.Assert(err, nil)
+		result, err = db.GetAllCache(time.Minute, "", query, 3)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), 2)
+
+		_, err = db.Update(table, g.Map{"nickname": "auto"}, "id=?", 2)
+		gtest.Assert(err, nil)
+		result, err = db.GetAllCache(time.Minute, "", query, 3)
+		gtest.Assert(err, nil)
+		gtest.Assert(result[1]["nickname"].String(), "auto")
+
+		_, err = db.Insert(table, g.Map{"id": 3, "passport": "user_3"})
+		gtest.Assert(err, nil)
+		result, err = db.GetAllCache(time.Minute, "", query, 3)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), 3)
+	})
+	gtest.Case(t, func() {
+		key := "test_tag_cache_" + table
+		defer db.ClearCache(key)
+		one, err := db.GetOneCache(time.Minute, key, "SELECT ? AS v", 1)
+		gtest.Assert(err, nil)
+		gtest.Assert(one["v"].Int(), 1)
+
+		db.TagCache(key, table)
+		one, err = db.GetOneCache(time.Minute, key, "SELECT ? AS v", 2)
+		gtest.Assert(err, nil)
+		gtest.Assert(one["v"].Int(), 1)
+
+		_, err = db.Delete(table, "id=?", 1)
+		gtest.Assert(err, nil)
+		one, err = db.GetOneCache(time.Minute, key, "SELECT ? AS v", 2)
+		gtest.Assert(err, nil)
+		gtest.Assert(one["v"].Int(), 2)
+	})
+}
+
+func Test_DB_GetPageWithAggregates(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		result, total, values, err := db.GetPageWithAggregates(2, 2, table, "id>? ORDER BY id", g.Slice{4}, g.MapStrStr{
+			"id_sum": "SUM(id)",
+			"id_avg": "AVG(id)",
+			"id_max": "MAX(id)",
+		})
+		gtest.Assert(err, nil)
+		gtest.Assert(total, 6)
+		gtest.Assert(len(result), 2)
+		gtest.Assert(result[0]["id"].Int(), 7)
+		gtest.Assert(result[1]["id"].Int(), 8)
+		gtest.Assert(len(values), 3)
+		gtest.Assert(values["id_sum"].Int(), 45)
+		gtest.Assert(values["id_avg"].Float64(), 7.5)
+		gtest.Assert(values["id_max"].Int(), 10)
+	})
+	// The ORDER BY clause spanning multiple lines.
+	gtest.Case(t, func() {
+		result, total, values, err := db.GetPageWithAggregates(1, 2, table, "id>?\nORDER BY\n\tid DESC", g.Slice{4}, g.MapStrStr{
+			"id_sum": "SUM(id)",
+		})
+		gtest.Assert(err, nil)
+		gtest.Assert(total, 6)
+		gtest.Assert(len(result), 2)
+		gtest.Assert(result[0]["id"].Int(), 10)
+		gtest.Assert(result[1]["id"].Int(), 9)
+		gtest.Assert(values["id_sum"].Int(), 45)
+	})
+	gtest.Case(t, func() {
+		result, total, values, err := db.GetPageWithAggregates(1, 2, table, g.Map{"id>": SIZE}, nil, g.MapStrStr{
+			"id_count": "COUNT(id)",
+		})
+		gtest.Assert(err, nil)
+		gtest.Assert(total, 0)
+		gtest.Assert(len(result), 0)
+		gtest.Assert(values["id_count"].Int(), 0)
+	})
+	gtest.Case(t, func() {
+		_, _, _, err := db.GetPageWithAggregates(1, 0, table, nil, nil, nil)
+		gtest.AssertNE(err, nil)
+	})
+}
+
+func Test_DB_GetStruct(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		type User struct {
+			Id         int
+			Passport   string
+			Password   string
+			NickName   string
+			CreateTime gtime.Time
+		}
+		user := new(User)
+		err := db.GetStruct(user, fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), 3)
+		gtest.Assert(err, nil)
+		gtest.Assert(user.NickName, "name_3")
+	})
+	gtest.Case(t, func() {
+		type User struct {
+			Id         int
+			Passport   string
+			Password   string
+			NickName   string
+			CreateTime *gtime.Time
+		}
+		user := new(User)
+		err := db.GetStruct(user, fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), 3)
+		gtest.Assert(err, nil)
+		gtest.Assert(user.NickName, "name_3")
+	})
+}
+
+func Test_DB_Find(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	type User struct {
+		Id       int
+		Passport string
+		Nickname string
+	}
+	gtest.Case(t, func() {
+		user := new(User)
+		err := db.Find(user, table, 3)
+		gtest.Assert(err, nil)
+		gtest.Assert(user.Id, 3)
+		gtest.Assert(user.Nickname, "name_3")
+	})
+	gtest.Case(t, func() {
+		user := new(User)
+		err := db.Find(user, table, g.Map{"id": 5, "passport": "user_5"})
+		gtest.Assert(err, nil)
+		gtest.Assert(user.Id, 5)
+		gtest.Assert(user.Passport, "user_5")
+
+		err = db.Find(user, table, g.Map{"id": 5, "passport": "user_6"})
+		gtest.Assert(err, sql.ErrNoRows)
+	})
+	gtest.Case(t, func() {
+		user := new(User)
+		err := db.Find(user, table, SIZE+1)
+		gtest.Assert(err, sql.ErrNoRows)
+	})
+}
+
+// The primary keys are detected from the table fields for all the drivers,
+// eg: running the tests with -type=pgsql or -type=sqlite.
+func Test_DB_Find_PrimaryKey(t *testing.T) {
+	table := fmt.Sprintf(`%s_%d`, TABLE, gtime.TimestampNano())
+	_, err := db.Exec(fmt.Sprintf(`
+	CREATE TABLE %s (
+	   uid int NOT NULL,
+	   gid int NOT NULL,
+	   name varchar(45),
+	   PRIMARY KEY (uid, gid)
+	)`, table))
+	gtest.Assert(err, nil)
+	defer dropTable(table)
+	_, err = db.Insert(table, g.Map{"uid": 1, "gid": 2, "name": "member_1_2"})
+	gtest.Assert(err, nil)
+	type Member struct {
+		Uid  int
+		Gid  int
+		Name string
+	}
+	gtest.Case(t, func() {
+		fields, err := db.TableFields(table)
+		gtest.Assert(err, nil)
+		gtest.Assert(fields["uid"].Key, "PRI")
+		gtest.Assert(fields["gid"].Key, "PRI")
+		gtest.Assert(fields["name"].Key, "")
+	})
+	gtest.Case(t, func() {
+		member := new(Member)
+		err := db.Find(member, table, g.Map{"uid": 1, "gid": 2})
+		gtest.Assert(err, nil)
+		gtest.Assert(member.Name, "member_1_2")
+
+		// The composite primary keys require map parameter.
+		err = db.Find(member, table, 1)
+		gtest.AssertNE(err, nil)
+	})
+}
+
+func Test_DB_GetMapStructs(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	type User struct {
+		Id       int
+		Passport string
+		Nickname string
+	}
+	gtest.Case(t, func() {
+		var users map[int]*User
+		err := db.GetMapStructs(&users, "id", fmt.Sprintf("SELECT * FROM %s WHERE id<?", table), 4)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(users), 3)
+		gtest.Assert(users[2].Id, 2)
+		gtest.Assert(users[2].Passport, "user_2")
+		gtest.Assert(users[3].Nickname, "name_3")
+	})
+	gtest.Case(t, func() {
+		var users map[string]User
+		err := db.GetMapStructs(&users, "passport", fmt.Sprintf("SELECT * FROM %s", table))
+		gtest.Assert(err, nil)
+		gtest.Assert(len(users), SIZE)
+		gtest.Assert(users["user_5"].Id, 5)
+	})
+	gtest.Case(t, func() {
+		var users map[int]*User
+		err := db.GetMapStructs(&users, "id", fmt.Sprintf("SELECT * FROM %s WHERE id<0", table))
+		gtest.Assert(err, gdb.ErrNoRows)
+		err = db.GetMapStructs(&users, "uid", fmt.Sprintf("SELECT * FROM %s", table))
+		gtest.AssertNE(err, nil)
+		err = db.GetMapStructs(users, "id", fmt.Sprintf("SELECT * FROM %s", table))
+		gtest.AssertNE(err, nil)
+	})
+	gtest.Case(t, func() {
+		tx, err := db.Begin()
+		gtest.Assert(err, nil)
+		defer tx.Rollback()
+		var users map[int64]*User
+		err = tx.GetMapStructs(&users, "id", fmt.Sprintf("SELECT * FROM %s", table))
+		gtest.Assert(err, nil)
+		gtest.Assert(users[int64(SIZE)].Passport, fmt.Sprintf("user_%d", SIZE))
+	})
+}
+
+func Test_DB_GetStructs(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		type User struct {
+			Id         int
+			Passport   string
+			Password   string
+			NickName   string
+			CreateTime gtime.Time
+		}
+		var users []User
+		err := db.GetStructs(&users, fmt.Sprintf("SELECT * FROM %s WHERE id>?", table), 1)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(users), SIZE-1)
+		gtest.Assert(users[0].Id, 2)
+		gtest.Assert(users[1].Id, 3)
+		gtest.Assert(users[2].Id, 4)
+		gtest.Assert(users[0].NickName, "name_2")
+		gtest.Assert(users[1].NickName, "name_3")
+		gtest.Assert(users[2].NickName, "name_4")
+	})
+
+	gtest.Case(t, func() {
+		type User struct {
+			Id         int
+			Passport   string
+			Password   string
+			NickName   string
+			CreateTime *gtime.Time
+		}
+		var users []User
+		err := db.GetStructs(&users, fmt.Sprintf("SELECT * FROM %s WHERE id>?", table), 1)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(users), SIZE-1)
+		gtest.Assert(users[0].Id, 2)
+		gtest.Assert(users[1].Id, 3)
+		gtest.Assert(users[2].Id, 4)
+		gtest.Assert(users[0].NickName, "name_2")
+		gtest.Assert(users[1].NickName, "name_3")
+		gtest.Assert(users[2].NickName, "name_4")
+	})
+
+	gtest.Case(t, func() {
+		type User struct {
+			Id       int
+			NickName string
+		}
+		user := new(User)
+		err := db.GetStruct(user, fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), SIZE+1)
+		gtest.Assert(err, gdb.ErrNoRows)
+		gtest.Assert(gdb.IsNoRows(err), true)
+
+		var users []User
+		err = db.GetStructs(&users, fmt.Sprintf("SELECT * FROM %s WHERE id>?", table), SIZE)
+		gtest.Assert(err, sql.ErrNoRows)
+		gtest.Assert(gdb.IsNoRows(err), true)
+
+		gtest.Assert(gdb.IsNoRows(nil), false)
+		gtest.Assert(gdb.IsNoRows(fmt.Errorf("no rows")), false)
+	})
+}
+
+func Test_DB_GetScan(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		type User struct {
+			Id         int
+			Passport   string
+			Password   string
+			NickName   string
+			CreateTime gtime.Time
+		}
+		user := new(User)
+		err := db.GetScan(user, fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), 3)
+		gtest.Assert(err, nil)
+		gtest.Assert(user.NickName, "name_3")
+	})
+	gtest.Case(t, func() {
+		type User struct {
+			Id         int
+			Passport   string
+			Password   string
+			NickName   string
+			CreateTime *gtime.Time
+		}
+		user := new(User)
+		err := db.GetScan(user, fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), 3)
+		gtest.Assert(err, nil)
+		gtest.Assert(user.NickName, "name_3")
+	})
+
+	gtest.Case(t, func() {
+		type User struct {
+			Id         int
+			Passport   string
+			Password   string
+			NickName   string
+			CreateTime gtime.Time
+		}
+		var users []User
+		err := db.GetScan(&users, fmt.Sprintf("SELECT * FROM %s WHERE id>?", table), 1)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(users), SIZE-1)
+		gtest.Assert(users[0].Id, 2)
+		gtest.Assert(users[1].Id, 3)
+		gtest.Assert(users[2].Id, 4)
+		gtest.Assert(users[0].NickName, "name_2")
+		gtest.Assert(users[1].NickName, "name_3")
+		gtest.Assert(users[2].NickName, "name_4")
+	})
+
+	gtest.Case(t, func() {
+		type User struct {
+			Id         int
+			Passport   string
+			Password   string
+			NickName   string
+			CreateTime *gtime.Time
+		}
+		var users []User
+		err := db.GetScan(&users, fmt.Sprintf("SELECT * FROM %s WHERE id>?", table), 1)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(users), SIZE-1)
+		gtest.Assert(users[0].Id, 2)
+		gtest.Assert(users[1].Id, 3)
+		gtest.Assert(users[2].Id, 4)
+		gtest.Assert(users[0].NickName, "name_2")
+		gtest.Assert(users[1].NickName, "name_3")
+		gtest.Assert(users[2].NickName, "name_4")
+	})
+
+	gtest.Case(t, func() {
+		var one map[string]interface{}
+		err := db.GetScan(&one, fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), 3)
+		gtest.Assert(err, nil)
+		gtest.Assert(one["nickname"], "name_3")
+
+		var record gdb.Record
+		err = db.GetScan(&record, fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), 3)
+		gtest.Assert(err, nil)
+		gtest.Assert(record["nickname"].String(), "name_3")
+
+		var all []map[string]interface{}
+		err = db.GetScan(&all, fmt.Sprintf("SELECT * FROM %s WHERE id>? ORDER BY id", table), 1)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(all), SIZE-1)
+		gtest.Assert(all[0]["nickname"], "name_2")
+
+		var result gdb.Result
+		err = db.GetScan(&result, fmt.Sprintf("SELECT * FROM %s WHERE id>? ORDER BY id", table), 1)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), SIZE-1)
+		gtest.Assert(result[0]["nickname"].String(), "name_2")
+
+		var strMap map[string]string
+		err = db.GetScan(&strMap, fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), 3)
+		gtest.AssertNE(err, nil)
+	})
+	// It returns ErrNoRows for all the types if there's no record.
+	gtest.Case(t, func() {
+		query := fmt.Sprintf("SELECT * FROM %s WHERE id>?", table)
+		var one map[string]interface{}
+		gtest.Assert(db.GetScan(&one, query, SIZE), gdb.ErrNoRows)
+		gtest.Assert(one, nil)
+		var record gdb.Record
+		gtest.Assert(db.GetScan(&record, query, SIZE), gdb.ErrNoRows)
+		var all []map[string]interface{}
+		gtest.Assert(db.GetScan(&all, query, SIZE), gdb.ErrNoRows)
+		var result gdb.Result
+		gtest.Assert(db.GetScan(&result, query, SIZE), gdb.ErrNoRows)
+		user := new(struct{ Id int })
+		gtest.Assert(db.GetScan(user, query, SIZE), gdb.ErrNoRows)
+	})
+}
+
+func Test_DB_Delete(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		result, err := db.Delete(table, nil)
+		gtest.Assert(err, nil)
+		n, _ := result.RowsAffected()
+		gtest.Assert(n, SIZE)
+	})
+}
+
+func Test_DB_UpdateAndCount(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		n, err := db.UpdateAndCount(table, g.Map{"nickname": "T"}, "id<=?", 3)
+		gtest.Assert(err, nil)
+		gtest.Assert(n, 3)
+
+		n, err = db.UpdateAndCount(table, g.Map{"nickname": "T"}, "id<=?", 3)
+		gtest.Assert(err, nil)
+		gtest.Assert(n, 0)
+
+		_, err = db.UpdateAndCount(table, g.Map{"not_exist": "T"}, "id", 1)
+		gtest.AssertNE(err, nil)
+
+		n, err = db.DeleteAndCount(table, "nickname", "T")
+		gtest.Assert(err, nil)
+		gtest.Assert(n, 3)
+
+		n, err = db.DeleteAndCount(table, nil)
+		gtest.Assert(err, nil)
+		gtest.Assert(n, SIZE-3)
+	})
+	gtest.Case(t, func() {
+		tx, err := db.Begin()
+		gtest.Assert(err, nil)
+		defer tx.Rollback()
+
+		n, err := tx.UpdateAndCount(table, g.Map{"nickname": "T"}, "id", 1)
+		gtest.Assert(err, nil)
+		gtest.Assert(n, 0)
+
+		n, err = tx.DeleteAndCount(table, "id", 1)
+		gtest.Assert(err, nil)
+		gtest.Assert(n, 0)
+	})
+}
+
+func Test_DB_Truncate(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		_, err := db.Truncate(table)
+		gtest.Assert(err, nil)
+		n, err := db.Table(table).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(n, 0)
+	})
+	// With prefix.
+	gtest.Case(t, func() {
+		name := fmt.Sprintf(`%s_%d`, TABLE, gtime.TimestampNano())
+		createInitTableWithDb(dbPrefix, PREFIX1+name)
+		defer dropTable(PREFIX1 + name)
+		_, err := dbPrefix.Truncate(name)
+		gtest.Assert(err, nil)
+		n, err := dbPrefix.Table(name).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(n, 0)
+	})
+}
+
+func Test_DB_TableExists(t *testing.T) {
+	table := createTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		exists, err := db.TableExists(table)
+		gtest.Assert(err, nil)
+		gtest.Assert(exists, true)
+
+		exists, err = db.TableExists(table + "_none")
+		gtest.Assert(err, nil)
+		gtest.Assert(exists, false)
+
+		exists, err = db.TableExists(table, SCHEMA2)
+		gtest.Assert(err, nil)
+		gtest.Assert(exists, false)
+	})
+	// With prefix.
+	gtest.Case(t, func() {
+		name := fmt.Sprintf(`%s_%d`, TABLE, gtime.TimestampNano())
+		createTableWithDb(dbPrefix, PREFIX1+name)
+		defer dropTable(PREFIX1 + name)
+		exists, err := dbPrefix.TableExists(name)
+		gtest.Assert(err, nil)
+		gtest.Assert(exists, true)
+
+		exists, err = dbPrefix.TableExists(PREFIX1 + name)
+		gtest.Assert(err, nil)
+		gtest.Assert(exists, true)
+	})
+}
+
+func Test_DB_TableFields(t *testing.T) {
+	table := createTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		fields, err := db.TableFields(table)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(fields), 5)
+		gtest.Assert(fields["id"].Index, 0)
+		gtest.Assert(fields["id"].Key, "PRI")
+		gtest.Assert(fields["id"].Null, false)
+		gtest.Assert(fields["id"].Extra, "auto_increment")
+		gtest.Assert(fields["passport"].Index, 1)
+		gtest.Assert(fields["passport"].Type, "varchar(45)")
+		gtest.Assert(fields["passport"].Null, true)
+		gtest.Assert(fields["passport"].Key, "")
+	})
+	// With prefix.
+	gtest.Case(t, func() {
+		name := fmt.Sprintf(`%s_%d`, TABLE, gtime.TimestampNano())
+		createTableWithDb(dbPrefix, PREFIX1+name)
+		defer dropTable(PREFIX1 + name)
+		fields, err := dbPrefix.TableFields(name)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(fields), 5)
+		gtest.Assert(fields["nickname"].Index, 3)
+	})
+}
+
+func Test_DB_ClearTableFieldsCache(t *testing.T) {
+	table := createTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		fields, err := db.TableFields(table)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(fields), 5)
+
+		_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN email varchar(45) NULL", table))
+		gtest.Assert(err, nil)
+		fields, err = db.TableFields(table)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(fields), 5)
+
+		db.ClearTableFieldsCache(table)
+		fields, err = db.TableFields(table)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(fields), 6)
+		gtest.Assert(fields["email"].Index, 5)
+	})
+	// Concurrent retrieving.
+	gtest.Case(t, func() {
+		db.ClearTableFieldsCache(table)
+		wg := sync.WaitGroup{}
+		counts := garray.NewIntArray(true)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if fields, err := db.TableFields(table); err == nil {
+					counts.Append(len(fields))
+				}
+			}()
+		}
+		wg.Wait()
+		gtest.Assert(counts.Len(), 10)
+		gtest.Assert(counts.Sum(), 60)
+	})
+}
+
+func Test_DB_FilterUnknownColumns(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		_, err := db.Insert(table, g.Map{
+			"id":       100,
+			"passport": "user_100",
+			"extra":    "extra",
+		})
+		gtest.AssertNE(err, nil)
+	})
+	gtest.Case(t, func() {
+		db.SetFilterUnknownColumns(true)
+		defer db.SetFilterUnknownColumns(false)
+
+		_, err := db.Insert(table, g.Map{
+			"id":       100,
+			"passport": "user_100",
+			"extra":    "extra",
+		})
+		gtest.Assert(err, nil)
+
+		_, err = db.BatchInsert(table, g.List{
+			{"id": 101, "passport": "user_101", "extra": 1},
+			{"id": 102, "passport": "user_102", "extra": 2},
+		})
+		gtest.Assert(err, nil)
+
+		_, err = db.Update(table, g.Map{"nickname": "name_100", "extra": "extra"}, "id", 100)
+		gtest.Assert(err, nil)
+
+		one, err := db.Table(table).Where("id", 100).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["passport"].String(), "user_100")
+		gtest.Assert(one["nickname"].String(), "name_100")
+		n, err := db.Table(table).Where("id>?", SIZE).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(n, 3)
+
+		_, err = db.Insert(table, g.Map{"extra": "extra"})
+		gtest.AssertNE(err, nil)
+		_, err = db.Update(table, g.Map{"extra": "extra"}, "id", 100)
+		gtest.AssertNE(err, nil)
+	})
+	// The keys are matched case-insensitively.
+	gtest.Case(t, func() {
+		db.SetFilterUnknownColumns(true)
+		defer db.SetFilterUnknownColumns(false)
+
+		type User struct {
+			Id       int
+			Passport string
+			Nickname string
+			Extra    string
+		}
+		_, err := db.Insert(table, User{Id: 200, Passport: "user_200", Nickname: "name_200", Extra: "extra"})
+		gtest.Assert(err, nil)
+		_, err = db.Update(table, g.Map{"NickName": "new_200"}, "id", 200)
+		gtest.Assert(err, nil)
+
+		one, err := db.Table(table).Where("id", 200).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["passport"].String(), "user_200")
+		gtest.Assert(one["nickname"].String(), "new_200")
+	})
+}
+
+func Test_DB_Time(t *testing.T) {
+	table := createTable()
+	defer dropTable(table)
+
+	gtest.Case(t, func() {
+		result, err := db.Insert(table, g.Map{
+			"id":          200,
+			"passport":    "t200",
+			"password":    "123456",
+			"nickname":    "T200",
+			"create_time": time.Now(),
+		})
+		if err != nil {
+			gtest.Error(err)
+		}
+		n, _ := result.RowsAffected()
+		gtest.Assert(n, 1)
+		value, err := db.GetValue(fmt.Sprintf("select `passport` from `%s` where id=?", table), 200)
+		gtest.Assert(err, nil)
+		gtest.Assert(value.String(), "t200")
+	})
+
+	gtest.Case(t, func() {
+		t := time.Now()
+		result, err := db.Insert(table, g.Map{
+			"id":          300,
+			"passport":    "t300",
+			"password":    "123456",
+			"nickname":    "T300",
+			"create_time": &t,
+		})
+		if err != nil {
+			gtest.Error(err)
+		}
+		n, _ := result.RowsAffected()
+		gtest.Assert(n, 1)
+		value, err := db.GetValue(fmt.Sprintf("select `passport` from `%s` where id=?", table), 300)
+		gtest.Assert(err, nil)
+		gtest.Assert(value.String(), "t300")
+	})
+
+	gtest.Case(t, func() {
+		result, err := db.Delete(table, nil)
+		gtest.Assert(err, nil)
+		n, _ := result.RowsAffected()
+		gtest.Assert(n, 2)
+	})
+}
+
+func Test_DB_ToJson(t *testing.T) {
+
+	table := createInitTable()
+	defer dropTable(table)
+	_, err := db.Update(table, "create_time='2010-10-10 00:00:01'", "id=?", 1)
+	gtest.Assert(err, nil)
+
+	gtest.Case(t, func() {
+		result, err := db.Table(table).Fields("*").Where("id =? ", 1).Select()
+		if err != nil {
+			gtest.Fatal(err)
+		}
+
+		type User struct {
+			Id         int
+			Passport   string
+			Password   string
+			NickName   string
+			CreateTime string
+		}
+
+		users := make([]User, 0)
+
+		err = result.Structs(users)
+		gtest.AssertNE(err, nil)
+
+		err = result.Structs(&users)
+		if err != nil {
+			gtest.Fatal(err)
+		}
+
+		//ToJson
+		resultJson, err := gjson.LoadContent(result.Json())
+		if err != nil {
+			gtest.Fatal(err)
+		}
+
+		gtest.Assert(users[0].Id, resultJson.GetInt("0.id"))
+		gtest.Assert(users[0].Passport, resultJson.GetString("0.passport"))
+		gtest.Assert(users[0].Password, resultJson.GetString("0.password"))
+		gtest.Assert(users[0].NickName, resultJson.GetString("0.nickname"))
+		gtest.Assert(users[0].CreateTime, resultJson.GetString("0.create_time"))
+
+		result = nil
+		err = result.Structs(&users)
+		gtest.AssertNE(err, nil)
+	})
 
 	gtest.Case(t, func() {
-		result, err := db.Insert(table, g.Map{
-			"id":          200,
-			"passport":    "t200",
-			"password":    "123456",
-			"nickname":    "T200",
-			"create_time": time.Now(),
-		})
+		result, err := db.Table(table).Fields("*").Where("id =? ", 1).One()
 		if err != nil {
-			gtest.Error(err)
+			gtest.Fatal(err)
 		}
-		n, _ := result.RowsAffected()
-		gtest.Assert(n, 1)
-		value, err := db.GetValue(fmt.Sprintf("select `passport` from `%s` where id=?", table), 200)
-		gtest.Assert(err, nil)
-		gtest.Assert(value.String(), "t200")
+
+		type User struct {
+			Id         int
+			Passport   string
+			Password   string
+			NickName   string
+			CreateTime string
+		}
+
+		users := User{}
+
+		err = result.Struct(&users)
+		if err != nil {
+			gtest.Fatal(err)
+		}
+
+		result = nil
+		err = result.Struct(&users)
+		gtest.AssertNE(err, nil)
 	})
+}
+
+func Test_DB_ToXml(t *testing.T) {
+
+	table := createInitTable()
+	defer dropTable(table)
+	_, err := db.Update(table, "create_time='2010-10-10 00:00:01'", "id=?", 1)
+	gtest.Assert(err, nil)
 
 	gtest.Case(t, func() {
-		t := time.Now()
-		result, err := db.Insert(table, g.Map{
-			"id":          300,
-			"passport":    "t300",
-			"password":    "123456",
-			"nickname":    "T300",
-			"create_time": &t,
-		})
+		record, err := db.Table(table).Fields("*").Where("id = ?", 1).One()
 		if err != nil {
-			gtest.Error(err)
+			gtest.Fatal(err)
 		}
-		n, _ := result.RowsAffected()
-		gtest.Assert(n, 1)
-		value, err := db.GetValue(fmt.Sprintf("select `passport` from `%s` where id=?", table), 300)
-		gtest.Assert(err, nil)
-		gtest.Assert(value.String(), "t300")
+
+		type User struct {
+			Id         int
+			Passport   string
+			Password   string
+			NickName   string
+			CreateTime string
+		}
+
+		user := User{}
+		err = record.Struct(&user)
+		if err != nil {
+			gtest.Fatal(err)
+		}
+
+		result, err := gxml.Decode([]byte(record.Xml("doc")))
+		if err != nil {
+			gtest.Fatal(err)
+		}
+
+		resultXml := result["doc"].(map[string]interface{})
+		if v, ok := resultXml["id"]; ok {
+			gtest.Assert(user.Id, v)
+		} else {
+			gtest.Fatal("FAIL")
+		}
+
+		if v, ok := resultXml["passport"]; ok {
+			gtest.Assert(user.Passport, v)
+		} else {
+			gtest.Fatal("FAIL")
+		}
+
+		if v, ok := resultXml["password"]; ok {
+			gtest.Assert(user.Password, v)
+		} else {
+			gtest.Fatal("FAIL")
+		}
+
+		if v, ok := resultXml["nickname"]; ok {
+			gtest.Assert(user.NickName, v)
+		} else {
+			gtest.Fatal("FAIL")
+		}
+
+		if v, ok := resultXml["create_time"]; ok {
+			gtest.Assert(user.CreateTime, v)
+		} else {
+			gtest.Fatal("FAIL")
+		}
+
+	})
+}
+
+func Test_DB_ToStringMap(t *testing.T) {
+
+	table := createInitTable()
+	defer dropTable(table)
+	_, err := db.Update(table, "create_time='2010-10-10 00:00:01'", "id=?", 1)
+	gtest.Assert(err, nil)
+	gtest.Case(t, func() {
+		id := "1"
+		result, err := db.Table(table).Fields("*").Where("id = ?", 1).Select()
+		if err != nil {
+			gtest.Fatal(err)
+		}
+
+		type t_user struct {
+			Id         int
+			Passport   string
+			Password   string
+			NickName   string
+			CreateTime string
+		}
+
+		t_users := make([]t_user, 0)
+		err = result.Structs(&t_users)
+		if err != nil {
+			gtest.Fatal(err)
+		}
+
+		resultStringMap := result.MapKeyStr("id")
+		gtest.Assert(t_users[0].Id, resultStringMap[id]["id"])
+		gtest.Assert(t_users[0].Passport, resultStringMap[id]["passport"])
+		gtest.Assert(t_users[0].Password, resultStringMap[id]["password"])
+		gtest.Assert(t_users[0].NickName, resultStringMap[id]["nickname"])
+		gtest.Assert(t_users[0].CreateTime, resultStringMap[id]["create_time"])
+	})
+}
+
+func Test_DB_ToIntMap(t *testing.T) {
+
+	table := createInitTable()
+	defer dropTable(table)
+	_, err := db.Update(table, "create_time='2010-10-10 00:00:01'", "id=?", 1)
+	gtest.Assert(err, nil)
+
+	gtest.Case(t, func() {
+		id := 1
+		result, err := db.Table(table).Fields("*").Where("id = ?", id).Select()
+		if err != nil {
+			gtest.Fatal(err)
+		}
+
+		type t_user struct {
+			Id         int
+			Passport   string
+			Password   string
+			NickName   string
+			CreateTime string
+		}
+
+		t_users := make([]t_user, 0)
+		err = result.Structs(&t_users)
+		if err != nil {
+			gtest.Fatal(err)
+		}
+
+		resultIntMap := result.MapKeyInt("id")
+		gtest.Assert(t_users[0].Id, resultIntMap[id]["id"])
+		gtest.Assert(t_users[0].Passport, resultIntMap[id]["passport"])
+		gtest.Assert(t_users[0].Password, resultIntMap[id]["password"])
+		gtest.Assert(t_users[0].NickName, resultIntMap[id]["nickname"])
+		gtest.Assert(t_users[0].CreateTime, resultIntMap[id]["create_time"])
 	})
+}
+
+func Test_DB_ToUintMap(t *testing.T) {
+
+	table := createInitTable()
+	defer dropTable(table)
+	_, err := db.Update(table, "create_time='2010-10-10 00:00:01'", "id=?", 1)
+	gtest.Assert(err, nil)
+
+	gtest.Case(t, func() {
+		id := 1
+		result, err := db.Table(table).Fields("*").Where("id = ?", id).Select()
+		if err != nil {
+			gtest.Fatal(err)
+		}
+
+		type t_user struct {
+			Id         int
+			Passport   string
+			Password   string
+			NickName   string
+			CreateTime string
+		}
+
+		t_users := make([]t_user, 0)
+		err = result.Structs(&t_users)
+		if err != nil {
+			gtest.Fatal(err)
+		}
+
+		resultUintMap := result.MapKeyUint("id")
+		gtest.Assert(t_users[0].Id, resultUintMap[uint(id)]["id"])
+		gtest.Assert(t_users[0].Passport, resultUintMap[uint(id)]["passport"])
+		gtest.Assert(t_users[0].Password, resultUintMap[uint(id)]["password"])
+		gtest.Assert(t_users[0].NickName, resultUintMap[uint(id)]["nickname"])
+		gtest.Assert(t_users[0].CreateTime, resultUintMap[uint(id)]["create_time"])
 
-	gtest.Case(t, func() {
-		result, err := db.Delete(table, nil)
-		gtest.Assert(err, nil)
-		n, _ := result.RowsAffected()
-		gtest.Assert(n, 2)
 	})
 }
 
-func Test_DB_ToJson(t *testing.T) {
+func Test_DB_ToStringRecord(t *testing.T) {
 
 	table := createInitTable()
 	defer dropTable(table)
@@ -669,12 +2577,14 @@ func Test_DB_ToJson(t *testing.T) {
 	gtest.Assert(err, nil)
 
 	gtest.Case(t, func() {
-		result, err := db.Table(table).Fields("*").Where("id =? ", 1).Select()
+		id := 1
+		ids := "1"
+		result, err := db.Table(table).Fields("*").Where("id = ?", id).Select()
 		if err != nil {
 			gtest.Fatal(err)
 		}
 
-		type User struct {
+		type t_user struct {
 			Id         int
 			Passport   string
 			Password   string
@@ -682,40 +2592,37 @@ func Test_DB_ToJson(t *testing.T) {
 			CreateTime string
 		}
 
-		users := make([]User, 0)
-
-		err = result.Structs(users)
-		gtest.AssertNE(err, nil)
-
-		err = result.Structs(&users)
-		if err != nil {
-			gtest.Fatal(err)
-		}
-
-		//ToJson
-		resultJson, err := gjson.LoadContent(result.Json())
+		t_users := make([]t_user, 0)
+		err = result.Structs(&t_users)
 		if err != nil {
 			gtest.Fatal(err)
 		}
 
-		gtest.Assert(users[0].Id, resultJson.GetInt("0.id"))
-		gtest.Assert(users[0].Passport, resultJson.GetString("0.passport"))
-		gtest.Assert(users[0].Password, resultJson.GetString("0.password"))
-		gtest.Assert(users[0].NickName, resultJson.GetString("0.nickname"))
-		gtest.Assert(users[0].CreateTime, resultJson.GetString("0.create_time"))
+		resultStringRecord := result.RecordKeyStr("id")
+		gtest.Assert(t_users[0].Id, resultStringRecord[ids]["id"].Int())
+		gtest.Assert(t_users[0].Passport, resultStringRecord[ids]["passport"].String())
+		gtest.Assert(t_users[0].Password, resultStringRecord[ids]["password"].String())
+		gtest.Assert(t_users[0].NickName, resultStringRecord[ids]["nickname"].String())
+		gtest.Assert(t_users[0].CreateTime, resultStringRecord[ids]["create_time"].String())
 
-		result = nil
-		err = result.Structs(&users)
-		gtest.AssertNE(err, nil)
 	})
+}
+
+func Test_DB_ToIntRecord(t *testing.T) {
+
+	table := createInitTable()
+	defer dropTable(table)
+	_, err := db.Update(table, "create_time='2010-10-10 00:00:01'", "id=?", 1)
+	gtest.Assert(err, nil)
 
 	gtest.Case(t, func() {
-		result, err := db.Table(table).Fields("*").Where("id =? ", 1).One()
+		id := 1
+		result, err := db.Table(table).Fields("*").Where("id = ?", id).Select()
 		if err != nil {
 			gtest.Fatal(err)
 		}
 
-		type User struct {
+		type t_user struct {
 			Id         int
 			Passport   string
 			Password   string
@@ -723,20 +2630,23 @@ func Test_DB_ToJson(t *testing.T) {
 			CreateTime string
 		}
 
-		users := User{}
-
-		err = result.Struct(&users)
+		t_users := make([]t_user, 0)
+		err = result.Structs(&t_users)
 		if err != nil {
 			gtest.Fatal(err)
 		}
 
-		result = nil
-		err = result.Struct(&users)
-		gtest.AssertNE(err, nil)
+		resultIntRecord := result.RecordKeyInt("id")
+		gtest.Assert(t_users[0].Id, resultIntRecord[id]["id"].Int())
+		gtest.Assert(t_users[0].Passport, resultIntRecord[id]["passport"].String())
+		gtest.Assert(t_users[0].Password, resultIntRecord[id]["password"].String())
+		gtest.Assert(t_users[0].NickName, resultIntRecord[id]["nickname"].String())
+		gtest.Assert(t_users[0].CreateTime, resultIntRecord[id]["create_time"].String())
+
 	})
 }
 
-func Test_DB_ToXml(t *testing.T) {
+func Test_DB_ToUintRecord(t *testing.T) {
 
 	table := createInitTable()
 	defer dropTable(table)
@@ -744,12 +2654,13 @@ func Test_DB_ToXml(t *testing.T) {
 	gtest.Assert(err, nil)
 
 	gtest.Case(t, func() {
-		record, err := db.Table(table).Fields("*").Where("id = ?", 1).One()
+		id := 1
+		result, err := db.Table(table).Fields("*").Where("id = ?", id).Select()
 		if err != nil {
 			gtest.Fatal(err)
 		}
 
-		type User struct {
+		type t_user struct {
 			Id         int
 			Passport   string
 			Password   string
@@ -757,273 +2668,698 @@ func Test_DB_ToXml(t *testing.T) {
 			CreateTime string
 		}
 
-		user := User{}
-		err = record.Struct(&user)
+		t_users := make([]t_user, 0)
+		err = result.Structs(&t_users)
 		if err != nil {
 			gtest.Fatal(err)
 		}
 
-		result, err := gxml.Decode([]byte(record.Xml("doc")))
-		if err != nil {
-			gtest.Fatal(err)
-		}
+		resultUintRecord := result.RecordKeyUint("id")
+		gtest.Assert(t_users[0].Id, resultUintRecord[uint(id)]["id"].Int())
+		gtest.Assert(t_users[0].Passport, resultUintRecord[uint(id)]["passport"].String())
+		gtest.Assert(t_users[0].Password, resultUintRecord[uint(id)]["password"].String())
+		gtest.Assert(t_users[0].NickName, resultUintRecord[uint(id)]["nickname"].String())
+		gtest.Assert(t_users[0].CreateTime, resultUintRecord[uint(id)]["create_time"].String())
+	})
+}
 
-		resultXml := result["doc"].(map[string]interface{})
-		if v, ok := resultXml["id"]; ok {
-			gtest.Assert(user.Id, v)
-		} else {
-			gtest.Fatal("FAIL")
-		}
+func Test_DB_RegisterMoneyColumn(t *testing.T) {
+	name := "money_test"
+	dropTable(name)
+	defer dropTable(name)
+	_, err := db.Exec(fmt.Sprintf(`
+		CREATE TABLE %s (
+		id     int(10) unsigned NOT NULL AUTO_INCREMENT,
+		amount decimal(10,2) NOT NULL,
+		PRIMARY KEY (id)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+	`, name))
+	if err != nil {
+		gtest.Fatal(err)
+	}
+	db.RegisterMoneyColumn(name, "amount", 2)
+	gtest.Case(t, func() {
+		_, err := db.Insert(name, g.Map{"id": 1, "amount": 1234})
+		gtest.Assert(err, nil)
+		value, err := db.GetValue(fmt.Sprintf("SELECT amount FROM %s WHERE id=1", name))
+		gtest.Assert(err, nil)
+		gtest.Assert(value.Int64(), 1234)
 
-		if v, ok := resultXml["passport"]; ok {
-			gtest.Assert(user.Passport, v)
-		} else {
-			gtest.Fatal("FAIL")
-		}
+		one, err := db.Table(name).Where("id", 1).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["amount"].Int64(), 1234)
 
-		if v, ok := resultXml["password"]; ok {
-			gtest.Assert(user.Password, v)
-		} else {
-			gtest.Fatal("FAIL")
-		}
+		_, err = db.Update(name, g.Map{"amount": 5}, "id=1")
+		gtest.Assert(err, nil)
+		raw, err := db.GetValue(fmt.Sprintf("SELECT CAST(amount AS CHAR) FROM %s WHERE id=1", name))
+		gtest.Assert(err, nil)
+		gtest.Assert(raw.String(), "0.05")
+	})
+}
 
-		if v, ok := resultXml["nickname"]; ok {
-			gtest.Assert(user.NickName, v)
-		} else {
-			gtest.Fatal("FAIL")
-		}
+func Test_DB_RegisterConverter(t *testing.T) {
+	name := "converter_test"
+	dropTable(name)
+	defer dropTable(name)
+	_, err := db.Exec(fmt.Sprintf(`
+		CREATE TABLE %s (
+		id     int(10) unsigned NOT NULL AUTO_INCREMENT,
+		tags   varchar(100) NULL,
+		flags  set('a','b','c') NULL,
+		PRIMARY KEY (id)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+	`, name))
+	if err != nil {
+		gtest.Fatal(err)
+	}
+	converter := gdb.Converter{
+		Decode: func(value []byte) interface{} {
+			return gstr.SplitAndTrim(string(value), ",")
+		},
+		Encode: func(value interface{}) interface{} {
+			return strings.Join(gconv.Strings(value), ",")
+		},
+	}
+	db.RegisterColumnConverter(name, "tags", converter)
+	db.RegisterTypeConverter("SET", converter)
+	gtest.Case(t, func() {
+		_, err := db.Insert(name, g.Map{"id": 1, "tags": []string{"go", "orm"}, "flags": []string{"a", "c"}})
+		gtest.Assert(err, nil)
+		raw, err := db.GetOne(fmt.Sprintf("SELECT CONCAT(tags) t, CONCAT(flags) f FROM %s WHERE id=1", name))
+		gtest.Assert(err, nil)
+		gtest.Assert(raw["t"].String(), "go,orm")
+		gtest.Assert(raw["f"].String(), "a,c")
 
-		if v, ok := resultXml["create_time"]; ok {
-			gtest.Assert(user.CreateTime, v)
-		} else {
-			gtest.Fatal("FAIL")
+		one, err := db.Table(name).Where("id", 1).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["tags"].Strings(), []string{"go", "orm"})
+		gtest.Assert(one["flags"].Strings(), []string{"a", "c"})
+
+		type Item struct {
+			Id    int
+			Tags  []string
+			Flags []string
 		}
+		item := new(Item)
+		gtest.Assert(db.Table(name).Where("id", 1).Struct(item), nil)
+		gtest.Assert(item.Tags, []string{"go", "orm"})
+
+		_, err = db.Update(name, g.Map{"tags": []string{"db"}}, "id=1")
+		gtest.Assert(err, nil)
+		value, err := db.GetValue(fmt.Sprintf("SELECT tags FROM %s WHERE id=1", name))
+		gtest.Assert(err, nil)
+		gtest.Assert(value.Strings(), []string{"db"})
 
+		// NULL value is not converted.
+		_, err = db.Insert(name, g.Map{"id": 2, "tags": nil})
+		gtest.Assert(err, nil)
+		value, err = db.GetValue(fmt.Sprintf("SELECT tags FROM %s WHERE id=2", name))
+		gtest.Assert(err, nil)
+		gtest.Assert(value.IsNil(), true)
 	})
 }
 
-func Test_DB_ToStringMap(t *testing.T) {
+func Test_DB_OrmTag(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	type User struct {
+		Id       int    `orm:"id"`
+		Name     string `orm:"nickname"`
+		Password string `orm:"-"`
+		Passport string
+	}
+	gtest.Case(t, func() {
+		_, err := db.Update(table, User{Name: "name_1", Password: "pass_new", Passport: "user_1"}, "id=1")
+		gtest.Assert(err, nil)
+		one, err := db.GetOne(fmt.Sprintf("SELECT * FROM %s WHERE id=1", table))
+		gtest.Assert(err, nil)
+		gtest.Assert(one["nickname"].String(), "name_1")
+		gtest.Assert(one["password"].String(), "pass_1")
+	})
+	gtest.Case(t, func() {
+		user := new(User)
+		err := db.GetStruct(user, fmt.Sprintf("SELECT * FROM %s WHERE id=1", table))
+		gtest.Assert(err, nil)
+		gtest.Assert(user.Id, 1)
+		gtest.Assert(user.Name, "name_1")
+		gtest.Assert(user.Passport, "user_1")
+		gtest.Assert(user.Password, "")
+	})
+}
 
+func Test_DB_ReplaceSet(t *testing.T) {
 	table := createInitTable()
 	defer dropTable(table)
-	_, err := db.Update(table, "create_time='2010-10-10 00:00:01'", "id=?", 1)
-	gtest.Assert(err, nil)
 	gtest.Case(t, func() {
-		id := "1"
-		result, err := db.Table(table).Fields("*").Where("id = ?", 1).Select()
-		if err != nil {
-			gtest.Fatal(err)
-		}
+		err := db.ReplaceSet(table, "id<=3", g.List{
+			{"id": 1, "passport": "t1", "password": "p1", "nickname": "n1"},
+			{"id": 2, "passport": "t2", "password": "p2", "nickname": "n2"},
+		})
+		gtest.Assert(err, nil)
+		count, err := db.Table(table).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, SIZE-1)
+		one, err := db.Table(table).Where("id", 1).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["passport"].String(), "t1")
+	})
+	// Rollback on failure.
+	gtest.Case(t, func() {
+		err := db.ReplaceSet(table, "id<=2", g.List{
+			{"id": 1, "passport": "t1", "password": "p1", "nickname": "n1"},
+			{"id": 5, "passport": "t5", "password": "p5", "nickname": "n5"},
+		})
+		gtest.AssertNE(err, nil)
+		count, err := db.Table(table).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, SIZE-1)
+	})
+}
 
-		type t_user struct {
-			Id         int
-			Passport   string
-			Password   string
-			NickName   string
-			CreateTime string
-		}
+func Test_DB_GetStruct_Nullable(t *testing.T) {
+	table := createTable()
+	defer dropTable(table)
+	_, err := db.Insert(table, g.List{
+		{"id": 1, "passport": "user_1", "nickname": "name_1", "create_time": "2020-01-01 12:00:00"},
+		{"id": 2},
+	})
+	if err != nil {
+		gtest.Fatal(err)
+	}
+	type User struct {
+		Id         *int
+		Passport   sql.NullString
+		Nickname   *string
+		CreateTime *gtime.Time
+	}
+	type UserTime struct {
+		Id         int
+		CreateTime *time.Time
+	}
+	gtest.Case(t, func() {
+		user := new(User)
+		err := db.GetStruct(user, fmt.Sprintf("SELECT * FROM %s WHERE id=1", table))
+		gtest.Assert(err, nil)
+		gtest.Assert(*user.Id, 1)
+		gtest.Assert(user.Passport.Valid, true)
+		gtest.Assert(user.Passport.String, "user_1")
+		gtest.Assert(*user.Nickname, "name_1")
+		gtest.Assert(user.CreateTime.String(), "2020-01-01 12:00:00")
+
+		userTime := new(UserTime)
+		err = db.GetStruct(userTime, fmt.Sprintf("SELECT * FROM %s WHERE id=1", table))
+		gtest.Assert(err, nil)
+		gtest.Assert(userTime.CreateTime.Format("2006-01-02 15:04:05"), "2020-01-01 12:00:00")
+	})
+	gtest.Case(t, func() {
+		user := new(User)
+		err := db.GetStruct(user, fmt.Sprintf("SELECT * FROM %s WHERE id=2", table))
+		gtest.Assert(err, nil)
+		gtest.Assert(*user.Id, 2)
+		gtest.Assert(user.Passport.Valid, false)
+		gtest.Assert(user.Nickname == nil, true)
+		gtest.Assert(user.CreateTime == nil, true)
 
-		t_users := make([]t_user, 0)
-		err = result.Structs(&t_users)
-		if err != nil {
-			gtest.Fatal(err)
-		}
+		userTime := new(UserTime)
+		err = db.GetStruct(userTime, fmt.Sprintf("SELECT * FROM %s WHERE id=2", table))
+		gtest.Assert(err, nil)
+		gtest.Assert(userTime.CreateTime == nil, true)
+	})
+}
 
-		resultStringMap := result.MapKeyStr("id")
-		gtest.Assert(t_users[0].Id, resultStringMap[id]["id"])
-		gtest.Assert(t_users[0].Passport, resultStringMap[id]["passport"])
-		gtest.Assert(t_users[0].Password, resultStringMap[id]["password"])
-		gtest.Assert(t_users[0].NickName, resultStringMap[id]["nickname"])
-		gtest.Assert(t_users[0].CreateTime, resultStringMap[id]["create_time"])
+func Test_DB_JsonTag(t *testing.T) {
+	name := "json_test"
+	dropTable(name)
+	defer dropTable(name)
+	_, err := db.Exec(fmt.Sprintf(`
+		CREATE TABLE %s (
+		id      int(10) unsigned NOT NULL AUTO_INCREMENT,
+		profile text NULL,
+		tags    json NULL,
+		PRIMARY KEY (id)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+	`, name))
+	if err != nil {
+		gtest.Fatal(err)
+	}
+	type Profile struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	type User struct {
+		Id      int      `orm:"id"`
+		Profile Profile  `orm:"profile,json"`
+		Tags    []string `orm:"tags,json"`
+	}
+	gtest.Case(t, func() {
+		_, err := db.Insert(name, User{
+			Id:      1,
+			Profile: Profile{Name: "john", Age: 18},
+			Tags:    []string{"a", "b"},
+		})
+		gtest.Assert(err, nil)
+		_, err = db.Insert(name, g.Map{"id": 2})
+		gtest.Assert(err, nil)
+
+		one, err := db.GetOne(fmt.Sprintf("SELECT * FROM %s WHERE id=1", name))
+		gtest.Assert(err, nil)
+		gtest.Assert(one["profile"].String(), `{"name":"john","age":18}`)
+
+		user := new(User)
+		err = db.GetStruct(user, fmt.Sprintf("SELECT * FROM %s WHERE id=1", name))
+		gtest.Assert(err, nil)
+		gtest.Assert(user.Profile.Name, "john")
+		gtest.Assert(user.Profile.Age, 18)
+		gtest.Assert(user.Tags, []string{"a", "b"})
+
+		var users []*User
+		err = db.GetStructs(&users, fmt.Sprintf("SELECT * FROM %s ORDER BY id", name))
+		gtest.Assert(err, nil)
+		gtest.Assert(len(users), 2)
+		gtest.Assert(users[0].Tags, []string{"a", "b"})
+		gtest.Assert(users[1].Tags == nil, true)
+	})
+	gtest.Case(t, func() {
+		_, err := db.Update(name, User{Id: 1, Tags: []string{"c"}}, "id=1")
+		gtest.Assert(err, nil)
+		user := new(User)
+		err = db.GetStruct(user, fmt.Sprintf("SELECT * FROM %s WHERE id=1", name))
+		gtest.Assert(err, nil)
+		gtest.Assert(user.Tags, []string{"c"})
+	})
+	// The error of marshaling json field is returned.
+	gtest.Case(t, func() {
+		type BadUser struct {
+			Id   int         `orm:"id"`
+			Tags interface{} `orm:"tags,json"`
+		}
+		user := BadUser{Id: 3, Tags: make(chan int)}
+		_, err := db.Insert(name, user)
+		gtest.AssertNE(err, nil)
+		_, err = db.BatchInsert(name, []BadUser{user})
+		gtest.AssertNE(err, nil)
+		_, err = db.Update(name, user, "id=3")
+		gtest.AssertNE(err, nil)
+		_, err = db.Table(name).Data(user).Insert()
+		gtest.AssertNE(err, nil)
+		n, err := db.Table(name).Where("id", 3).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(n, 0)
 	})
 }
 
-func Test_DB_ToIntMap(t *testing.T) {
-
+func Test_DB_WhereBuilder(t *testing.T) {
 	table := createInitTable()
 	defer dropTable(table)
-	_, err := db.Update(table, "create_time='2010-10-10 00:00:01'", "id=?", 1)
-	gtest.Assert(err, nil)
+	gtest.Case(t, func() {
+		where, args := gdb.NewWhereBuilder().
+			Where("id", ">", 1).
+			WhereIn("u.id", g.Slice{1, 2}).
+			WhereGroup(gdb.NewWhereBuilder().WhereNull("nickname").OrWhere("nickname", "like", "name%")).
+			OrWhereNull("passport").
+			Build(db)
+		gtest.Assert(where, "`id`>? AND `u`.`id` IN(?) AND (`nickname` IS NULL OR `nickname` LIKE ?) OR `passport` IS NULL")
+		gtest.Assert(args, g.Slice{1, g.Slice{1, 2}, "name%"})
+	})
+	gtest.Case(t, func() {
+		result, err := db.Table(table).Where(gdb.NewWhereBuilder().WhereIn("id", g.Slice{1, 2, 3}).Where("id", "!=", 2)).Order("id").All()
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), 2)
+		gtest.Assert(result[0]["id"].Int(), 1)
+		gtest.Assert(result[1]["id"].Int(), 3)
+
+		r, err := db.Update(table, g.Map{"nickname": "T"}, gdb.NewWhereBuilder().Where("id", "<=", 3))
+		gtest.Assert(err, nil)
+		n, _ := r.RowsAffected()
+		gtest.Assert(n, 3)
 
+		r, err = db.Delete(table, gdb.NewWhereBuilder().Where("nickname", "=", "T").OrWhere("id", "=", SIZE))
+		gtest.Assert(err, nil)
+		n, _ = r.RowsAffected()
+		gtest.Assert(n, 4)
+	})
 	gtest.Case(t, func() {
-		id := 1
-		result, err := db.Table(table).Fields("*").Where("id = ?", id).Select()
-		if err != nil {
-			gtest.Fatal(err)
-		}
+		defer func() {
+			gtest.AssertNE(recover(), nil)
+		}()
+		gdb.NewWhereBuilder().Where("id; DROP TABLE user", "=", 1)
+	})
+	// COLLATE.
+	gtest.Case(t, func() {
+		where, args := gdb.NewWhereBuilder().WhereCollate("passport", "=", "USER_5", "utf8_bin").Build(db)
+		gtest.Assert(where, "`passport`=? COLLATE utf8_bin")
+		gtest.Assert(args, g.Slice{"USER_5"})
 
-		type t_user struct {
-			Id         int
-			Passport   string
-			Password   string
-			NickName   string
-			CreateTime string
-		}
+		// Case-insensitive in default collation of the table.
+		count, err := db.Table(table).Where("passport", "USER_5").Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 1)
 
-		t_users := make([]t_user, 0)
-		err = result.Structs(&t_users)
-		if err != nil {
-			gtest.Fatal(err)
-		}
+		count, err = db.Table(table).Where(gdb.NewWhereBuilder().WhereCollate("passport", "=", "USER_5", "utf8_bin")).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 0)
 
-		resultIntMap := result.MapKeyInt("id")
-		gtest.Assert(t_users[0].Id, resultIntMap[id]["id"])
-		gtest.Assert(t_users[0].Passport, resultIntMap[id]["passport"])
-		gtest.Assert(t_users[0].Password, resultIntMap[id]["password"])
-		gtest.Assert(t_users[0].NickName, resultIntMap[id]["nickname"])
-		gtest.Assert(t_users[0].CreateTime, resultIntMap[id]["create_time"])
+		count, err = db.Table(table).Where(gdb.NewWhereBuilder().WhereCollate("passport", "=", "user_5", "utf8_bin")).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 1)
+	})
+	gtest.Case(t, func() {
+		defer func() {
+			gtest.AssertNE(recover(), nil)
+		}()
+		gdb.NewWhereBuilder().WhereCollate("passport", "=", "user_5", "utf8_bin; DROP TABLE user")
 	})
 }
 
-func Test_DB_ToUintMap(t *testing.T) {
-
+func Test_DB_WhereBuilder_Comparison(t *testing.T) {
 	table := createInitTable()
 	defer dropTable(table)
-	_, err := db.Update(table, "create_time='2010-10-10 00:00:01'", "id=?", 1)
-	gtest.Assert(err, nil)
-
 	gtest.Case(t, func() {
-		id := 1
-		result, err := db.Table(table).Fields("*").Where("id = ?", id).Select()
-		if err != nil {
-			gtest.Fatal(err)
-		}
+		where, args := gdb.NewWhereBuilder().
+			WhereBetween("create_time", "2018-01-01", "2018-12-31").
+			WhereGT("id", 1).
+			WhereGTE("id", 2).
+			WhereLT("u.id", 9).
+			WhereLTE("id", 8).
+			WhereLike("nickname", "name%").
+			Build(db)
+		gtest.Assert(where, "`create_time` BETWEEN ? AND ? AND `id`>? AND `id`>=? AND `u`.`id`<? AND `id`<=? AND `nickname` LIKE ?")
+		gtest.Assert(args, g.Slice{"2018-01-01", "2018-12-31", 1, 2, 9, 8, "name%"})
+	})
+	gtest.Case(t, func() {
+		result, err := db.Table(table).Where(gdb.NewWhereBuilder().WhereBetween("id", 3, 5)).Order("id").All()
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), 3)
+		gtest.Assert(result[0]["id"].Int(), 3)
+		gtest.Assert(result[2]["id"].Int(), 5)
 
-		type t_user struct {
-			Id         int
-			Passport   string
-			Password   string
-			NickName   string
-			CreateTime string
-		}
+		count, err := db.Table(table).Where(gdb.NewWhereBuilder().WhereGT("id", 3).WhereLTE("id", 5)).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 2)
 
-		t_users := make([]t_user, 0)
-		err = result.Structs(&t_users)
-		if err != nil {
-			gtest.Fatal(err)
-		}
+		count, err = db.Table(table).Where(gdb.NewWhereBuilder().WhereGTE("id", 3).WhereLT("id", 5)).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 2)
 
-		resultUintMap := result.MapKeyUint("id")
-		gtest.Assert(t_users[0].Id, resultUintMap[uint(id)]["id"])
-		gtest.Assert(t_users[0].Passport, resultUintMap[uint(id)]["passport"])
-		gtest.Assert(t_users[0].Password, resultUintMap[uint(id)]["password"])
-		gtest.Assert(t_users[0].NickName, resultUintMap[uint(id)]["nickname"])
-		gtest.Assert(t_users[0].CreateTime, resultUintMap[uint(id)]["create_time"])
+		r, err := db.Update(table, g.Map{"nickname": "T"}, gdb.NewWhereBuilder().WhereLike("passport", "user_1%"))
+		gtest.Assert(err, nil)
+		n, _ := r.RowsAffected()
+		gtest.Assert(n, 2)
 
+		r, err = db.Delete(table, gdb.NewWhereBuilder().WhereBetween("id", 1, 2))
+		gtest.Assert(err, nil)
+		n, _ = r.RowsAffected()
+		gtest.Assert(n, 2)
+	})
+	gtest.Case(t, func() {
+		defer func() {
+			gtest.AssertNE(recover(), nil)
+		}()
+		gdb.NewWhereBuilder().WhereGT("id>1 OR 1", 1)
 	})
 }
 
-func Test_DB_ToStringRecord(t *testing.T) {
+func Test_DB_WhereBuilder_Like(t *testing.T) {
+	table := createTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		where, args := gdb.NewWhereBuilder().
+			WhereContains("nickname", "50%").
+			WhereHasPrefix("passport", "a_b").
+			WhereHasSuffix("password", "!").
+			Build(db)
+		gtest.Assert(where, "`nickname` LIKE ? ESCAPE '!' AND `passport` LIKE ? ESCAPE '!' AND `password` LIKE ? ESCAPE '!'")
+		gtest.Assert(args, g.Slice{"%50!%%", "a!_b%", "%!!"})
+	})
+	gtest.Case(t, func() {
+		_, err := db.Insert(table, g.List{
+			{"id": 1, "passport": "a_b", "password": "pass", "nickname": "50% off", "create_time": "2018-10-24 10:00:00"},
+			{"id": 2, "passport": "axb", "password": "pass", "nickname": "500 off", "create_time": "2018-10-24 10:00:00"},
+			{"id": 3, "passport": "a!b", "password": "pass", "nickname": "[50] off", "create_time": "2018-10-24 10:00:00"},
+		})
+		gtest.Assert(err, nil)
+
+		all, err := db.Table(table).Where(gdb.NewWhereBuilder().WhereContains("nickname", "50%")).All()
+		gtest.Assert(err, nil)
+		gtest.Assert(len(all), 1)
+		gtest.Assert(all[0]["id"].Int(), 1)
+
+		all, err = db.Table(table).Where(gdb.NewWhereBuilder().WhereHasPrefix("passport", "a_")).All()
+		gtest.Assert(err, nil)
+		gtest.Assert(len(all), 1)
+		gtest.Assert(all[0]["id"].Int(), 1)
+
+		all, err = db.Table(table).Where(gdb.NewWhereBuilder().WhereHasSuffix("passport", "!b")).All()
+		gtest.Assert(err, nil)
+		gtest.Assert(len(all), 1)
+		gtest.Assert(all[0]["id"].Int(), 3)
+
+		all, err = db.GetAll(
+			fmt.Sprintf("SELECT * FROM %s WHERE nickname LIKE ? %s", table, gdb.LIKE_ESCAPE),
+			gdb.LikePattern("[50]")+"%",
+		)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(all), 1)
+		gtest.Assert(all[0]["id"].Int(), 3)
+	})
+}
 
+func Test_DB_OrderBuilder(t *testing.T) {
 	table := createInitTable()
 	defer dropTable(table)
-	_, err := db.Update(table, "create_time='2010-10-10 00:00:01'", "id=?", 1)
-	gtest.Assert(err, nil)
-
 	gtest.Case(t, func() {
-		id := 1
-		ids := "1"
-		result, err := db.Table(table).Fields("*").Where("id = ?", id).Select()
-		if err != nil {
-			gtest.Fatal(err)
-		}
+		clause, err := gdb.NewOrderBuilder().Build(db)
+		gtest.Assert(err, nil)
+		gtest.Assert(clause, "")
 
-		type t_user struct {
-			Id         int
-			Passport   string
-			Password   string
-			NickName   string
-			CreateTime string
-		}
+		clause, err = gdb.NewOrderBuilder("id", "u.nickname").OrderBy("u.nickname", "desc").OrderBy("id", "").Build(db)
+		gtest.Assert(err, nil)
+		gtest.Assert(clause, "ORDER BY `u`.`nickname` DESC,`id` ASC")
 
-		t_users := make([]t_user, 0)
-		err = result.Structs(&t_users)
-		if err != nil {
-			gtest.Fatal(err)
-		}
+		clause, err = gdb.NewOrderBuilder().GroupBy("passport", "nickname").OrderBy("passport", "ASC").Build(db)
+		gtest.Assert(err, nil)
+		gtest.Assert(clause, "GROUP BY `passport`,`nickname` ORDER BY `passport` ASC")
+	})
+	gtest.Case(t, func() {
+		_, err := gdb.NewOrderBuilder("id").OrderBy("password", "ASC").Build(db)
+		gtest.AssertNE(err, nil)
 
-		resultStringRecord := result.RecordKeyStr("id")
-		gtest.Assert(t_users[0].Id, resultStringRecord[ids]["id"].Int())
-		gtest.Assert(t_users[0].Passport, resultStringRecord[ids]["passport"].String())
-		gtest.Assert(t_users[0].Password, resultStringRecord[ids]["password"].String())
-		gtest.Assert(t_users[0].NickName, resultStringRecord[ids]["nickname"].String())
-		gtest.Assert(t_users[0].CreateTime, resultStringRecord[ids]["create_time"].String())
+		_, err = gdb.NewOrderBuilder().OrderBy("id", "ASC; DROP TABLE user").Build(db)
+		gtest.AssertNE(err, nil)
+
+		_, err = gdb.NewOrderBuilder().OrderBy("(SELECT 1)", "ASC").Build(db)
+		gtest.AssertNE(err, nil)
 
+		_, err = gdb.NewOrderBuilder().GroupBy("id", "id,password").Build(db)
+		gtest.AssertNE(err, nil)
+	})
+	gtest.Case(t, func() {
+		clause, err := gdb.NewOrderBuilder("id", "nickname").OrderBy("id", "DESC").Build(db)
+		gtest.Assert(err, nil)
+		result, err := db.GetAll(fmt.Sprintf("SELECT * FROM %s %s", table, clause))
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), SIZE)
+		gtest.Assert(result[0]["id"].Int(), SIZE)
+		gtest.Assert(result[SIZE-1]["id"].Int(), 1)
 	})
 }
 
-func Test_DB_ToIntRecord(t *testing.T) {
-
+func Test_DB_WhereBuilder_Struct(t *testing.T) {
+	type Paging struct {
+		Page int `orm:"-"`
+	}
+	type UserSearch struct {
+		Paging
+		Id       []int
+		Passport string
+		Nickname *string `orm:"nickname"`
+		Password string  `orm:"password"`
+	}
 	table := createInitTable()
 	defer dropTable(table)
-	_, err := db.Update(table, "create_time='2010-10-10 00:00:01'", "id=?", 1)
-	gtest.Assert(err, nil)
+	gtest.Case(t, func() {
+		where, args := gdb.NewWhereBuilder().WhereStruct(&UserSearch{
+			Paging:   Paging{Page: 1},
+			Passport: "user_1",
+		}).Build(db)
+		gtest.Assert(where, "`Passport`=?")
+		gtest.Assert(args, g.Slice{"user_1"})
+
+		nickname := ""
+		where, args = gdb.NewWhereBuilder().WhereStruct(&UserSearch{
+			Id:       []int{1, 2},
+			Nickname: &nickname,
+		}).Build(db)
+		gtest.Assert(where, "`Id` IN(?) AND `nickname`=?")
+		gtest.Assert(args, g.Slice{[]int{1, 2}, ""})
+
+		where, args = gdb.NewWhereBuilder().WhereStruct(UserSearch{}).Build(db)
+		gtest.Assert(where, "")
+		gtest.Assert(len(args), 0)
+	})
+	gtest.Case(t, func() {
+		where, args := gdb.NewWhereBuilder().WhereStructStrict(&UserSearch{
+			Id:       []int{1},
+			Passport: "user_1",
+		}).Build(db)
+		gtest.Assert(where, "`Id` IN(?) AND `Passport`=? AND `nickname` IS NULL AND `password`=?")
+		gtest.Assert(args, g.Slice{[]int{1}, "user_1", ""})
+	})
+	gtest.Case(t, func() {
+		result, err := db.Table(table).Where(gdb.NewWhereBuilder().WhereStruct(&UserSearch{
+			Id:       []int{1, 2, 3},
+			Password: "pass_2",
+		})).All()
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), 1)
+		gtest.Assert(result[0]["id"].Int(), 2)
 
+		count, err := db.Table(table).Where(gdb.NewWhereBuilder().WhereStructStrict(&UserSearch{
+			Id:       []int{1, 2, 3},
+			Password: "pass_2",
+		})).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 0)
+	})
 	gtest.Case(t, func() {
-		id := 1
-		result, err := db.Table(table).Fields("*").Where("id = ?", id).Select()
-		if err != nil {
-			gtest.Fatal(err)
-		}
+		defer func() {
+			gtest.AssertNE(recover(), nil)
+		}()
+		gdb.NewWhereBuilder().WhereStruct(g.Map{"id": 1})
+	})
+}
 
-		type t_user struct {
-			Id         int
-			Passport   string
-			Password   string
-			NickName   string
-			CreateTime string
+func Test_DB_BatchInsertAndGetIds(t *testing.T) {
+	table := createTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		list := g.List{}
+		for i := 1; i <= 5; i++ {
+			list = append(list, g.Map{"passport": fmt.Sprintf("user_%d", i)})
 		}
+		ids, err := db.BatchInsertAndGetIds(table, list, "id", 2)
+		gtest.Assert(err, nil)
+		gtest.Assert(ids, []int64{1, 2, 3, 4, 5})
 
-		t_users := make([]t_user, 0)
-		err = result.Structs(&t_users)
-		if err != nil {
-			gtest.Fatal(err)
+		ids, err = db.BatchInsertAndGetIds(table, g.List{
+			{"id": 100, "passport": "user_100"},
+			{"id": 200, "passport": "user_200"},
+		}, "id")
+		gtest.Assert(err, nil)
+		gtest.Assert(ids, []int64{100, 200})
+
+		ids, err = db.BatchInsertAndGetIds(table, g.List{
+			{"passport": "user_201"},
+		}, "id")
+		gtest.Assert(err, nil)
+		gtest.Assert(ids, []int64{201})
+
+		value, err := db.Table(table).Where("id", 3).Value("passport")
+		gtest.Assert(err, nil)
+		gtest.Assert(value.String(), "user_3")
+	})
+	// The zero value of primary key is generated.
+	gtest.Case(t, func() {
+		type User struct {
+			Id       int
+			Passport string
 		}
+		ids, err := db.BatchInsertAndGetIds(table, []User{
+			{Passport: "user_202"},
+			{Passport: "user_203"},
+		}, "id")
+		gtest.Assert(err, nil)
+		gtest.Assert(ids, []int64{202, 203})
 
-		resultIntRecord := result.RecordKeyInt("id")
-		gtest.Assert(t_users[0].Id, resultIntRecord[id]["id"].Int())
-		gtest.Assert(t_users[0].Passport, resultIntRecord[id]["passport"].String())
-		gtest.Assert(t_users[0].Password, resultIntRecord[id]["password"].String())
-		gtest.Assert(t_users[0].NickName, resultIntRecord[id]["nickname"].String())
-		gtest.Assert(t_users[0].CreateTime, resultIntRecord[id]["create_time"].String())
+		value, err := db.Table(table).Where("id", 203).Value("passport")
+		gtest.Assert(err, nil)
+		gtest.Assert(value.String(), "user_203")
+	})
+	// It cannot mix given and generated values of primary key.
+	gtest.Case(t, func() {
+		_, err := db.BatchInsertAndGetIds(table, g.List{
+			{"id": 300, "passport": "user_300"},
+			{"id": 0, "passport": "user_301"},
+		}, "id")
+		gtest.AssertNE(err, nil)
 
+		count, err := db.Table(table).Where("passport", g.Slice{"user_300", "user_301"}).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 0)
 	})
 }
 
-func Test_DB_ToUintRecord(t *testing.T) {
-
+func Test_DB_BatchSave_Counts(t *testing.T) {
 	table := createInitTable()
 	defer dropTable(table)
-	_, err := db.Update(table, "create_time='2010-10-10 00:00:01'", "id=?", 1)
-	gtest.Assert(err, nil)
-
 	gtest.Case(t, func() {
-		id := 1
-		result, err := db.Table(table).Fields("*").Where("id = ?", id).Select()
-		if err != nil {
-			gtest.Fatal(err)
+		list := g.List{}
+		for i := 8; i <= 13; i++ {
+			list = append(list, g.Map{
+				"id":       i,
+				"passport": fmt.Sprintf("user_%d", i),
+				"password": fmt.Sprintf("pass_%d", i),
+				"nickname": fmt.Sprintf("new_%d", i),
+			})
 		}
+		r, err := db.BatchSave(table, list, 4)
+		gtest.Assert(err, nil)
+		s, ok := r.(gdb.SaveResult)
+		gtest.Assert(ok, true)
+		gtest.Assert(s.InsertedRows(), 3)
+		gtest.Assert(s.UpdatedRows(), 3)
+		gtest.Assert(s.SavedRows(), 0)
+		n, _ := r.RowsAffected()
+		gtest.Assert(n, 9)
+	})
+}
 
-		type t_user struct {
-			Id         int
-			Passport   string
-			Password   string
-			NickName   string
-			CreateTime string
-		}
+func Test_DB_BatchSaveAndGetIds(t *testing.T) {
+	name := "save_ids_test"
+	dropTable(name)
+	defer dropTable(name)
+	_, err := db.Exec(fmt.Sprintf(`
+		CREATE TABLE %s (
+		id       int(10) unsigned NOT NULL AUTO_INCREMENT,
+		passport varchar(45) NOT NULL,
+		nickname varchar(45) NULL,
+		PRIMARY KEY (id),
+		UNIQUE KEY (passport)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+	`, name))
+	if err != nil {
+		gtest.Fatal(err)
+	}
+	gtest.Case(t, func() {
+		_, err := db.Insert(name, g.List{
+			{"id": 10, "passport": "user_10", "nickname": "name_10"},
+			{"id": 20, "passport": "user_20", "nickname": "name_20"},
+		})
+		gtest.Assert(err, nil)
 
-		t_users := make([]t_user, 0)
-		err = result.Structs(&t_users)
-		if err != nil {
-			gtest.Fatal(err)
-		}
+		ids, err := db.BatchSaveAndGetIds(name, g.List{
+			{"passport": "user_20", "nickname": "new_20"},
+			{"passport": "user_new", "nickname": "name_new"},
+			{"passport": "user_10", "nickname": "new_10"},
+		}, "id", "passport")
+		gtest.Assert(err, nil)
+		gtest.Assert(len(ids), 3)
+		gtest.Assert(ids[0], 20)
+		gtest.Assert(ids[2], 10)
+		gtest.AssertGT(ids[1], 20)
 
-		resultUintRecord := result.RecordKeyUint("id")
-		gtest.Assert(t_users[0].Id, resultUintRecord[uint(id)]["id"].Int())
-		gtest.Assert(t_users[0].Passport, resultUintRecord[uint(id)]["passport"].String())
-		gtest.Assert(t_users[0].Password, resultUintRecord[uint(id)]["password"].String())
-		gtest.Assert(t_users[0].NickName, resultUintRecord[uint(id)]["nickname"].String())
-		gtest.Assert(t_users[0].CreateTime, resultUintRecord[uint(id)]["create_time"].String())
+		one, err := db.Table(name).Where("id", ids[1]).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["passport"].String(), "user_new")
+		one, err = db.Table(name).Where("id", 20).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["nickname"].String(), "new_20")
+	})
+	gtest.Case(t, func() {
+		_, err := db.BatchSaveAndGetIds(name, g.List{
+			{"nickname": "name"},
+		}, "id", "passport")
+		gtest.AssertNE(err, nil)
 	})
 }
 
@@ -7,17 +7,21 @@
 package gdb_test
 
 import (
+	"bytes"
 	"database/sql"
 	"fmt"
 	"github.com/gogf/gf/container/gmap"
 	"github.com/gogf/gf/util/gutil"
 	"testing"
+	"time"
 
 	"github.com/gogf/gf/database/gdb"
 
 	"github.com/gogf/gf/frame/g"
+	"github.com/gogf/gf/os/glog"
 	"github.com/gogf/gf/os/gtime"
 	"github.com/gogf/gf/test/gtest"
+	"github.com/gogf/gf/text/gstr"
 )
 
 func Test_Model_Insert(t *testing.T) {
@@ -120,6 +124,19 @@ func Test_Model_InsertIgnore(t *testing.T) {
 		}).InsertIgnore()
 		gtest.Assert(err, nil)
 	})
+	gtest.Case(t, func() {
+		result, err := db.Table(table).Filter().InsertIgnore(g.List{
+			{"id": 1, "passport": "t1", "password": "pass_1", "nickname": "name_1", "create_time": gtime.Now().String()},
+			{"id": 11, "passport": "t11", "password": "pass_11", "nickname": "name_11", "create_time": gtime.Now().String()},
+		})
+		gtest.Assert(err, nil)
+		n, _ := result.RowsAffected()
+		gtest.Assert(n, 1)
+
+		value, err := db.Table(table).Fields("passport").Where("id", 1).Value()
+		gtest.Assert(err, nil)
+		gtest.Assert(value.String(), "user_1")
+	})
 }
 
 func Test_Model_Batch(t *testing.T) {
@@ -222,6 +239,52 @@ func Test_Model_Save(t *testing.T) {
 	})
 }
 
+func Test_Model_Save_CompositeKey(t *testing.T) {
+	table := "user_group_test"
+	dropTable(table)
+	defer dropTable(table)
+	_, err := db.Exec(fmt.Sprintf(`
+		CREATE TABLE %s (
+		uid  int(10) unsigned NOT NULL,
+		gid  int(10) unsigned NOT NULL,
+		role varchar(45) NOT NULL,
+		PRIMARY KEY (uid, gid)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+	`, table))
+	if err != nil {
+		gtest.Fatal(err)
+	}
+	gtest.Case(t, func() {
+		_, err := db.Table(table).OnConflict("uid", "gid").Data(g.List{
+			{"uid": 1, "gid": 1, "role": "owner"},
+			{"uid": 1, "gid": 2, "role": "member"},
+		}).Save()
+		gtest.Assert(err, nil)
+
+		_, err = db.Table(table).OnConflict("uid", "gid").Data(g.Map{
+			"uid": 1, "gid": 2, "role": "admin",
+		}).Save()
+		gtest.Assert(err, nil)
+
+		_, err = db.Table(table).OnConflict("uid", "gid").Data(g.Map{
+			"uid": 2, "gid": 2, "role": "member",
+		}).Save()
+		gtest.Assert(err, nil)
+
+		count, err := db.Table(table).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 3)
+
+		value, err := db.Table(table).Fields("role").Where("uid=1 AND gid=2").Value()
+		gtest.Assert(err, nil)
+		gtest.Assert(value.String(), "admin")
+
+		value, err = db.Table(table).Fields("role").Where("uid=1 AND gid=1").Value()
+		gtest.Assert(err, nil)
+		gtest.Assert(value.String(), "owner")
+	})
+}
+
 func Test_Model_Update(t *testing.T) {
 	table := createInitTable()
 	defer dropTable(table)
@@ -1383,6 +1446,31 @@ func Test_Model_WherePri(t *testing.T) {
 	})
 }
 
+func Test_Model_UpdateAndCount(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		n, err := db.Table(table).Data("nickname", "T").Where("id>?", SIZE-2).UpdateAndCount()
+		gtest.Assert(err, nil)
+		gtest.Assert(n, 2)
+
+		n, err = db.Table(table).UpdateAndCount(g.Map{"nickname": "T1"}, "id", 1)
+		gtest.Assert(err, nil)
+		gtest.Assert(n, 1)
+
+		_, err = db.Table(table).UpdateAndCount()
+		gtest.AssertNE(err, nil)
+
+		n, err = db.Table(table).Where("nickname", "T").DeleteAndCount()
+		gtest.Assert(err, nil)
+		gtest.Assert(n, 2)
+
+		n, err = db.Table(table).DeleteAndCount("id", SIZE)
+		gtest.Assert(err, nil)
+		gtest.Assert(n, 0)
+	})
+}
+
 func Test_Model_Delete(t *testing.T) {
 	table := createInitTable()
 	defer dropTable(table)
@@ -1568,6 +1656,124 @@ func Test_Model_Option_Map(t *testing.T) {
 		gtest.AssertNE(one["passport"].String(), "")
 		gtest.AssertNE(one["passport"].String(), "123")
 	})
+
+	// Struct
+	gtest.Case(t, func() {
+		table := createTable()
+		defer dropTable(table)
+		type User struct {
+			Id         int
+			Passport   string
+			Password   string
+			Nickname   string
+			CreateTime *gtime.Time
+		}
+		r, err := db.Table(table).OmitEmpty().Data(User{Passport: "user_1", Password: "pass_1"}).Insert()
+		gtest.Assert(err, nil)
+		id, _ := r.LastInsertId()
+		gtest.Assert(id, 1)
+
+		one, err := db.Table(table).Where("id", id).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["passport"].String(), "user_1")
+		gtest.Assert(one["nickname"].IsNil(), true)
+		gtest.Assert(one["create_time"].IsNil(), true)
+
+		_, err = db.Table(table).OmitEmpty().Data(User{Nickname: "name_1"}).Where("id", id).Update()
+		gtest.Assert(err, nil)
+		one, err = db.Table(table).Where("id", id).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["passport"].String(), "user_1")
+		gtest.Assert(one["nickname"].String(), "name_1")
+	})
+}
+
+func Test_Model_Fields_Alias(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		one, err := db.Table(table).Fields("id AS order, nickname group").Where("id", 1).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["order"].Int(), 1)
+		gtest.Assert(one["group"].String(), "name_1")
+	})
+	gtest.Case(t, func() {
+		one, err := db.Table(table).Fields("COUNT(*) AS order").One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["order"].Int(), SIZE)
+	})
+}
+
+func Test_Model_ColumnOrder(t *testing.T) {
+	table := createTable()
+	defer dropTable(table)
+	buffer := bytes.NewBuffer(nil)
+	logger := glog.New()
+	logger.SetWriter(buffer)
+	logger.SetLevel(glog.LEVEL_ALL)
+	oldLogger := db.GetLogger()
+	db.SetLogger(logger)
+	db.SetDebug(true)
+	defer func() {
+		db.SetLogger(oldLogger)
+		db.SetDebug(false)
+	}()
+	gtest.Case(t, func() {
+		buffer.Reset()
+		_, err := db.Table(table).ColumnOrder("nickname", "passport", "id").Data(g.Map{
+			"id":       1,
+			"passport": "user_1",
+			"nickname": "name_1",
+		}).Insert()
+		gtest.Assert(err, nil)
+		gtest.Assert(gstr.Contains(buffer.String(), fmt.Sprintf(
+			"INSERT INTO `%s`(`nickname`,`passport`,`id`) VALUES('name_1','user_1',1)", table,
+		)), true)
+	})
+	gtest.Case(t, func() {
+		buffer.Reset()
+		_, err := db.Table(table).ColumnOrder("nickname", "id").Data(g.List{
+			{"id": 2, "passport": "user_2", "nickname": "name_2"},
+			{"id": 3, "passport": "user_3", "nickname": "name_3"},
+		}).Insert()
+		gtest.AssertNE(err, nil)
+
+		_, err = db.Table(table).ColumnOrder("nickname", "id").Option(gdb.OPTION_APPENDCOLUMNS).Data(g.List{
+			{"id": 2, "passport": "user_2", "nickname": "name_2"},
+			{"id": 3, "passport": "user_3", "nickname": "name_3"},
+		}).Insert()
+		gtest.Assert(err, nil)
+		gtest.Assert(gstr.Contains(buffer.String(), fmt.Sprintf(
+			"INSERT INTO `%s`(`nickname`,`id`,`passport`) VALUES('name_2',2,'user_2'),('name_3',3,'user_3')", table,
+		)), true)
+	})
+}
+
+func Test_Model_Where_EmptySlice(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		count, err := db.Table(table).Where("id IN(?)", g.Slice{}).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 0)
+
+		count, err = db.Table(table).Where("id NOT IN(?)", g.Slice{}).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, SIZE)
+
+		count, err = db.Table(table).Where(g.Map{"id": g.Slice{}}).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 0)
+	})
+	gtest.Case(t, func() {
+		count, err := db.Table(table).Where("id IN(?)", g.Slice{1}).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 1)
+
+		count, err = db.Table(table).Where("id NOT IN(?)", g.Slice{1}).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, SIZE-1)
+	})
 }
 
 func Test_Model_Option_List(t *testing.T) {
@@ -1698,6 +1904,59 @@ func Test_Model_FieldsEx(t *testing.T) {
 	})
 }
 
+func Test_Model_FieldsOf(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	type User struct {
+		Id       int
+		NickName string `orm:"nickname"`
+		Pass     string `orm:"password"`
+		Passport string `orm:"-"`
+		Extra    string
+	}
+	gtest.Case(t, func() {
+		one, err := db.Table(table).FieldsOf(&User{}).Where("id", 1).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(len(one), 3)
+		gtest.Assert(one["id"].Int(), 1)
+		gtest.Assert(one["nickname"].String(), "name_1")
+		gtest.Assert(one["password"].String(), "pass_1")
+	})
+	gtest.Case(t, func() {
+		var users []*User
+		err := db.Table(table).FieldsOf(users).Where("id<?", 3).Order("id asc").Structs(&users)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(users), 2)
+		gtest.Assert(users[1].Id, 2)
+		gtest.Assert(users[1].NickName, "name_2")
+		gtest.Assert(users[1].Pass, "pass_2")
+		gtest.Assert(users[1].Passport, "")
+	})
+	// Embedded struct and case-insensitive mapping.
+	gtest.Case(t, func() {
+		type Base struct {
+			ID         int
+			CreateTime string
+		}
+		type Entity struct {
+			Base
+			PASSPORT string
+		}
+		one, err := db.Table(table).FieldsOf(Entity{}).Where("id", 1).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(len(one), 3)
+		gtest.Assert(one["id"].Int(), 1)
+		gtest.Assert(one["passport"].String(), "user_1")
+		gtest.Assert(one["create_time"].IsEmpty(), false)
+	})
+	// Nothing mapped.
+	gtest.Case(t, func() {
+		one, err := db.Table(table).FieldsOf(&struct{ Extra string }{}).Where("id", 1).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(len(one), 5)
+	})
+}
+
 func Test_Model_FieldsStr(t *testing.T) {
 	table := createTable()
 	defer dropTable(table)
@@ -1896,3 +2155,217 @@ func Test_Model_Schema2(t *testing.T) {
 		gtest.Assert(v.String(), "")
 	})
 }
+
+func Test_Model_NoQuote(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		collector := db.CatchSql()
+		defer collector.Close()
+		one, err := db.NoQuote().Table(table).Fields("count(*) total, MAX(id) max_id").Where("id>?", 3).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["total"].Int(), SIZE-3)
+		gtest.Assert(one["max_id"].Int(), SIZE)
+
+		_, err = db.NoQuote().Table(table).Data(g.Map{"id": SIZE + 1, "passport": "user_11"}).Insert()
+		gtest.Assert(err, nil)
+
+		sqls := collector.Retrieve()
+		gtest.Assert(len(sqls) >= 2, true)
+		for _, s := range sqls {
+			if gstr.Contains(s.Sql, table) && !gstr.HasPrefix(s.Sql, "SHOW") {
+				gtest.Assert(gstr.Contains(s.Sql, "`"), false)
+			}
+		}
+		count, err := db.Table(table).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, SIZE+1)
+	})
+}
+
+func Test_Model_TableRedirect(t *testing.T) {
+	table := createInitTable()
+	shadow := "_" + table + "_new"
+	defer dropTable(table)
+	_, err := db.Exec(fmt.Sprintf("CREATE TABLE `%s` LIKE `%s`", shadow, table))
+	gtest.Assert(err, nil)
+	defer dropTable(shadow)
+	gtest.Case(t, func() {
+		db.SetTableRedirect(table, shadow)
+		defer db.SetTableRedirect(table, "")
+
+		_, err := db.Table(table).Data(g.Map{"id": 1, "passport": "shadow_user_1"}).Insert()
+		gtest.Assert(err, nil)
+		count, err := db.Table(table).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 1)
+		value, err := db.Table(table).Fields("passport").Where("id", 1).Value()
+		gtest.Assert(err, nil)
+		gtest.Assert(value.String(), "shadow_user_1")
+
+		// The DB objects created from it are redirected too.
+		count, err = db.WithSqlComment("shadow").Table(table).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 1)
+	})
+	gtest.Case(t, func() {
+		count, err := db.Table(table).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, SIZE)
+		count, err = db.Table(shadow).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 1)
+	})
+	// The new columns of the shadow table are not filtered.
+	gtest.Case(t, func() {
+		_, err := db.Exec(fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN nickname_new varchar(45)", shadow))
+		gtest.Assert(err, nil)
+		db.SetTableRedirect(table, shadow)
+		defer db.SetTableRedirect(table, "")
+		db.SetFilterUnknownColumns(true)
+		defer db.SetFilterUnknownColumns(false)
+
+		fields, err := db.TableFields(table)
+		gtest.Assert(err, nil)
+		gtest.AssertNE(fields["nickname_new"], nil)
+		_, err = db.Table(table).Data(g.Map{"id": 2, "passport": "shadow_user_2", "nickname_new": "name_new_2"}).Insert()
+		gtest.Assert(err, nil)
+		value, err := db.Table(table).Fields("nickname_new").Where("id", 2).Value()
+		gtest.Assert(err, nil)
+		gtest.Assert(value.String(), "name_new_2")
+	})
+}
+
+func Test_Model_TableResolver(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	_, err := db.Exec(fmt.Sprintf("CREATE TABLE `%s`.`%s` LIKE `%s`.`%s`", SCHEMA2, table, SCHEMA1, table))
+	gtest.Assert(err, nil)
+	defer db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS `%s`.`%s`", SCHEMA2, table))
+	_, err = db.Exec(fmt.Sprintf(
+		"INSERT INTO `%s`.`%s`(id, passport) VALUES(1, 'tenant_user_1')", SCHEMA2, table,
+	))
+	gtest.Assert(err, nil)
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE `%s`.`%s` ADD COLUMN tenant_code varchar(45)", SCHEMA2, table))
+	gtest.Assert(err, nil)
+
+	resolver := func(table string) string {
+		return SCHEMA2 + "." + table
+	}
+	gtest.Case(t, func() {
+		tenant := db.WithTableResolver(resolver)
+		count, err := tenant.Table(table).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 1)
+		one, err := tenant.Table(table).Where("id", 1).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["passport"].String(), "tenant_user_1")
+
+		_, err = tenant.Table(table).Data(g.Map{"id": 2, "passport": "tenant_user_2"}).Insert()
+		gtest.Assert(err, nil)
+		_, err = tenant.Table(table).Data("nickname", "tenant_name_2").Where("id", 2).Update()
+		gtest.Assert(err, nil)
+		value, err := tenant.Table(table).Fields("nickname").Where("id", 2).Value()
+		gtest.Assert(err, nil)
+		gtest.Assert(value.String(), "tenant_name_2")
+
+		// The original object is not affected.
+		count, err = db.Table(table).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, SIZE)
+		value, err = db.Table(table).Fields("nickname").Where("id", 2).Value()
+		gtest.Assert(err, nil)
+		gtest.Assert(value.String(), "name_2")
+
+		// The schema-qualified table is not resolved.
+		count, err = tenant.Table(SCHEMA1 + "." + table).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, SIZE)
+	})
+	gtest.Case(t, func() {
+		db.SetTableResolver(resolver)
+		count, err := db.Table(table).Count()
+		db.SetTableResolver(nil)
+		gtest.Assert(err, nil)
+		gtest.Assert(count, 1)
+
+		count, err = db.Table(table).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, SIZE)
+	})
+	// The table fields are retrieved from the resolved table.
+	gtest.Case(t, func() {
+		tenant := db.WithTableResolver(resolver)
+		fields, err := tenant.TableFields(table)
+		gtest.Assert(err, nil)
+		gtest.AssertNE(fields["tenant_code"], nil)
+		fields, err = db.TableFields(table)
+		gtest.Assert(err, nil)
+		gtest.Assert(fields["tenant_code"], nil)
+
+		tenant.SetFilterUnknownColumns(true)
+		defer tenant.SetFilterUnknownColumns(false)
+		_, err = tenant.Table(table).Data(g.Map{
+			"id":          3,
+			"passport":    "tenant_user_3",
+			"tenant_code": "t001",
+			"extra":       "extra",
+		}).Insert()
+		gtest.Assert(err, nil)
+		value, err := tenant.Table(table).Fields("tenant_code").Where("id", 3).Value()
+		gtest.Assert(err, nil)
+		gtest.Assert(value.String(), "t001")
+	})
+}
+
+func Test_Model_MasterBreaker(t *testing.T) {
+	gtest.Case(t, func() {
+		gdb.AddConfigNode("test_model_breaker", gdb.ConfigNode{
+			Host: "127.0.0.1",
+			Port: "1",
+			User: "root",
+			Name: "test",
+			Type: "mysql",
+		})
+		breakerDb, err := gdb.New("test_model_breaker")
+		gtest.Assert(err, nil)
+		breakerDb.SetMasterBreaker(1, time.Minute, time.Minute)
+		// The connection failure opens the breaker.
+		_, err = breakerDb.Table("user").Master().All()
+		gtest.AssertNE(err, nil)
+		// The operations fail fast with the error instead of panic.
+		_, err = breakerDb.Table("user").Data(g.Map{"id": 1}).Insert()
+		gtest.Assert(err, gdb.ErrMasterUnavailable)
+		_, err = breakerDb.Table("user").Data(g.Map{"nickname": "T"}).Where("id", 1).Update()
+		gtest.Assert(err, gdb.ErrMasterUnavailable)
+		_, err = breakerDb.Table("user").Where("id", 1).Delete()
+		gtest.Assert(err, gdb.ErrMasterUnavailable)
+		_, err = breakerDb.Table("user").Master().All()
+		gtest.Assert(err, gdb.ErrMasterUnavailable)
+	})
+}
+
+func Test_Model_MaxStaleness(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	db.SetReplicaLagMonitor(10 * time.Millisecond)
+	defer db.SetReplicaLagMonitor(0)
+	gtest.Case(t, func() {
+		// There's no slave node configured, so it reads from the master node.
+		one, err := db.Table(table).MaxStaleness(time.Second).Where("id", 1).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["passport"].String(), "user_1")
+
+		count, err := db.Table(table).Slave().MaxStaleness(time.Second).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(count, SIZE)
+
+		n, err := db.Table(table).MaxStaleness(0).Data("nickname", "T1").Where("id", 1).UpdateAndCount()
+		gtest.Assert(err, nil)
+		gtest.Assert(n, 1)
+
+		value, err := db.Table(table).MaxStaleness(0).Fields("nickname").Where("id", 1).Value()
+		gtest.Assert(err, nil)
+		gtest.Assert(value.String(), "T1")
+	})
+}
@@ -7,6 +7,7 @@
 package gdb_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/gogf/gf/frame/g"
@@ -96,3 +97,61 @@ func Test_Model_Inherit_MapToStruct(t *testing.T) {
 	})
 
 }
+
+func Test_Model_Inherit_Pointer_MapToStruct(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+
+	type Ids struct {
+		Id int `orm:"id"`
+	}
+	type Base struct {
+		*Ids
+		CreatedAt *gtime.Time `orm:"create_time"`
+	}
+	// Embedded by pointer.
+	gtest.Case(t, func() {
+		type User struct {
+			*Base
+			Passport string
+			Nickname string `orm:"nickname"`
+		}
+		user := new(User)
+		err := db.Table(table).Where("id", 1).Struct(user)
+		gtest.Assert(err, nil)
+		gtest.AssertNE(user.Base, nil)
+		gtest.AssertNE(user.Ids, nil)
+		gtest.Assert(user.Id, 1)
+		gtest.Assert(user.CreatedAt.String(), "2018-10-24 10:00:00")
+		gtest.Assert(user.Passport, "user_1")
+		gtest.Assert(user.Nickname, "name_1")
+	})
+	// Embedded by value, and the struct pointer is allocated.
+	gtest.Case(t, func() {
+		type User struct {
+			Base
+			Passport string
+		}
+		var user *User
+		err := db.GetStruct(&user, fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), 2)
+		gtest.Assert(err, nil)
+		gtest.AssertNE(user, nil)
+		gtest.Assert(user.Id, 2)
+		gtest.Assert(user.CreatedAt.String(), "2018-10-24 10:00:00")
+		gtest.Assert(user.Passport, "user_2")
+	})
+	// Slice of structs.
+	gtest.Case(t, func() {
+		type User struct {
+			*Base
+			Passport string
+		}
+		var users []*User
+		err := db.Table(table).Where("id<?", 3).Order("id asc").Structs(&users)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(users), 2)
+		gtest.Assert(users[0].Id, 1)
+		gtest.Assert(users[1].Id, 2)
+		gtest.Assert(users[1].Passport, "user_2")
+	})
+}
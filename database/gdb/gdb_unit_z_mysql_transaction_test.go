@@ -7,9 +7,12 @@
 package gdb_test
 
 import (
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/gogf/gf/database/gdb"
 	"github.com/gogf/gf/frame/g"
 	"github.com/gogf/gf/os/gtime"
 	"github.com/gogf/gf/test/gtest"
@@ -85,6 +88,59 @@ func Test_TX_Rollback(t *testing.T) {
 	}
 }
 
+func Test_TX_ReadOnly(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+	gtest.Case(t, func() {
+		tx, err := db.Begin(true)
+		gtest.Assert(err, nil)
+		defer tx.Rollback()
+		gtest.Assert(tx.IsReadOnly(), true)
+
+		count, err := tx.GetCount(fmt.Sprintf("SELECT * FROM %s", table))
+		gtest.Assert(err, nil)
+		gtest.Assert(count, SIZE)
+		one, err := tx.Table(table).Where("id", 1).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["passport"].String(), "user_1")
+
+		_, err = tx.Update(table, g.Map{"nickname": "read_only"}, "id=?", 1)
+		gtest.Assert(err, gdb.ErrReadOnlyTx)
+		_, err = tx.Table(table).Data(g.Map{"id": SIZE + 1, "passport": "user_11"}).Insert()
+		gtest.Assert(err, gdb.ErrReadOnlyTx)
+		_, err = tx.Exec(fmt.Sprintf("DELETE FROM %s", table))
+		gtest.Assert(err, gdb.ErrReadOnlyTx)
+
+		// Savepoints are allowed.
+		err = tx.Run(func(tx *gdb.TX) error {
+			_, err := tx.GetOne(fmt.Sprintf("SELECT * FROM %s WHERE id=?", table), 1)
+			return err
+		})
+		gtest.Assert(err, nil)
+	})
+	// The writing statements not executed by Exec are rejected by the database,
+	// which does not apply to sqlite and mssql as their drivers do not support read-only access mode.
+	if configNode.Type != "sqlite" && configNode.Type != "mssql" {
+		gtest.Case(t, func() {
+			tx, err := db.Begin(true)
+			gtest.Assert(err, nil)
+			defer tx.Rollback()
+			_, err = tx.GetAll(fmt.Sprintf("DELETE FROM %s WHERE id=?", table), 1)
+			gtest.AssertNE(err, nil)
+
+			count, err := db.Table(table).Count()
+			gtest.Assert(err, nil)
+			gtest.Assert(count, SIZE)
+		})
+	}
+	gtest.Case(t, func() {
+		tx, err := db.Begin()
+		gtest.Assert(err, nil)
+		defer tx.Rollback()
+		gtest.Assert(tx.IsReadOnly(), false)
+	})
+}
+
 func Test_TX_Prepare(t *testing.T) {
 	tx, err := db.Begin()
 	if err != nil {
@@ -558,6 +614,63 @@ func Test_TX_GetStructs(t *testing.T) {
 	})
 }
 
+func Test_TX_Helpers(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+
+	gtest.Case(t, func() {
+		tx, err := db.Begin()
+		if err != nil {
+			gtest.Error(err)
+		}
+		defer tx.Rollback()
+		// The record inserted on transaction is only visible on the same transaction.
+		_, err = tx.Insert(table, g.Map{
+			"id":       SIZE + 1,
+			"passport": "t_tx",
+			"nickname": "name_tx",
+		})
+		gtest.Assert(err, nil)
+
+		ids, err := tx.GetInts(fmt.Sprintf("SELECT id FROM %s WHERE id>? ORDER BY id", table), SIZE-1)
+		gtest.Assert(err, nil)
+		gtest.Assert(ids, []int{SIZE, SIZE + 1})
+
+		names, err := tx.GetStrings(fmt.Sprintf("SELECT nickname FROM %s WHERE id=?", table), SIZE+1)
+		gtest.Assert(err, nil)
+		gtest.Assert(names, []string{"name_tx"})
+
+		array, err := db.GetArray(fmt.Sprintf("SELECT id FROM %s WHERE id=?", table), SIZE+1)
+		gtest.Assert(err, nil)
+		gtest.Assert(len(array), 0)
+
+		type User struct {
+			Id       int
+			Passport string
+			NickName string
+		}
+		user := new(User)
+		gtest.Assert(tx.Find(user, table, SIZE+1), nil)
+		gtest.Assert(user.Passport, "t_tx")
+		gtest.Assert(user.NickName, "name_tx")
+
+		result, total, err := tx.Paginate(fmt.Sprintf("SELECT * FROM %s ORDER BY id", table), 2, SIZE)
+		gtest.Assert(err, nil)
+		gtest.Assert(total, SIZE+1)
+		gtest.Assert(len(result), 1)
+		gtest.Assert(result[0]["id"].Int(), SIZE+1)
+
+		result, total, values, err := tx.GetPageWithAggregates(
+			1, 2, table, "id>? ORDER BY id DESC", g.Slice{SIZE - 1}, g.MapStrStr{"id_max": "MAX(id)"},
+		)
+		gtest.Assert(err, nil)
+		gtest.Assert(total, 2)
+		gtest.Assert(len(result), 2)
+		gtest.Assert(result[0]["id"].Int(), SIZE+1)
+		gtest.Assert(values["id_max"].Int(), SIZE+1)
+	})
+}
+
 func Test_TX_GetScan(t *testing.T) {
 	table := createInitTable()
 	defer dropTable(table)
@@ -715,3 +828,109 @@ func Test_TX_Delete(t *testing.T) {
 	})
 
 }
+
+func Test_Transaction(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+
+	gtest.Case(t, func() {
+		err := db.Transaction(func(tx *gdb.TX) error {
+			if _, err := tx.Update(table, g.Map{"nickname": "outer"}, "id=?", 1); err != nil {
+				return err
+			}
+			// Partial rollback.
+			err := tx.Run(func(tx *gdb.TX) error {
+				if _, err := tx.Update(table, g.Map{"nickname": "inner"}, "id IN(?)", g.Slice{1, 2}); err != nil {
+					return err
+				}
+				return errors.New("inner error")
+			})
+			gtest.Assert(err.Error(), "inner error")
+			return tx.Run(func(tx *gdb.TX) error {
+				_, err := tx.Delete(table, "id=?", 3)
+				return err
+			})
+		})
+		gtest.Assert(err, nil)
+
+		result, err := db.Table(table).Where("id<=?", 3).Order("id").All()
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), 2)
+		gtest.Assert(result[0]["nickname"].String(), "outer")
+		gtest.Assert(result[1]["nickname"].String(), "name_2")
+	})
+	gtest.Case(t, func() {
+		err := db.Transaction(func(tx *gdb.TX) error {
+			if _, err := tx.Delete(table, "id=?", 4); err != nil {
+				return err
+			}
+			return errors.New("outer error")
+		})
+		gtest.Assert(err.Error(), "outer error")
+
+		n, err := db.Table(table).Where("id", 4).Count()
+		gtest.Assert(err, nil)
+		gtest.Assert(n, 1)
+	})
+}
+
+func Test_Transaction_Lock(t *testing.T) {
+	table := createInitTable()
+	defer dropTable(table)
+
+	gtest.Case(t, func() {
+		var (
+			locked  = make(chan struct{})
+			updated = make(chan time.Duration)
+		)
+		go func() {
+			<-locked
+			start := time.Now()
+			_, err := db.Update(table, g.Map{"nickname": "updated"}, "id=?", 1)
+			gtest.Assert(err, nil)
+			updated <- time.Since(start)
+		}()
+		err := db.Transaction(func(tx *gdb.TX) error {
+			one, err := tx.Table(table).Where("id", 1).LockUpdate().One()
+			if err != nil {
+				return err
+			}
+			gtest.Assert(one["nickname"].String(), "name_1")
+			close(locked)
+			time.Sleep(200 * time.Millisecond)
+			_, err = tx.Update(table, g.Map{"nickname": "locked"}, "id=?", 1)
+			return err
+		})
+		gtest.Assert(err, nil)
+		// The updating waits for the lock released.
+		gtest.AssertGE(int64(<-updated/time.Millisecond), 150)
+
+		value, err := db.Table(table).Where("id", 1).Value("nickname")
+		gtest.Assert(err, nil)
+		gtest.Assert(value.String(), "updated")
+	})
+	gtest.Case(t, func() {
+		err := db.Transaction(func(tx *gdb.TX) error {
+			all, err := tx.Table(table).Where("id<=?", 2).LockShared().All()
+			gtest.Assert(len(all), 2)
+			return err
+		})
+		gtest.Assert(err, nil)
+	})
+	// The locking query does not read from cache.
+	gtest.Case(t, func() {
+		one, err := db.Table(table).Cache(time.Hour, "lock_cache").Where("id", 2).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["nickname"].String(), "name_2")
+		_, err = db.Exec(fmt.Sprintf("UPDATE %s SET nickname='uncached' WHERE id=2", table))
+		gtest.Assert(err, nil)
+
+		err = db.Transaction(func(tx *gdb.TX) error {
+			one, err := tx.Table(table).Cache(time.Hour, "lock_cache").Where("id", 2).LockUpdate().One()
+			gtest.Assert(one["nickname"].String(), "uncached")
+			return err
+		})
+		gtest.Assert(err, nil)
+		db.ClearCache("lock_cache")
+	})
+}
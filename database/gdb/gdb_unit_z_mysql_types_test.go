@@ -9,8 +9,10 @@ package gdb_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/gogf/gf/frame/g"
+	"github.com/gogf/gf/os/gtime"
 
 	"github.com/gogf/gf/test/gtest"
 )
@@ -68,3 +70,205 @@ func Test_Types(t *testing.T) {
 		gtest.Assert(one["tinyint"].Bool(), data["tinyint"])
 	})
 }
+
+func Test_Types_Json(t *testing.T) {
+	table := "types_json"
+	if _, err := db.Exec(fmt.Sprintf(`
+    CREATE TABLE IF NOT EXISTS %s (
+        id int(10) unsigned NOT NULL AUTO_INCREMENT,
+        attrs json NULL,
+        tags json NULL,
+        PRIMARY KEY (id)
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+    `, table)); err != nil {
+		gtest.Error(err)
+	}
+	defer dropTable(table)
+
+	type Item struct {
+		Id    int
+		Attrs map[string]interface{}
+		Tags  []string
+	}
+	gtest.Case(t, func() {
+		_, err := db.Insert(table, g.Map{
+			"id":    1,
+			"attrs": g.Map{"name": "john", "age": 18},
+			"tags":  g.SliceStr{"a", "b"},
+		})
+		gtest.Assert(err, nil)
+		_, err = db.Table(table).Data(g.Map{
+			"id":    2,
+			"attrs": g.Map{"name": "smith"},
+		}).Insert()
+		gtest.Assert(err, nil)
+
+		one, err := db.Table(table).Where("id", 1).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["tags"].Strings(), g.SliceStr{"a", "b"})
+
+		item := new(Item)
+		err = db.Table(table).Where("id", 1).Struct(item)
+		gtest.Assert(err, nil)
+		gtest.Assert(item.Attrs["name"], "john")
+		gtest.Assert(item.Attrs["age"], 18)
+		gtest.Assert(item.Tags, g.SliceStr{"a", "b"})
+	})
+	gtest.Case(t, func() {
+		_, err := db.Update(table, g.Map{"tags": g.SliceStr{"c"}}, "id=?", 2)
+		gtest.Assert(err, nil)
+
+		item := new(Item)
+		err = db.Table(table).Where("id", 2).Struct(item)
+		gtest.Assert(err, nil)
+		gtest.Assert(item.Attrs["name"], "smith")
+		gtest.Assert(item.Tags, g.SliceStr{"c"})
+	})
+}
+
+func Test_Types_Decimal(t *testing.T) {
+	table := "types_decimal"
+	if _, err := db.Exec(fmt.Sprintf(`
+    CREATE TABLE IF NOT EXISTS %s (
+        id int(10) unsigned NOT NULL AUTO_INCREMENT,
+        amount decimal(18,4) NOT NULL,
+        flag bit(1) NOT NULL,
+        PRIMARY KEY (id)
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+    `, table)); err != nil {
+		gtest.Error(err)
+	}
+	defer dropTable(table)
+
+	gtest.Case(t, func() {
+		_, err := db.Insert(table, g.List{
+			{"id": 1, "amount": "12345678901234.5678", "flag": 1},
+			{"id": 2, "amount": "0.0001", "flag": 0},
+		})
+		gtest.Assert(err, nil)
+
+		result, err := db.Table(table).Order("id").All()
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), 2)
+		gtest.Assert(result[0]["amount"].String(), "12345678901234.5678")
+		gtest.Assert(result[0]["flag"].Int(), 1)
+		gtest.Assert(result[0]["flag"].Bool(), true)
+		gtest.Assert(result[1]["amount"].String(), "0.0001")
+		gtest.Assert(result[1]["flag"].Int(), 0)
+		gtest.Assert(result[1]["flag"].Bool(), false)
+	})
+}
+
+func Test_Types_UnsignedBigint(t *testing.T) {
+	table := "types_unsigned_bigint"
+	if _, err := db.Exec(fmt.Sprintf(`
+    CREATE TABLE IF NOT EXISTS %s (
+        id bigint(20) unsigned NOT NULL,
+        PRIMARY KEY (id)
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+    `, table)); err != nil {
+		gtest.Error(err)
+	}
+	defer dropTable(table)
+
+	gtest.Case(t, func() {
+		_, err := db.Insert(table, g.List{
+			{"id": "18446744073709551615"},
+			{"id": "9223372036854775808"},
+			{"id": 1},
+		})
+		gtest.Assert(err, nil)
+
+		result, err := db.Table(table).Order("id").All()
+		gtest.Assert(err, nil)
+		gtest.Assert(len(result), 3)
+		gtest.Assert(result[0]["id"].Uint64(), 1)
+		gtest.Assert(result[1]["id"].Uint64(), uint64(9223372036854775808))
+		gtest.Assert(result[2]["id"].Uint64(), uint64(18446744073709551615))
+		gtest.Assert(result[2]["id"].String(), "18446744073709551615")
+	})
+}
+
+func Test_Types_Datetime(t *testing.T) {
+	table := "types_datetime"
+	if _, err := db.Exec(fmt.Sprintf(`
+    CREATE TABLE IF NOT EXISTS %s (
+        id int(10) unsigned NOT NULL AUTO_INCREMENT,
+        created datetime(6) NOT NULL,
+        updated timestamp(6) NOT NULL,
+        PRIMARY KEY (id)
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+    `, table)); err != nil {
+		gtest.Error(err)
+	}
+	defer dropTable(table)
+
+	gtest.Case(t, func() {
+		_, err := db.Insert(table, g.Map{
+			"id":      1,
+			"created": "2020-01-02 03:04:05.123456",
+			"updated": "2020-01-02 03:04:05.654321",
+		})
+		gtest.Assert(err, nil)
+
+		one, err := db.Table(table).One()
+		gtest.Assert(err, nil)
+		gtest.Assert(one["created"].String(), "2020-01-02 03:04:05.123456")
+		gtest.Assert(one["updated"].String(), "2020-01-02 03:04:05.654321")
+
+		type Item struct {
+			Id      int
+			Created time.Time
+			Updated *gtime.Time
+		}
+		var item *Item
+		gtest.Assert(one.Struct(&item), nil)
+		gtest.Assert(item.Created.Equal(time.Date(2020, 1, 2, 3, 4, 5, 123456000, time.Local)), true)
+		gtest.Assert(item.Created.Nanosecond(), 123456000)
+		gtest.Assert(item.Updated.Time.Equal(time.Date(2020, 1, 2, 3, 4, 5, 654321000, time.Local)), true)
+	})
+}
+
+func Test_Types_Binary(t *testing.T) {
+	table := "types_binary"
+	if _, err := db.Exec(fmt.Sprintf(`
+    CREATE TABLE IF NOT EXISTS %s (
+        id int(10) unsigned NOT NULL AUTO_INCREMENT,
+        content blob NOT NULL,
+        digest varbinary(16) NOT NULL,
+        PRIMARY KEY (id)
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+    `, table)); err != nil {
+		gtest.Error(err)
+	}
+	defer dropTable(table)
+
+	gtest.Case(t, func() {
+		content := make([]byte, 256)
+		for i := range content {
+			content[i] = byte(i)
+		}
+		digest := []byte{0xff, 0xfe, 0x00, 0x80, 0xc3, 0x28}
+		_, err := db.Insert(table, g.Map{
+			"id":      1,
+			"content": content,
+			"digest":  digest,
+		})
+		gtest.Assert(err, nil)
+
+		one, err := db.Table(table).Where("digest", digest).One()
+		gtest.Assert(err, nil)
+		gtest.AssertEQ(one["content"].Bytes(), content)
+		gtest.AssertEQ(one["digest"].Bytes(), digest)
+
+		type Item struct {
+			Id      int
+			Content []byte
+			Digest  []byte
+		}
+		var item *Item
+		gtest.Assert(one.Struct(&item), nil)
+		gtest.AssertEQ(item.Content, content)
+		gtest.AssertEQ(item.Digest, digest)
+	})
+}
@@ -0,0 +1,339 @@
+// Copyright 2020 gf Author(https://github.com/gogf/gf). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/gogf/gf.
+
+package gdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// WhereBuilder is the builder for composing where conditions programmatically.
+// It quotes the column names and uses place holders for all the values,
+// which is safe from sql injection for dynamic conditions.
+//
+// The builder can be used as the condition parameter of Update/Delete and Model.Where, eg:
+// NewWhereBuilder().Where("age", ">", 18).WhereIn("status", g.Slice{1, 2}).OrWhereNull("deleted_at")
+type WhereBuilder struct {
+	items []*whereBuilderItem
+}
+
+// whereBuilderItem is the item of WhereBuilder for each condition.
+type whereBuilderItem struct {
+	or      bool          // Joins the condition using "OR" or "AND".
+	column  string        // Column name.
+	clause  string        // Clause after the column, eg: "=?", " IN(?)", " IS NULL".
+	collate string        // Collation of the comparison, which is appended to the clause.
+	args    []interface{} // Arguments of the clause.
+	builder *WhereBuilder // Nested builder for grouped conditions.
+}
+
+var (
+	// whereBuilderColumnReg is the regular expression object for the column names of WhereBuilder,
+	// eg: "id", "u.id".
+	whereBuilderColumnReg = regexp.MustCompile(`^[a-zA-Z0-9\-_]+(\.[a-zA-Z0-9\-_]+)*$`)
+
+	// whereBuilderCollationReg is the regular expression object for the collation names of WhereBuilder,
+	// eg: "utf8mb4_bin", "Latin1_General_CS_AS", "en_US.utf8", "und-x-icu".
+	whereBuilderCollationReg = regexp.MustCompile(`^[a-zA-Z0-9\-_.@]+$`)
+
+	// whereBuilderOperators are the operators allowed for WhereBuilder.
+	whereBuilderOperators = map[string]struct{}{
+		"=": {}, "!=": {}, "<>": {}, ">": {}, ">=": {}, "<": {}, "<=": {},
+		"LIKE": {}, "NOT LIKE": {},
+	}
+)
+
+// NewWhereBuilder creates and returns a new WhereBuilder.
+func NewWhereBuilder() *WhereBuilder {
+	return &WhereBuilder{
+		items: make([]*whereBuilderItem, 0),
+	}
+}
+
+// Where adds condition "column operator value" joined with "AND".
+// The parameter <operator> can be one of: =, !=, <>, >, >=, <, <=, LIKE, NOT LIKE.
+//
+// Note that it panics if the <column> is not a valid column name or the <operator> is not allowed.
+func (b *WhereBuilder) Where(column string, operator string, value interface{}) *WhereBuilder {
+	return b.add(false, column, operator, value)
+}
+
+// OrWhere adds condition "column operator value" joined with "OR".
+// Also see Where.
+func (b *WhereBuilder) OrWhere(column string, operator string, value interface{}) *WhereBuilder {
+	return b.add(true, column, operator, value)
+}
+
+// WhereCollate adds condition "column operator value COLLATE collation" joined with "AND",
+// which is commonly used for case/accent sensitive or insensitive comparisons, eg:
+// WhereCollate("name", "=", "John", "utf8mb4_bin").
+// The COLLATE clause is built according to the driver of the DB passed to Build.
+//
+// Note that it panics if the <collation> is not a valid collation name.
+// Also see Where.
+func (b *WhereBuilder) WhereCollate(column string, operator string, value interface{}, collation string) *WhereBuilder {
+	return b.add(false, column, operator, value).collate(collation)
+}
+
+// OrWhereCollate adds condition "column operator value COLLATE collation" joined with "OR".
+// Also see WhereCollate.
+func (b *WhereBuilder) OrWhereCollate(column string, operator string, value interface{}, collation string) *WhereBuilder {
+	return b.add(true, column, operator, value).collate(collation)
+}
+
+// WhereBetween adds condition "column BETWEEN min AND max" joined with "AND", eg:
+// WhereBetween("create_time", "2020-01-01", "2020-01-31").
+func (b *WhereBuilder) WhereBetween(column string, min interface{}, max interface{}) *WhereBuilder {
+	return b.addClause(false, column, " BETWEEN ? AND ?", min, max)
+}
+
+// WhereGT adds condition "column>value" joined with "AND".
+func (b *WhereBuilder) WhereGT(column string, value interface{}) *WhereBuilder {
+	return b.addClause(false, column, ">?", value)
+}
+
+// WhereGTE adds condition "column>=value" joined with "AND".
+func (b *WhereBuilder) WhereGTE(column string, value interface{}) *WhereBuilder {
+	return b.addClause(false, column, ">=?", value)
+}
+
+// WhereLT adds condition "column<value" joined with "AND".
+func (b *WhereBuilder) WhereLT(column string, value interface{}) *WhereBuilder {
+	return b.addClause(false, column, "<?", value)
+}
+
+// WhereLTE adds condition "column<=value" joined with "AND".
+func (b *WhereBuilder) WhereLTE(column string, value interface{}) *WhereBuilder {
+	return b.addClause(false, column, "<=?", value)
+}
+
+// WhereLike adds condition "column LIKE pattern" joined with "AND", eg: WhereLike("nickname", "john%").
+// Note that the wildcard chars '%' and '_' in <pattern> are not escaped, use WhereContains/WhereHasPrefix/
+// WhereHasSuffix for the values from user input.
+func (b *WhereBuilder) WhereLike(column string, pattern interface{}) *WhereBuilder {
+	return b.addClause(false, column, " LIKE ?", pattern)
+}
+
+// WhereContains adds condition "column LIKE '%value%'" joined with "AND", in which the wildcard chars
+// of <value> are escaped, so that <value> is matched literally, eg: WhereContains("title", "50%").
+// Also see LikePattern.
+func (b *WhereBuilder) WhereContains(column string, value string) *WhereBuilder {
+	return b.addClause(false, column, " LIKE ? "+LIKE_ESCAPE, "%"+LikePattern(value)+"%")
+}
+
+// WhereHasPrefix adds condition "column LIKE 'value%'" joined with "AND", in which the wildcard chars
+// of <value> are escaped. Also see WhereContains.
+func (b *WhereBuilder) WhereHasPrefix(column string, value string) *WhereBuilder {
+	return b.addClause(false, column, " LIKE ? "+LIKE_ESCAPE, LikePattern(value)+"%")
+}
+
+// WhereHasSuffix adds condition "column LIKE '%value'" joined with "AND", in which the wildcard chars
+// of <value> are escaped. Also see WhereContains.
+func (b *WhereBuilder) WhereHasSuffix(column string, value string) *WhereBuilder {
+	return b.addClause(false, column, " LIKE ? "+LIKE_ESCAPE, "%"+LikePattern(value))
+}
+
+// WhereIn adds condition "column IN(values...)" joined with "AND".
+// The parameter <values> should be type of slice.
+func (b *WhereBuilder) WhereIn(column string, values interface{}) *WhereBuilder {
+	return b.addClause(false, column, " IN(?)", values)
+}
+
+// OrWhereIn adds condition "column IN(values...)" joined with "OR".
+func (b *WhereBuilder) OrWhereIn(column string, values interface{}) *WhereBuilder {
+	return b.addClause(true, column, " IN(?)", values)
+}
+
+// WhereNotIn adds condition "column NOT IN(values...)" joined with "AND".
+// The parameter <values> should be type of slice.
+func (b *WhereBuilder) WhereNotIn(column string, values interface{}) *WhereBuilder {
+	return b.addClause(false, column, " NOT IN(?)", values)
+}
+
+// WhereNull adds condition "column IS NULL" joined with "AND".
+func (b *WhereBuilder) WhereNull(column string) *WhereBuilder {
+	return b.addClause(false, column, " IS NULL")
+}
+
+// OrWhereNull adds condition "column IS NULL" joined with "OR".
+func (b *WhereBuilder) OrWhereNull(column string) *WhereBuilder {
+	return b.addClause(true, column, " IS NULL")
+}
+
+// WhereNotNull adds condition "column IS NOT NULL" joined with "AND".
+func (b *WhereBuilder) WhereNotNull(column string) *WhereBuilder {
+	return b.addClause(false, column, " IS NOT NULL")
+}
+
+// WhereStruct adds conditions "column=value" joined with "AND" for the non-zero attributes of struct
+// <pointer>, which is commonly used for the search conditions of which only the attributes set by the
+// user matter, eg: WhereStruct(&UserSearch{Name: "john"}) adds only condition "`name`=?".
+// The column name is the "orm" tag of the attribute, or else the attribute name. The attribute of
+// slice type adds condition "column IN(values...)", and the embedded struct is handled recursively.
+//
+// Note that it cannot distinguish the zero value set by the user from the unset one, so the
+// attributes of zero value, eg: 0, "", false, are always skipped. Use pointer attributes, of which
+// only nil is zero, for the zero values that matter, or use WhereStructStrict for all the attributes.
+func (b *WhereBuilder) WhereStruct(pointer interface{}) *WhereBuilder {
+	return b.addStruct(pointer, false)
+}
+
+// WhereStructStrict adds conditions "column=value" joined with "AND" for all the attributes of
+// struct <pointer>, including the ones of zero value, in which the nil pointer attribute adds
+// condition "column IS NULL". Also see WhereStruct.
+func (b *WhereBuilder) WhereStructStrict(pointer interface{}) *WhereBuilder {
+	return b.addStruct(pointer, true)
+}
+
+// WhereGroup adds the conditions of <builder> in parentheses joined with "AND".
+func (b *WhereBuilder) WhereGroup(builder *WhereBuilder) *WhereBuilder {
+	b.items = append(b.items, &whereBuilderItem{builder: builder})
+	return b
+}
+
+// OrWhereGroup adds the conditions of <builder> in parentheses joined with "OR".
+func (b *WhereBuilder) OrWhereGroup(builder *WhereBuilder) *WhereBuilder {
+	b.items = append(b.items, &whereBuilderItem{or: true, builder: builder})
+	return b
+}
+
+// Build builds and returns the condition string and its arguments using the quote chars of <db>.
+func (b *WhereBuilder) Build(db DB) (where string, args []interface{}) {
+	buffer := bytes.NewBuffer(nil)
+	for _, item := range b.items {
+		clause := ""
+		if item.builder != nil {
+			groupWhere, groupArgs := item.builder.Build(db)
+			if groupWhere == "" {
+				continue
+			}
+			clause = "(" + groupWhere + ")"
+			args = append(args, groupArgs...)
+		} else {
+			clause = db.quoteString(item.column) + item.clause
+			if item.collate != "" {
+				clause += db.getCollate(item.collate)
+			}
+			args = append(args, item.args...)
+		}
+		if buffer.Len() > 0 {
+			if item.or {
+				buffer.WriteString(" OR ")
+			} else {
+				buffer.WriteString(" AND ")
+			}
+		}
+		buffer.WriteString(clause)
+	}
+	return buffer.String(), args
+}
+
+// add adds condition "column operator value" to the builder.
+func (b *WhereBuilder) add(or bool, column string, operator string, value interface{}) *WhereBuilder {
+	operator = strings.ToUpper(strings.Join(strings.Fields(operator), " "))
+	if _, ok := whereBuilderOperators[operator]; !ok {
+		panic(fmt.Sprintf(`invalid operator "%s" for where builder`, operator))
+	}
+	if operator == "LIKE" || operator == "NOT LIKE" {
+		operator = " " + operator + " "
+	}
+	return b.addClause(or, column, operator+"?", value)
+}
+
+// collate sets the collation of the last added condition.
+func (b *WhereBuilder) collate(collation string) *WhereBuilder {
+	if !whereBuilderCollationReg.MatchString(collation) {
+		panic(fmt.Sprintf(`invalid collation "%s" for where builder`, collation))
+	}
+	b.items[len(b.items)-1].collate = collation
+	return b
+}
+
+// addStruct adds conditions "column=value" for the attributes of struct <pointer> to the builder,
+// which skips the attributes of zero value if <strict> is false.
+//
+// Note that it panics if <pointer> is not a struct or pointer of struct.
+func (b *WhereBuilder) addStruct(pointer interface{}, strict bool) *WhereBuilder {
+	rv := reflect.ValueOf(pointer)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf(`invalid struct "%T" for where builder`, pointer))
+	}
+	rt := rv.Type()
+	for i := 0; i < rv.NumField(); i++ {
+		field := rt.Field(i)
+		value := rv.Field(i)
+		// Only the exported attributes.
+		if field.PkgPath != "" {
+			continue
+		}
+		if field.Anonymous {
+			if value.Kind() == reflect.Ptr {
+				if value.IsNil() {
+					continue
+				}
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Struct {
+				b.addStruct(value.Interface(), strict)
+				continue
+			}
+		}
+		column, options := field.Name, []string(nil)
+		for _, priority := range structTagPriority {
+			if tag := field.Tag.Get(priority); tag != "" {
+				array := strings.Split(tag, ",")
+				column, options = strings.TrimSpace(array[0]), array[1:]
+				break
+			}
+		}
+		if column == "-" || (!strict && isZeroValue(value)) {
+			continue
+		}
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				b.addClause(false, column, " IS NULL")
+				continue
+			}
+			value = value.Elem()
+		}
+		arg := value.Interface()
+		for _, option := range options {
+			if strings.TrimSpace(option) == ORM_TAG_FOR_JSON {
+				if content, err := json.Marshal(arg); err == nil {
+					arg = string(content)
+				}
+			}
+		}
+		if _, ok := arg.(string); !ok && value.Kind() == reflect.Slice && value.Type().Elem().Kind() != reflect.Uint8 {
+			b.addClause(false, column, " IN(?)", arg)
+		} else {
+			b.addClause(false, column, "=?", arg)
+		}
+	}
+	return b
+}
+
+// addClause adds condition "column clause" with its arguments to the builder.
+func (b *WhereBuilder) addClause(or bool, column string, clause string, args ...interface{}) *WhereBuilder {
+	if !whereBuilderColumnReg.MatchString(column) {
+		panic(fmt.Sprintf(`invalid column "%s" for where builder`, column))
+	}
+	b.items = append(b.items, &whereBuilderItem{
+		or:     or,
+		column: column,
+		clause: clause,
+		args:   args,
+	})
+	return b
+}